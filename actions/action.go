@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"net/url"
 
-	"github.com/segmentio/parquet-go"
+	"github.com/parquet-go/parquet-go"
 )
 
 const AddAction = "add"
@@ -26,8 +26,10 @@ func SerializeActionJSON(a Action) ([]byte, error) {
 	})
 }
 
+// decodePath decodes a path stored in an action. Paths are URIs, so "+" is a literal
+// plus sign and not an escaped space.
 func decodePath(path string) (string, error) {
-	return url.QueryUnescape(path)
+	return url.PathUnescape(path)
 }
 
 // ParseActionJSON parses a JSON-encoded action and returns the action.
@@ -155,3 +157,235 @@ func firstNonNull(values []parquet.Value) int {
 	}
 	return -1
 }
+
+// rowValues groups the values of a row by column index. Repeated columns, such as the
+// columns of maps and lists, have more than one value per row.
+func rowValues(row parquet.Row) [][]parquet.Value {
+	n := 0
+	for _, v := range row {
+		if c := v.Column(); c >= n {
+			n = c + 1
+		}
+	}
+	values := make([][]parquet.Value, n)
+	for _, v := range row {
+		if c := v.Column(); c >= 0 {
+			values[c] = append(values[c], v)
+		}
+	}
+	return values
+}
+
+// columnValue returns the first value of the column, or false if the column is missing or null.
+func columnValue(values [][]parquet.Value, column int) (parquet.Value, bool) {
+	if column < 0 || column >= len(values) || len(values[column]) == 0 || values[column][0].IsNull() {
+		return parquet.Value{}, false
+	}
+	return values[column][0], true
+}
+
+// firstValue returns the first value of the column, or a null value if the column is missing.
+func firstValue(values [][]parquet.Value, column int) parquet.Value {
+	if column < 0 || column >= len(values) || len(values[column]) == 0 {
+		return parquet.Value{}
+	}
+	return values[column][0]
+}
+
+// stringValue returns the string of the column, or an empty string if the column is missing or null.
+func stringValue(values [][]parquet.Value, column int) string {
+	if v, ok := columnValue(values, column); ok {
+		return v.String()
+	}
+	return ""
+}
+
+// stringMapValue decodes the map column at the path, like ["add", "partitionValues"],
+// into a map of strings. Null values are decoded as empty strings. An empty map is
+// returned if the column is missing or null.
+func stringMapValue(schema *parquet.Schema, values [][]parquet.Value, path ...string) map[string]string {
+	m := make(map[string]string)
+	keys, vals := mapColumns(schema, path)
+	if keys < 0 || keys >= len(values) {
+		return m
+	}
+	for i, key := range values[keys] {
+		if key.IsNull() {
+			continue
+		}
+		m[key.String()] = ""
+		if vals >= 0 && vals < len(values) && i < len(values[vals]) && !values[vals][i].IsNull() {
+			m[key.String()] = values[vals][i].String()
+		}
+	}
+	return m
+}
+
+// mapColumns returns the indexes of the key and value columns of the map column at the path,
+// or -1 if they are missing.
+func mapColumns(schema *parquet.Schema, path []string) (keys, vals int) {
+	keys, vals = -1, -1
+	for _, column := range schema.Columns() {
+		if len(column) != len(path)+2 || !hasPrefix(column, path) {
+			continue
+		}
+		leaf, ok := schema.Lookup(column...)
+		if !ok {
+			continue
+		}
+		switch column[len(column)-1] {
+		case "key":
+			keys = leaf.ColumnIndex
+		case "value":
+			vals = leaf.ColumnIndex
+		}
+	}
+	return keys, vals
+}
+
+// stringListValue decodes the list column at the path, like ["metaData", "partitionColumns"],
+// into a slice of strings, skipping null elements. False is returned if the column is missing.
+func stringListValue(schema *parquet.Schema, values [][]parquet.Value, path ...string) ([]string, bool) {
+	column := listColumn(schema, path)
+	if column < 0 {
+		return nil, false
+	}
+	list := make([]string, 0)
+	if column < len(values) {
+		for _, v := range values[column] {
+			if !v.IsNull() {
+				list = append(list, v.String())
+			}
+		}
+	}
+	return list, true
+}
+
+// listColumn returns the index of the element column of the list column at the path, or -1
+// if it is missing. Lists may also be written as repeated primitive columns.
+func listColumn(schema *parquet.Schema, path []string) int {
+	for _, column := range schema.Columns() {
+		if len(column) < len(path) || !hasPrefix(column, path) {
+			continue
+		}
+		if leaf, ok := schema.Lookup(column...); ok {
+			return leaf.ColumnIndex
+		}
+	}
+	return -1
+}
+
+// groupValue decodes the group column at the path, like ["commitInfo"], into a map the way
+// encoding/json decodes an object into a map: numbers are float64, nested groups and maps
+// are maps and lists are slices. Null fields are omitted.
+func groupValue(schema *parquet.Schema, values [][]parquet.Value, path ...string) map[string]any {
+	node := parquet.Node(schema)
+	for _, name := range path {
+		node = fieldByName(node, name)
+		if node == nil {
+			return make(map[string]any)
+		}
+	}
+	m, _ := nodeValue(schema, values, node, path).(map[string]any)
+	if m == nil {
+		m = make(map[string]any)
+	}
+	return m
+}
+
+// nodeValue decodes the column of the node at the path, returning nil if it is null.
+func nodeValue(schema *parquet.Schema, values [][]parquet.Value, node parquet.Node, path []string) any {
+	if node.Leaf() && !node.Repeated() {
+		leaf, ok := schema.Lookup(path...)
+		if !ok {
+			return nil
+		}
+		v, ok := columnValue(values, leaf.ColumnIndex)
+		if !ok {
+			return nil
+		}
+		return jsonValue(v)
+	}
+
+	lt := node.Type().LogicalType()
+	switch {
+	case lt != nil && lt.Map != nil:
+		keys, vals := mapColumns(schema, path)
+		if keys < 0 || keys >= len(values) {
+			return nil
+		}
+		m := make(map[string]any)
+		for i, key := range values[keys] {
+			if key.IsNull() {
+				continue
+			}
+			m[key.String()] = nil
+			if vals >= 0 && vals < len(values) && i < len(values[vals]) && !values[vals][i].IsNull() {
+				m[key.String()] = jsonValue(values[vals][i])
+			}
+		}
+		if len(m) == 0 && !isDefined(values[keys], len(path)) {
+			return nil
+		}
+		return m
+	case lt != nil && lt.List != nil, node.Repeated():
+		column := listColumn(schema, path)
+		if column < 0 || column >= len(values) {
+			return nil
+		}
+		list := make([]any, 0)
+		for _, v := range values[column] {
+			if !v.IsNull() {
+				list = append(list, jsonValue(v))
+			}
+		}
+		if len(list) == 0 && !isDefined(values[column], len(path)) {
+			return nil
+		}
+		return list
+	}
+
+	m := make(map[string]any)
+	for _, field := range node.Fields() {
+		if v := nodeValue(schema, values, field, append(path[:len(path):len(path)], field.Name())); v != nil {
+			m[field.Name()] = v
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// isDefined returns true if the values of a nested column show that the group at the
+// given depth of the column path is not null, for example an empty map or list.
+func isDefined(values []parquet.Value, depth int) bool {
+	return len(values) > 0 && values[0].DefinitionLevel() >= depth
+}
+
+// fieldByName returns the field of the group node with the name, or nil if it has none.
+func fieldByName(node parquet.Node, name string) parquet.Node {
+	for _, field := range node.Fields() {
+		if field.Name() == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// jsonValue converts a non-null parquet value to the type encoding/json would decode it to.
+func jsonValue(v parquet.Value) any {
+	switch v.Kind() {
+	case parquet.Boolean:
+		return v.Boolean()
+	case parquet.Int32:
+		return float64(v.Int32())
+	case parquet.Int64:
+		return float64(v.Int64())
+	case parquet.Float:
+		return float64(v.Float())
+	case parquet.Double:
+		return v.Double()
+	}
+	return v.String()
+}
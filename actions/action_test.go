@@ -0,0 +1,177 @@
+package actions
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseParquetRecord(t *testing.T) {
+	type format struct {
+		Provider string            `parquet:"provider"`
+		Options  map[string]string `parquet:"options,optional"`
+	}
+	type metaData struct {
+		ID               string            `parquet:"id"`
+		Name             *string           `parquet:"name,optional"`
+		Description      *string           `parquet:"description,optional"`
+		Format           format            `parquet:"format"`
+		SchemaString     string            `parquet:"schemaString"`
+		PartitionColumns []string          `parquet:"partitionColumns,list"`
+		CreatedTime      *int64            `parquet:"createdTime,optional"`
+		Configuration    map[string]string `parquet:"configuration,optional"`
+	}
+	type add struct {
+		Path             string            `parquet:"path"`
+		PartitionValues  map[string]string `parquet:"partitionValues,optional"`
+		Size             int64             `parquet:"size"`
+		ModificationTime int64             `parquet:"modificationTime"`
+		DataChange       bool              `parquet:"dataChange"`
+		Tags             map[string]string `parquet:"tags,optional"`
+	}
+	type remove struct {
+		Path              string            `parquet:"path"`
+		DeletionTimestamp *int64            `parquet:"deletionTimestamp,optional"`
+		DataChange        bool              `parquet:"dataChange"`
+		PartitionValues   map[string]string `parquet:"partitionValues,optional"`
+		Tags              map[string]string `parquet:"tags,optional"`
+	}
+	type protocol struct {
+		MinReaderVersion int32    `parquet:"minReaderVersion"`
+		MinWriterVersion int32    `parquet:"minWriterVersion"`
+		ReaderFeatures   []string `parquet:"readerFeatures,list"`
+		WriterFeatures   []string `parquet:"writerFeatures,list"`
+	}
+	type operationMetrics struct {
+		NumFiles *string `parquet:"numFiles,optional"`
+	}
+	type commitInfo struct {
+		Timestamp           *int64            `parquet:"timestamp,optional"`
+		Operation           *string           `parquet:"operation,optional"`
+		OperationParameters map[string]string `parquet:"operationParameters,optional"`
+		OperationMetrics    *operationMetrics `parquet:"operationMetrics,optional"`
+		IsBlindAppend       *bool             `parquet:"isBlindAppend,optional"`
+	}
+	type row struct {
+		MetaData   *metaData   `parquet:"metaData,optional"`
+		Add        *add        `parquet:"add,optional"`
+		Remove     *remove     `parquet:"remove,optional"`
+		Protocol   *protocol   `parquet:"protocol,optional"`
+		CommitInfo *commitInfo `parquet:"commitInfo,optional"`
+	}
+
+	name, timestamp, operation, numFiles, blind := "test", int64(1587968586154), "WRITE", "2", true
+	tests := map[string]struct {
+		row  row
+		want Action
+	}{
+		"metaData": {
+			row: row{MetaData: &metaData{
+				ID:               "id",
+				Name:             &name,
+				Format:           format{Provider: "parquet", Options: map[string]string{"a": "b"}},
+				SchemaString:     "{}",
+				PartitionColumns: []string{"date", "region"},
+				Configuration:    map[string]string{"delta.appendOnly": "true"},
+			}},
+			want: &Metadata{
+				ID:               "id",
+				TableName:        "test",
+				Format:           Format{Provider: "parquet", Options: map[string]string{"a": "b"}},
+				SchemaString:     "{}",
+				PartitionColumns: []string{"date", "region"},
+				Configuration:    map[string]string{"delta.appendOnly": "true"},
+			},
+		},
+		"metaData empty": {
+			row: row{MetaData: &metaData{ID: "id", Format: format{Provider: "parquet"}, SchemaString: "{}"}},
+			want: &Metadata{
+				ID:               "id",
+				Format:           Format{Provider: "parquet", Options: map[string]string{}},
+				SchemaString:     "{}",
+				PartitionColumns: []string{},
+				Configuration:    map[string]string{},
+			},
+		},
+		"add": {
+			row: row{Add: &add{
+				Path:             "date=2021-01-01/a.parquet",
+				PartitionValues:  map[string]string{"date": "2021-01-01", "region": ""},
+				Size:             1,
+				ModificationTime: 2,
+				DataChange:       true,
+				Tags:             map[string]string{"INSERTION_TIME": "1"},
+			}},
+			want: &Add{
+				Path:             "date=2021-01-01/a.parquet",
+				PartitionValues:  map[string]string{"date": "2021-01-01", "region": ""},
+				Size:             1,
+				ModificationTime: 2,
+				DataChange:       true,
+				Tags:             map[string]string{"INSERTION_TIME": "1"},
+			},
+		},
+		"remove": {
+			row: row{Remove: &remove{
+				Path:              "a.parquet",
+				DeletionTimestamp: &timestamp,
+				PartitionValues:   map[string]string{"date": "2021-01-01"},
+			}},
+			want: &Remove{
+				Path:              "a.parquet",
+				DeletionTimestamp: timestamp,
+				PartitionValues:   map[string]string{"date": "2021-01-01"},
+				Tags:              map[string]string{},
+			},
+		},
+		"protocol": {
+			row: row{Protocol: &protocol{
+				MinReaderVersion: 3,
+				MinWriterVersion: 7,
+				ReaderFeatures:   []string{"columnMapping"},
+				WriterFeatures:   []string{"columnMapping", "appendOnly"},
+			}},
+			want: &Protocol{
+				MinReaderVersion: 3,
+				MinWriterVersion: 7,
+				ReaderFeatures:   []string{"columnMapping"},
+				WriterFeatures:   []string{"columnMapping", "appendOnly"},
+			},
+		},
+		"commitInfo": {
+			row: row{CommitInfo: &commitInfo{
+				Timestamp:           &timestamp,
+				Operation:           &operation,
+				OperationParameters: map[string]string{"mode": "Append"},
+				OperationMetrics:    &operationMetrics{NumFiles: &numFiles},
+				IsBlindAppend:       &blind,
+			}},
+			want: &CommitInfo{
+				Timestamp:           1587968586154,
+				Operation:           "WRITE",
+				OperationParameters: map[string]any{"mode": "Append"},
+				OperationMetrics:    map[string]any{"numFiles": "2"},
+				IsBlindAppend:       &blind,
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.NoError(t, parquet.Write(buf, []row{test.row}))
+			file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			require.NoError(t, err)
+			reader := parquet.NewReader(file)
+			defer reader.Close()
+			rows := make([]parquet.Row, 1)
+			n, _ := reader.ReadRows(rows)
+			require.Equal(t, 1, n)
+
+			got, err := ParseParquetRecord(file.Schema(), rows[0])
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
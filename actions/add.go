@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/segmentio/parquet-go"
+	"github.com/parquet-go/parquet-go"
 )
 
 var _ Action = (*Add)(nil)
@@ -50,24 +50,63 @@ func NewAdd(path string, size int64, partitionValues map[string]string, dataChan
 	}
 }
 
-// pathDecoded returns the decoded path of the add action.
-func (a *Add) pathDecoded() (string, error) {
+// PathDecoded returns the path of the add action with the URI escaping removed.
+func (a *Add) PathDecoded() (string, error) {
 	return decodePath(a.Path)
 }
 
+// NumRecords returns the number of records in the file, or false if the add action
+// has no statistics.
+func (a *Add) NumRecords() (int64, bool) {
+	if a.Stats == nil {
+		return 0, false
+	}
+	return a.Stats.NumRecords, true
+}
+
+// addJSON is the JSON representation of an add action, where the stats are a JSON-encoded string.
+type addJSON struct {
+	*addAlias
+	Stats string `json:"stats,omitempty"`
+}
+
+type addAlias Add // prevent recursion
+
+// MarshalJSON marshals the add action to JSON, encoding the stats as a string.
+func (a Add) MarshalJSON() ([]byte, error) {
+	v := addJSON{addAlias: (*addAlias)(&a)}
+	if a.Stats != nil {
+		stats, err := json.Marshal(a.Stats)
+		if err != nil {
+			return nil, err
+		}
+		v.Stats = string(stats)
+	}
+	return json.Marshal(v)
+}
+
 // UnmarshalJSON unmarshals the add action from JSON.
 // The data to unmarshal is wrapped in the key "add".
 func (a *Add) UnmarshalJSON(data []byte) error {
 	a.Tags = make(map[string]string)
 	a.PartitionValues = make(map[string]string)
-	type Alias Add // prevent recursion
-	return json.Unmarshal(data, (*Alias)(a))
+	v := addJSON{addAlias: (*addAlias)(a)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	a.Stats = nil
+	if v.Stats != "" {
+		stats, err := ParseStats([]byte(v.Stats))
+		if err != nil {
+			return err
+		}
+		a.Stats = stats
+	}
+	return nil
 }
 
+// UnmarshalParquet unmarshals the add action from a row of a checkpoint.
 func (a *Add) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) error {
-	a.Tags = make(map[string]string)
-	a.PartitionValues = make(map[string]string)
-
 	path, ok := schema.Lookup("add", "path")
 	if !ok {
 		return fmt.Errorf("path not found in schema")
@@ -88,12 +127,37 @@ func (a *Add) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) error {
 		return fmt.Errorf("modificationTime not found in schema")
 	}
 
-	// TODO: handle stats, tags and partitionValues
-
-	a.Path = row[path.ColumnIndex].String()
-	a.Size = row[size.ColumnIndex].Int64()
-	a.DataChange = row[dataChange.ColumnIndex].Boolean()
-	a.ModificationTime = row[modificationTime.ColumnIndex].Int64()
+	values := rowValues(row)
+	if v, ok := columnValue(values, path.ColumnIndex); ok {
+		a.Path = v.String()
+	}
+	if v, ok := columnValue(values, size.ColumnIndex); ok {
+		a.Size = v.Int64()
+	}
+	if v, ok := columnValue(values, dataChange.ColumnIndex); ok {
+		a.DataChange = v.Boolean()
+	}
+	if v, ok := columnValue(values, modificationTime.ColumnIndex); ok {
+		a.ModificationTime = v.Int64()
+	}
+	a.PartitionValues = stringMapValue(schema, values, "add", "partitionValues")
+	a.Tags = stringMapValue(schema, values, "add", "tags")
+
+	// stats_parsed is only written when delta.checkpoint.writeStatsAsStruct is enabled
+	if stats, ok := schema.Lookup("add", "stats"); ok {
+		if v, ok := columnValue(values, stats.ColumnIndex); ok && len(v.ByteArray()) > 0 {
+			parsed, err := ParseStats(v.ByteArray())
+			if err != nil {
+				return err
+			}
+			a.Stats = parsed
+		}
+	}
+	if a.Stats == nil {
+		if parsed, ok := unmarshalParsedStats(schema, values, "add", "stats_parsed"); ok {
+			a.Stats = parsed
+		}
+	}
 
 	return nil
 }
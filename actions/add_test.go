@@ -36,3 +36,21 @@ func TestAdd_MarshalUnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestAdd_PathDecoded(t *testing.T) {
+	tests := map[string]struct {
+		path string
+		want string
+	}{
+		"plain":   {path: "part-00000.parquet", want: "part-00000.parquet"},
+		"escaped": {path: "ts=2021-01-01%2010%253A00%253A00/part-00000.parquet", want: "ts=2021-01-01 10%3A00%3A00/part-00000.parquet"},
+		"plus":    {path: "a=1+1/part-00000.parquet", want: "a=1+1/part-00000.parquet"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewAdd(test.path, 0, nil, true, 0, nil, nil).PathDecoded()
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
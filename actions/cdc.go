@@ -3,7 +3,7 @@ package actions
 import (
 	"encoding/json"
 
-	"github.com/segmentio/parquet-go"
+	"github.com/parquet-go/parquet-go"
 )
 
 var _ Action = (*CDC)(nil)
@@ -36,7 +36,8 @@ func NewCDC(path string, partitionValues map[string]string, size int64, dataChan
 	}
 }
 
-func (c *CDC) pathDecoded() (string, error) {
+// PathDecoded returns the path of the cdc action with the URI escaping removed.
+func (c *CDC) PathDecoded() (string, error) {
 	return decodePath(c.Path)
 }
 
@@ -48,6 +49,7 @@ func (c *CDC) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, (*Alias)(c))
 }
 
+// UnmarshalParquet unmarshals the cdc action from a parquet row.
 func (c *CDC) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) error {
 	c.Tags = make(map[string]string)
 	c.PartitionValues = make(map[string]string)
@@ -67,9 +69,12 @@ func (c *CDC) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) error {
 		return nil
 	}
 
-	c.Path = row[path.ColumnIndex].String()
-	c.Size = row[size.ColumnIndex].Int64()
-	c.DataChange = row[dataChange.ColumnIndex].Boolean()
+	values := rowValues(row)
+	c.Path = stringValue(values, path.ColumnIndex)
+	c.Size = firstValue(values, size.ColumnIndex).Int64()
+	c.DataChange = firstValue(values, dataChange.ColumnIndex).Boolean()
+	c.PartitionValues = stringMapValue(schema, values, "cdc", "partitionValues")
+	c.Tags = stringMapValue(schema, values, "cdc", "tags")
 
 	return nil
 }
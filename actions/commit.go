@@ -1,20 +1,95 @@
 package actions
 
 import (
-	"github.com/segmentio/parquet-go"
+	"encoding/json"
+
+	"github.com/parquet-go/parquet-go"
 )
 
 var _ Action = (*CommitInfo)(nil)
 
 // CommitInfo is a struct that represents the commit info of a delta table.
-type CommitInfo map[string]interface{}
+// It records the provenance of a commit; none of its fields are required.
+type CommitInfo struct {
+	// Timestamp is the time of the commit in milliseconds since epoch.
+	Timestamp int64 `json:"timestamp,omitempty"`
+	// Operation is the name of the operation, like WRITE, DELETE or OPTIMIZE.
+	Operation string `json:"operation,omitempty"`
+	// OperationParameters are the parameters of the operation, like the write mode or the predicate.
+	OperationParameters map[string]interface{} `json:"operationParameters,omitempty"`
+	// OperationMetrics are metrics of the operation, like the number of files added.
+	OperationMetrics map[string]interface{} `json:"operationMetrics,omitempty"`
+	// UserID is the id of the user that committed.
+	UserID string `json:"userId,omitempty"`
+	// UserName is the name of the user that committed.
+	UserName string `json:"userName,omitempty"`
+	// EngineInfo is the name and version of the engine that committed.
+	EngineInfo string `json:"engineInfo,omitempty"`
+	// IsBlindAppend is true if the commit only added files without reading the table.
+	IsBlindAppend *bool `json:"isBlindAppend,omitempty"`
+	// ReadVersion is the version of the table the commit was based on. Nil for the first commit.
+	ReadVersion *int64 `json:"readVersion,omitempty"`
+	// IsolationLevel is the isolation level of the commit, like Serializable or WriteSerializable.
+	IsolationLevel string `json:"isolationLevel,omitempty"`
+	// UserMetadata is metadata set by the user for the commit.
+	UserMetadata string `json:"userMetadata,omitempty"`
+	// Extra holds the fields without a typed field, like clusterId or notebook.
+	Extra map[string]interface{} `json:"-"`
+}
 
 func (c *CommitInfo) Name() string {
 	return "commitInfo"
 }
 
+type commitInfoAlias CommitInfo // prevent recursion
+
+// MarshalJSON marshals the commit info to JSON, including the extra fields.
+func (c CommitInfo) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(commitInfoAlias(c))
+	if err != nil || len(c.Extra) == 0 {
+		return data, err
+	}
+	fields := make(map[string]interface{}, len(c.Extra))
+	for k, v := range c.Extra {
+		fields[k] = v
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON unmarshals the commit info from JSON, keeping the fields without a typed
+// field in Extra.
+func (c *CommitInfo) UnmarshalJSON(data []byte) error {
+	*c = CommitInfo{}
+	if err := json.Unmarshal(data, (*commitInfoAlias)(c)); err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, name := range commitInfoFields {
+		delete(fields, name)
+	}
+	if len(fields) > 0 {
+		c.Extra = fields
+	}
+	return nil
+}
+
+// commitInfoFields are the JSON names of the typed fields of CommitInfo.
+var commitInfoFields = []string{
+	"timestamp", "operation", "operationParameters", "operationMetrics", "userId", "userName",
+	"engineInfo", "isBlindAppend", "readVersion", "isolationLevel", "userMetadata",
+}
+
 // UnmarshalParquet is a custom Parquet unmarshaler for CommitInfo.
 func (c *CommitInfo) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) error {
-	*c = make(map[string]interface{})
-	return nil
+	data, err := json.Marshal(groupValue(schema, rowValues(row), CommitInfoAction))
+	if err != nil {
+		return err
+	}
+	return c.UnmarshalJSON(data)
 }
@@ -8,44 +8,59 @@ import (
 )
 
 func TestCommitInfo_MarshalUnmarhshalJSON(t *testing.T) {
+	blindAppend, readVersion := true, int64(3)
 	tests := map[string]struct {
 		commitInfo CommitInfo
 		wantJSON   string
 	}{
 		"empty": {
-			commitInfo: CommitInfo(map[string]interface{}{}),
+			commitInfo: CommitInfo{},
 			wantJSON:   `{}`,
 		},
 		"full": { // Example from https://github.com/delta-io/delta/blob/master/PROTOCOL.md#commit-provenance-information
-			commitInfo: CommitInfo(map[string]interface{}{
-				"timestamp": float64(1515491537026), // Loses type information when unmarshalling into map[string]interface{} as an int64
-				"userId":    "100121",
-				"userName":  "michael@databricks.com",
-				"operation": "INSERT",
-				"operationParameters": map[string]interface{}{
+			commitInfo: CommitInfo{
+				Timestamp: 1515491537026,
+				UserID:    "100121",
+				UserName:  "michael@databricks.com",
+				Operation: "INSERT",
+				OperationParameters: map[string]interface{}{
 					"mode":        "Append",
 					"partitionBy": "[]",
 				},
-				"notebook": map[string]interface{}{
-					"notebookId":   "4443029",
-					"notebookPath": "Users/michael@databricks.com/actions",
+				Extra: map[string]interface{}{
+					"notebook": map[string]interface{}{
+						"notebookId":   "4443029",
+						"notebookPath": "Users/michael@databricks.com/actions",
+					},
+					"clusterId": "1027-202406-pooh991",
 				},
-				"clusterId": "1027-202406-pooh991",
-			}),
+			},
 			wantJSON: `{"timestamp":1515491537026,"userId":"100121","userName":"michael@databricks.com","operation":"INSERT","operationParameters":{"mode":"Append","partitionBy":"[]"},"notebook":{"notebookId":"4443029","notebookPath":"Users/michael@databricks.com/actions"},"clusterId":"1027-202406-pooh991"}`,
 		},
 		"tbd": {
-			commitInfo: CommitInfo(map[string]interface{}{
-				"timestamp": float64(1587968586154), // Loses type information when unmarshalling into map[string]interface{} as an int64
-				"operation": "WRITE",
-				"operationParameters": map[string]interface{}{
+			commitInfo: CommitInfo{
+				Timestamp: 1587968586154,
+				Operation: "WRITE",
+				OperationParameters: map[string]interface{}{
 					"mode":        "ErrorIfExists",
 					"partitionBy": "[]",
 				},
-				"isBlindAppend": true,
-			}),
+				IsBlindAppend: &blindAppend,
+			},
 			wantJSON: `{"timestamp":1587968586154,"operation":"WRITE","operationParameters":{"mode":"ErrorIfExists","partitionBy":"[]"},"isBlindAppend":true}`,
 		},
+		"typed fields": {
+			commitInfo: CommitInfo{
+				Timestamp:        1587968586154,
+				Operation:        "DELETE",
+				OperationMetrics: map[string]interface{}{"numRemovedFiles": "1"},
+				EngineInfo:       "deltalake-go",
+				ReadVersion:      &readVersion,
+				IsolationLevel:   "Serializable",
+				UserMetadata:     "cleanup",
+			},
+			wantJSON: `{"timestamp":1587968586154,"operation":"DELETE","operationMetrics":{"numRemovedFiles":"1"},"engineInfo":"deltalake-go","readVersion":3,"isolationLevel":"Serializable","userMetadata":"cleanup"}`,
+		},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/segmentio/parquet-go"
+	"github.com/parquet-go/parquet-go"
 )
 
 var _ Action = (*Metadata)(nil)
@@ -68,10 +68,8 @@ func (m *Metadata) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, (*Alias)(m))
 }
 
+// UnmarshalParquet unmarshals the metadata action from a row of a checkpoint.
 func (m *Metadata) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) error {
-	m.Configuration = make(map[string]string)
-	m.PartitionColumns = make([]string, 0)
-
 	id, ok := schema.Lookup("metaData", "id")
 	if !ok {
 		return fmt.Errorf("could not find id in schema")
@@ -92,18 +90,31 @@ func (m *Metadata) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) err
 		return fmt.Errorf("could not find schemaString in schema")
 	}
 
-	// TODO: format
+	provider, ok := schema.Lookup("metaData", "format", "provider")
+	if !ok {
+		return fmt.Errorf("could not find format.provider in schema")
+	}
 
 	createdTime, ok := schema.Lookup("metaData", "createdTime")
 	if !ok {
 		return fmt.Errorf("could not find createdTime in schema")
 	}
 
-	m.ID = row[id.ColumnIndex].String()
-	m.TableName = row[name.ColumnIndex].String()
-	m.Description = row[description.ColumnIndex].String()
-	m.SchemaString = row[schemaString.ColumnIndex].String()
-	m.CreatedTime = row[createdTime.ColumnIndex].Int64()
-	
+	values := rowValues(row)
+	m.ID = stringValue(values, id.ColumnIndex)
+	m.TableName = stringValue(values, name.ColumnIndex)
+	m.Description = stringValue(values, description.ColumnIndex)
+	m.SchemaString = stringValue(values, schemaString.ColumnIndex)
+	m.CreatedTime = firstValue(values, createdTime.ColumnIndex).Int64()
+	m.Format = Format{
+		Provider: stringValue(values, provider.ColumnIndex),
+		Options:  stringMapValue(schema, values, "metaData", "format", "options"),
+	}
+	m.Configuration = stringMapValue(schema, values, "metaData", "configuration")
+	m.PartitionColumns, ok = stringListValue(schema, values, "metaData", "partitionColumns")
+	if !ok {
+		return fmt.Errorf("could not find partitionColumns in schema")
+	}
+
 	return nil
 }
@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/segmentio/parquet-go"
+	"github.com/parquet-go/parquet-go"
 )
 
 var _ Action = (*Protocol)(nil)
@@ -39,6 +39,8 @@ func (p *Protocol) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, (*Alias)(p))
 }
 
+// UnmarshalParquet unmarshals the protocol action from a row of a checkpoint.
+// The feature columns are only present in checkpoints of tables using table features.
 func (p *Protocol) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) error {
 	minReaderVersion, ok := schema.Lookup("protocol", "minReaderVersion")
 	if !ok {
@@ -50,10 +52,17 @@ func (p *Protocol) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) err
 		return fmt.Errorf("could not find minWriterVersion in schema")
 	}
 
-	p.MinReaderVersion = int(row[minReaderVersion.ColumnIndex].Int32())
-	p.MinWriterVersion = int(row[minWriterVersion.ColumnIndex].Int32())
-	p.ReaderFeatures = make([]string, 0)
-	p.WriterFeatures = make([]string, 0)
+	values := rowValues(row)
+	p.MinReaderVersion = int(firstValue(values, minReaderVersion.ColumnIndex).Int32())
+	p.MinWriterVersion = int(firstValue(values, minWriterVersion.ColumnIndex).Int32())
+	p.ReaderFeatures, _ = stringListValue(schema, values, "protocol", "readerFeatures")
+	p.WriterFeatures, _ = stringListValue(schema, values, "protocol", "writerFeatures")
+	if p.ReaderFeatures == nil {
+		p.ReaderFeatures = make([]string, 0)
+	}
+	if p.WriterFeatures == nil {
+		p.WriterFeatures = make([]string, 0)
+	}
 
 	return nil
 }
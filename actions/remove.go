@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/segmentio/parquet-go"
+	"github.com/parquet-go/parquet-go"
 )
 
 var _ Action = (*Remove)(nil)
@@ -58,7 +58,8 @@ func NewRemove(
 	}
 }
 
-func (r *Remove) pathDecoded() (string, error) {
+// PathDecoded returns the path of the remove action with the URI escaping removed.
+func (r *Remove) PathDecoded() (string, error) {
 	return decodePath(r.Path)
 }
 
@@ -70,10 +71,9 @@ func (r *Remove) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, (*Alias)(r))
 }
 
+// UnmarshalParquet unmarshals the remove action from a row of a checkpoint.
+// Older checkpoints may not have the extended file metadata columns.
 func (r *Remove) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) error {
-	r.Tags = make(map[string]string)
-	r.PartitionValues = make(map[string]string)
-
 	path, ok := schema.Lookup("remove", "path")
 	if !ok {
 		return fmt.Errorf("could not find path in schema")
@@ -89,21 +89,18 @@ func (r *Remove) UnmarshalParquet(schema *parquet.Schema, row parquet.Row) error
 		return fmt.Errorf("could not find dataChange in schema")
 	}
 
-	extendedFileMeta, ok := schema.Lookup("remove", "extendedFileMetadata")
-	if !ok {
-		return fmt.Errorf("could not find extendedFileMetadata in schema")
+	values := rowValues(row)
+	r.Path = stringValue(values, path.ColumnIndex)
+	r.DeletionTimestamp = firstValue(values, deletionTimestamp.ColumnIndex).Int64()
+	r.DataChange = firstValue(values, dataChange.ColumnIndex).Boolean()
+	if extendedFileMeta, ok := schema.Lookup("remove", "extendedFileMetadata"); ok {
+		r.ExtendedFileMetadata = firstValue(values, extendedFileMeta.ColumnIndex).Boolean()
 	}
-
-	size, ok := schema.Lookup("remove", "size")
-	if !ok {
-		return fmt.Errorf("could not find size in schema")
+	if size, ok := schema.Lookup("remove", "size"); ok {
+		r.Size = firstValue(values, size.ColumnIndex).Int64()
 	}
-
-	r.Path = row[path.ColumnIndex].String()
-	r.DeletionTimestamp = row[deletionTimestamp.ColumnIndex].Int64()
-	r.DataChange = row[dataChange.ColumnIndex].Boolean()
-	r.ExtendedFileMetadata = row[extendedFileMeta.ColumnIndex].Boolean()
-	r.Size = row[size.ColumnIndex].Int64()
+	r.PartitionValues = stringMapValue(schema, values, "remove", "partitionValues")
+	r.Tags = stringMapValue(schema, values, "remove", "tags")
 
 	return nil
 }
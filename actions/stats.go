@@ -1,5 +1,219 @@
 package actions
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/deprecated"
+)
+
+// Stats contains statistics about the data in a file. The statistics of a column of a
+// nested struct are nested the same way as the column, for example
+//
+//	{"numRecords":2,"minValues":{"a":{"b":1}},"maxValues":{"a":{"b":2}},"nullCount":{"a":{"b":0}}}
+//
+// Numbers are decoded as json.Number so that large integers keep their precision.
+// Dates and timestamps are strings, formatted like "2006-01-02" and
+// "2006-01-02T15:04:05.000Z" respectively.
+// https://github.com/delta-io/delta/blob/master/PROTOCOL.md#per-file-statistics
 type Stats struct {
-	// TODO: add stats
+	// NumRecords is the number of records in the file.
+	NumRecords int64 `json:"numRecords"`
+	// MinValues is the minimum value of each column.
+	MinValues map[string]any `json:"minValues,omitempty"`
+	// MaxValues is the maximum value of each column.
+	MaxValues map[string]any `json:"maxValues,omitempty"`
+	// NullCount is the number of null values of each column.
+	NullCount map[string]any `json:"nullCount,omitempty"`
+}
+
+// ParseStats parses the JSON-encoded statistics stored in the stats field of add actions.
+func ParseStats(data []byte) (*Stats, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var stats Stats
+	if err := decoder.Decode(&stats); err != nil {
+		return nil, fmt.Errorf("invalid stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// Min returns the minimum value of the column at the path, for example Min("a", "b")
+// for the column b of the struct column a.
+func (s *Stats) Min(path ...string) (any, bool) {
+	return lookupStat(s.MinValues, path)
+}
+
+// Max returns the maximum value of the column at the path.
+func (s *Stats) Max(path ...string) (any, bool) {
+	return lookupStat(s.MaxValues, path)
+}
+
+// NullCountOf returns the number of null values of the column at the path.
+func (s *Stats) NullCountOf(path ...string) (int64, bool) {
+	v, ok := lookupStat(s.NullCount, path)
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+func lookupStat(values map[string]any, path []string) (any, bool) {
+	if len(path) == 0 || values == nil {
+		return nil, false
+	}
+	v, ok := values[path[0]]
+	if !ok || v == nil {
+		return nil, false
+	}
+	if len(path) == 1 {
+		if _, nested := v.(map[string]any); nested {
+			return nil, false
+		}
+		return v, true
+	}
+	nested, ok := v.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return lookupStat(nested, path[1:])
+}
+
+// unmarshalParsedStats decodes the stats_parsed struct of a checkpoint row, whose leaf
+// columns start with the prefix, for example ["add", "stats_parsed"].
+func unmarshalParsedStats(schema *parquet.Schema, values [][]parquet.Value, prefix ...string) (*Stats, bool) {
+	var stats Stats
+	found := false
+	for _, columnPath := range schema.Columns() {
+		if len(columnPath) <= len(prefix) || !hasPrefix(columnPath, prefix) {
+			continue
+		}
+		leaf, ok := schema.Lookup(columnPath...)
+		if !ok {
+			continue
+		}
+		v, ok := columnValue(values, leaf.ColumnIndex)
+		if !ok {
+			continue
+		}
+		found = true
+
+		path := columnPath[len(prefix):]
+		var target *map[string]any
+		switch path[0] {
+		case "numRecords":
+			stats.NumRecords = v.Int64()
+			continue
+		case "minValues":
+			target = &stats.MinValues
+		case "maxValues":
+			target = &stats.MaxValues
+		case "nullCount":
+			target = &stats.NullCount
+		default:
+			continue
+		}
+		if len(path) < 2 {
+			continue
+		}
+		if *target == nil {
+			*target = make(map[string]any)
+		}
+		setStat(*target, path[1:], statValue(leaf.Node, v))
+	}
+	return &stats, found
+}
+
+func setStat(values map[string]any, path []string, v any) {
+	for _, name := range path[:len(path)-1] {
+		nested, ok := values[name].(map[string]any)
+		if !ok {
+			nested = make(map[string]any)
+			values[name] = nested
+		}
+		values = nested
+	}
+	values[path[len(path)-1]] = v
+}
+
+// statValue converts a parquet value to the representation used by JSON-encoded stats.
+func statValue(node parquet.Node, v parquet.Value) any {
+	lt := node.Type().LogicalType()
+	if lt != nil && lt.Decimal != nil {
+		return decimalStatValue(v, int(lt.Decimal.Scale))
+	}
+	switch v.Kind() {
+	case parquet.Boolean:
+		return v.Boolean()
+	case parquet.Int32:
+		if lt != nil && lt.Date != nil {
+			return time.Unix(int64(v.Int32())*24*60*60, 0).UTC().Format("2006-01-02")
+		}
+		return json.Number(strconv.FormatInt(int64(v.Int32()), 10))
+	case parquet.Int64:
+		if lt != nil && lt.Timestamp != nil {
+			var t time.Time
+			switch {
+			case lt.Timestamp.Unit.Millis != nil:
+				t = time.UnixMilli(v.Int64())
+			case lt.Timestamp.Unit.Nanos != nil:
+				t = time.Unix(0, v.Int64())
+			default:
+				t = time.UnixMicro(v.Int64())
+			}
+			return t.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+		}
+		if ct := node.Type().ConvertedType(); ct != nil && *ct == deprecated.TimestampMillis {
+			return time.UnixMilli(v.Int64()).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+		}
+		return json.Number(strconv.FormatInt(v.Int64(), 10))
+	case parquet.Float:
+		return json.Number(strconv.FormatFloat(float64(v.Float()), 'g', -1, 32))
+	case parquet.Double:
+		return json.Number(strconv.FormatFloat(v.Double(), 'g', -1, 64))
+	}
+	return v.String()
+}
+
+// decimalStatValue converts the unscaled value of a decimal to a number with the digits of
+// the scale.
+func decimalStatValue(v parquet.Value, scale int) any {
+	var unscaled *big.Int
+	switch v.Kind() {
+	case parquet.Int32:
+		unscaled = big.NewInt(int64(v.Int32()))
+	case parquet.Int64:
+		unscaled = big.NewInt(v.Int64())
+	default: // big-endian two's complement
+		b := v.ByteArray()
+		unscaled = new(big.Int).SetBytes(b)
+		if len(b) > 0 && b[0]&0x80 != 0 {
+			unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+		}
+	}
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	return json.Number(new(big.Rat).SetFrac(unscaled, denom).FloatString(scale))
+}
+
+func hasPrefix(path, prefix []string) bool {
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,140 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStats(t *testing.T) {
+	stats, err := ParseStats([]byte(`{"numRecords":3,"minValues":{"id":1,"a":{"b":"x"}},"maxValues":{"id":9007199254740993,"a":{"b":"z"}},"nullCount":{"id":0,"a":{"b":1}}}`))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), stats.NumRecords)
+
+	tests := map[string]struct {
+		path      []string
+		wantMin   any
+		wantMax   any
+		wantNulls int64
+		wantOK    bool
+	}{
+		"top level":      {path: []string{"id"}, wantMin: json.Number("1"), wantMax: json.Number("9007199254740993"), wantNulls: 0, wantOK: true},
+		"nested":         {path: []string{"a", "b"}, wantMin: "x", wantMax: "z", wantNulls: 1, wantOK: true},
+		"struct":         {path: []string{"a"}},
+		"missing":        {path: []string{"missing"}},
+		"missing nested": {path: []string{"id", "b"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			min, ok := stats.Min(test.path...)
+			require.Equal(t, test.wantOK, ok)
+			require.Equal(t, test.wantMin, min)
+			max, ok := stats.Max(test.path...)
+			require.Equal(t, test.wantOK, ok)
+			require.Equal(t, test.wantMax, max)
+			nulls, ok := stats.NullCountOf(test.path...)
+			require.Equal(t, test.wantOK, ok)
+			require.Equal(t, test.wantNulls, nulls)
+		})
+	}
+
+	_, err = ParseStats([]byte(`{"numRecords":"a"}`))
+	require.Error(t, err)
+}
+
+func TestAdd_Stats(t *testing.T) {
+	data := []byte(`{"path":"a.parquet","size":1,"partitionValues":{},"modificationTime":1,"dataChange":true,"stats":"{\"numRecords\":2,\"minValues\":{\"id\":1},\"maxValues\":{\"id\":2},\"nullCount\":{\"id\":0}}"}`)
+	var add Add
+	require.NoError(t, json.Unmarshal(data, &add))
+	require.NotNil(t, add.Stats)
+	n, ok := add.NumRecords()
+	require.True(t, ok)
+	require.Equal(t, int64(2), n)
+
+	// stats are written back as a JSON-encoded string
+	out, err := json.Marshal(&add)
+	require.NoError(t, err)
+	require.JSONEq(t, string(data), string(out))
+
+	_, ok = NewAdd("b.parquet", 1, nil, true, 1, nil, nil).NumRecords()
+	require.False(t, ok)
+}
+
+func TestAdd_UnmarshalParquetStats(t *testing.T) {
+	type values struct {
+		ID    *int64 `parquet:"id,optional"`
+		Price int32  `parquet:"price,optional,decimal(2:9)"`
+		Name  *struct {
+			First *string `parquet:"first,optional"`
+		} `parquet:"name,optional"`
+	}
+	type statsParsed struct {
+		NumRecords int64   `parquet:"numRecords"`
+		MinValues  *values `parquet:"minValues,optional"`
+		MaxValues  *values `parquet:"maxValues,optional"`
+	}
+	type add struct {
+		Path             string       `parquet:"path"`
+		Size             int64        `parquet:"size"`
+		ModificationTime int64        `parquet:"modificationTime"`
+		DataChange       bool         `parquet:"dataChange"`
+		Stats            *string      `parquet:"stats,optional"`
+		StatsParsed      *statsParsed `parquet:"stats_parsed,optional"`
+	}
+	type row struct {
+		Add *add `parquet:"add,optional"`
+	}
+
+	one, two := int64(1), int64(2)
+	first := "a"
+	stats := `{"numRecords":5}`
+	rows := []row{
+		{Add: &add{Path: "json.parquet", Stats: &stats}},
+		{Add: &add{Path: "parsed.parquet", StatsParsed: &statsParsed{
+			NumRecords: 2,
+			MinValues: &values{ID: &one, Price: -1234, Name: &struct {
+				First *string `parquet:"first,optional"`
+			}{First: &first}},
+			MaxValues: &values{ID: &two},
+		}}},
+		{Add: &add{Path: "none.parquet"}},
+	}
+	buf := new(bytes.Buffer)
+	require.NoError(t, parquet.Write(buf, rows))
+
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	reader := parquet.NewReader(file)
+	defer reader.Close()
+	parquetRows := make([]parquet.Row, len(rows))
+	n, _ := reader.ReadRows(parquetRows)
+	require.Equal(t, len(rows), n)
+
+	var got []*Add
+	for _, r := range parquetRows {
+		action, err := ParseParquetRecord(file.Schema(), r)
+		require.NoError(t, err)
+		got = append(got, action.(*Add))
+	}
+
+	require.Equal(t, int64(5), got[0].Stats.NumRecords)
+
+	require.Equal(t, int64(2), got[1].Stats.NumRecords)
+	min, ok := got[1].Stats.Min("id")
+	require.True(t, ok)
+	require.Equal(t, json.Number("1"), min)
+	min, ok = got[1].Stats.Min("name", "first")
+	require.True(t, ok)
+	require.Equal(t, "a", min)
+	min, ok = got[1].Stats.Min("price")
+	require.True(t, ok)
+	require.Equal(t, json.Number("-12.34"), min)
+	max, ok := got[1].Stats.Max("id")
+	require.True(t, ok)
+	require.Equal(t, json.Number("2"), max)
+
+	require.Nil(t, got[2].Stats)
+}
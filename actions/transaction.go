@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/segmentio/parquet-go"
+	"github.com/parquet-go/parquet-go"
 )
 
 var _ Action = (*Protocol)(nil)
@@ -49,9 +49,10 @@ func (t *Transaction) UnmarshalParquet(schema *parquet.Schema, row parquet.Row)
 		return fmt.Errorf("could not find lastUpdated in schema")
 	}
 
-	t.AppID = row[appId.ColumnIndex].String()
-	t.Version = row[version.ColumnIndex].Int64()
-	t.LastUpdated = row[lastUpdated.ColumnIndex].Int64()
+	values := rowValues(row)
+	t.AppID = firstValue(values, appId.ColumnIndex).String()
+	t.Version = firstValue(values, version.ColumnIndex).Int64()
+	t.LastUpdated = firstValue(values, lastUpdated.ColumnIndex).Int64()
 
 	return nil
 }
@@ -0,0 +1,199 @@
+package deltalake
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/types"
+)
+
+// Names of the columns added to the rows returned by Changes.
+const (
+	// ChangeTypeColumn is the type of the change of the row, one of the Change constants.
+	ChangeTypeColumn = "_change_type"
+	// CommitVersionColumn is the version of the commit that changed the row, as int64.
+	CommitVersionColumn = "_commit_version"
+	// CommitTimestampColumn is the time of the commit that changed the row, as time.Time.
+	CommitTimestampColumn = "_commit_timestamp"
+)
+
+// Types of the changes of the rows returned by Changes.
+const (
+	ChangeInsert          = "insert"
+	ChangeDelete          = "delete"
+	ChangeUpdatePreimage  = "update_preimage"
+	ChangeUpdatePostimage = "update_postimage"
+)
+
+// changeDataDir is the directory of the change data files, relative to the root of the table.
+const changeDataDir = "_change_data"
+
+// Changes returns a Scanner over the row-level changes of the commits from startVersion to
+// endVersion, both included, in the order of the commits. If endVersion is negative, the
+// changes up to the latest commit are returned. Rows have the columns of the current
+// schema of the table and the columns ChangeTypeColumn, CommitVersionColumn and
+// CommitTimestampColumn.
+//
+// The changes of a commit are read from its change data files, the cdc actions, when it has
+// some. Otherwise, they are inferred from the data files added and removed with dataChange
+// set: all the rows of added files are inserts and all the rows of removed files are
+// deletes, so the rows rewritten by an update or a delete appear as a delete and an insert.
+// Removed files must not have been vacuumed.
+//
+// ErrVersionNotFound is returned if endVersion has not been committed and ErrVersionExpired
+// if commits of the range have been removed by log cleanup. Like NewScanner, tables whose
+// protocol requires reader features this library does not support are refused.
+func (t *Table) Changes(startVersion, endVersion int64) (*Scanner, error) {
+	if t.State.CurrentMetadata == nil {
+		return nil, fmt.Errorf("table has no metadata")
+	}
+	if err := t.State.checkReaderVersion(); err != nil {
+		return nil, err
+	}
+	listing, err := t.listLog()
+	if err != nil {
+		return nil, err
+	}
+	commits := make(map[int64]logFile, len(listing.commits))
+	for _, commit := range listing.commits {
+		commits[commit.Version] = commit
+	}
+	latest := int64(-1)
+	if len(listing.commits) > 0 {
+		latest = listing.commits[len(listing.commits)-1].Version
+	}
+	if endVersion < 0 {
+		endVersion = latest
+	}
+	if startVersion < 0 || startVersion > endVersion {
+		return nil, fmt.Errorf("invalid version range %d to %d", startVersion, endVersion)
+	}
+	if endVersion > latest {
+		return nil, fmt.Errorf("%w: %d", ErrVersionNotFound, endVersion)
+	}
+
+	var files []*actions.Add
+	var fileValues []map[string]any
+	for version := startVersion; version <= endVersion; version++ {
+		commit, ok := commits[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: commit %d has been removed", ErrVersionExpired, version)
+		}
+		acts, err := t.peakNextCommit(version - 1)
+		if err != nil {
+			return nil, err
+		}
+		ts, err := t.commitTimestamp(commit)
+		if err != nil {
+			return nil, err
+		}
+		changes := commitChanges(acts)
+		for _, change := range changes {
+			values := map[string]any{
+				CommitVersionColumn:   version,
+				CommitTimestampColumn: time.UnixMilli(ts).UTC(),
+			}
+			if change.changeType != "" {
+				values[ChangeTypeColumn] = change.changeType
+			}
+			files = append(files, change.file)
+			fileValues = append(fileValues, values)
+		}
+	}
+	log.Debug().
+		Int64("startVersion", startVersion).
+		Int64("endVersion", endVersion).
+		Int("files", len(files)).
+		Msg("read changes")
+
+	// change data files have the change type as a column
+	scanner := newScanner(t.Storage, changeMetadata(t.State.CurrentMetadata), files)
+	scanner.fileValues = fileValues
+	return scanner, nil
+}
+
+// changeFile is a file with changed rows. The change type is empty for change data files,
+// which have it as a column.
+type changeFile struct {
+	file       *actions.Add
+	changeType string
+}
+
+// commitChanges returns the files with the changes of the commit: its change data files if
+// it has some, else its added and removed data files that change data.
+func commitChanges(acts []actions.Action) []changeFile {
+	var cdc, inferred []changeFile
+	for _, action := range acts {
+		switch a := action.(type) {
+		case *actions.CDC:
+			cdc = append(cdc, changeFile{file: &actions.Add{Path: a.Path, PartitionValues: a.PartitionValues, Size: a.Size}})
+		case *actions.Add:
+			if a.DataChange {
+				inferred = append(inferred, changeFile{file: a, changeType: ChangeInsert})
+			}
+		case *actions.Remove:
+			if a.DataChange {
+				file := &actions.Add{Path: a.Path, PartitionValues: a.PartitionValues, Size: a.Size}
+				inferred = append(inferred, changeFile{file: file, changeType: ChangeDelete})
+			}
+		}
+	}
+	if len(cdc) > 0 {
+		return cdc
+	}
+	return inferred
+}
+
+// changeMetadata returns a copy of the metadata with the change type column added to the
+// schema, as in change data files.
+func changeMetadata(metadata *TableMetadata) *TableMetadata {
+	m := *metadata
+	fields := make([]*types.StructField, 0, len(m.Schema.Fields)+1)
+	fields = append(fields, m.Schema.Fields...)
+	fields = append(fields, types.NewStructField(ChangeTypeColumn, types.DataTypeString, true, nil))
+	m.Schema = *types.NewStruct(fields...)
+	return &m
+}
+
+// newChangeWriter returns a Writer of change data files for the rows changed by an
+// operation, or nil if the change data feed of the table is not enabled.
+func (t *Table) newChangeWriter() (*Writer, error) {
+	if !t.State.CurrentMetadata.EnableChangeDataFeed() {
+		return nil, nil
+	}
+	return NewWriter(t.Storage, changeMetadata(t.State.CurrentMetadata), withDirectory(changeDataDir))
+}
+
+// writeChange writes a copy of the row with the change type to the change data writer,
+// unless it is nil.
+func writeChange(w *Writer, changeType string, row map[string]any) error {
+	if w == nil {
+		return nil
+	}
+	change := make(map[string]any, len(row)+1)
+	for column, v := range row {
+		change[column] = v
+	}
+	change[ChangeTypeColumn] = changeType
+	return w.Write(change)
+}
+
+// closeChangeWriter closes the change data writer, unless it is nil, and returns the cdc
+// actions of the files written.
+func closeChangeWriter(w *Writer) ([]*actions.CDC, error) {
+	if w == nil {
+		return nil, nil
+	}
+	adds, err := w.Close()
+	if err != nil {
+		return nil, err
+	}
+	cdcs := make([]*actions.CDC, len(adds))
+	for i, add := range adds {
+		cdcs[i] = actions.NewCDC(add.Path, add.PartitionValues, add.Size, false, nil)
+	}
+	return cdcs, nil
+}
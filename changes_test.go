@@ -0,0 +1,313 @@
+package deltalake
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+// readChanges returns the rows of the changes between the versions.
+func readChanges(t *testing.T, tbl *Table, startVersion, endVersion int64) []map[string]any {
+	t.Helper()
+	scanner, err := tbl.Changes(startVersion, endVersion)
+	require.NoError(t, err)
+	defer scanner.Close()
+	var rows []map[string]any
+	for scanner.Next() {
+		rows = append(rows, scanner.Row())
+	}
+	require.NoError(t, scanner.Err())
+	return rows
+}
+
+// ids returns the ids from lo to hi, excluded, without those skipped.
+func ids(lo, hi int64, skip ...int64) []int64 {
+	var ids []int64
+next:
+	for id := lo; id < hi; id++ {
+		for _, s := range skip {
+			if id == s {
+				continue next
+			}
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// changedIDs returns the ids of the rows by version and change type.
+func changedIDs(rows []map[string]any) map[int64]map[string][]int64 {
+	byVersion := make(map[int64]map[string][]int64)
+	for _, row := range rows {
+		version := row[CommitVersionColumn].(int64)
+		if byVersion[version] == nil {
+			byVersion[version] = make(map[string][]int64)
+		}
+		changeType := row[ChangeTypeColumn].(string)
+		byVersion[version][changeType] = append(byVersion[version][changeType], row["id"].(int64))
+	}
+	return byVersion
+}
+
+func TestTable_Changes(t *testing.T) {
+	// the table has ids 0-29 in three files on 2021-01-01, 30-39 on 2021-01-02 and 40-59
+	// in two files on 2021-01-03, committed one file per version, and the row 5 is deleted
+	// at version 7
+	tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+	_, err := tbl.Delete(Eq(Col("id"), Lit(5)))
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		startVersion int64
+		endVersion   int64
+		want         map[int64]map[string][]int64
+	}{
+		"single commit": {
+			startVersion: 1, endVersion: 1,
+			want: map[int64]map[string][]int64{1: {ChangeInsert: ids(0, 10)}},
+		},
+		"range": {
+			startVersion: 3, endVersion: 4,
+			want: map[int64]map[string][]int64{3: {ChangeInsert: ids(20, 30)}, 4: {ChangeInsert: ids(30, 40)}},
+		},
+		"delete up to latest": {
+			startVersion: 7, endVersion: -1,
+			want: map[int64]map[string][]int64{7: {ChangeDelete: ids(0, 10), ChangeInsert: ids(0, 10, 5)}},
+		},
+		"metadata only": {
+			startVersion: 0, endVersion: 0,
+			want: map[int64]map[string][]int64{},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rows := readChanges(t, tbl, test.startVersion, test.endVersion)
+			got := changedIDs(rows)
+			for _, changes := range got {
+				for _, ids := range changes {
+					sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+				}
+			}
+			require.Equal(t, test.want, got)
+
+			for _, row := range rows {
+				require.Contains(t, row, "name")
+				require.IsType(t, time.Time{}, row[CommitTimestampColumn])
+			}
+		})
+	}
+}
+
+func TestTable_ChangesDataFiles(t *testing.T) {
+	root := t.TempDir()
+	store, err := storage.NewLocalStorage(root)
+	require.NoError(t, err)
+	metadata := NewTableMetadata("test", "", actions.DefaultFormat, *types.NewStruct(
+		types.NewStructField("id", types.DataTypeLong, false, nil),
+		types.NewStructField("name", types.DataTypeString, true, nil),
+	), nil, nil)
+	tbl, err := CreateTable(store, metadata)
+	require.NoError(t, err)
+
+	w, err := tbl.NewWriter()
+	require.NoError(t, err)
+	require.NoError(t, w.Write(map[string]any{"id": 1, "name": "a"}, map[string]any{"id": 2, "name": "a"}))
+	adds, err := w.Close()
+	require.NoError(t, err)
+	tx := tbl.NewTransaction()
+	tx.AddActions(toActions(adds)...)
+	_, err = tx.Commit()
+	require.NoError(t, err)
+
+	// the change data file of an update of the row 1, written with the change type column
+	changeStore, err := storage.NewLocalStorage(filepath.Join(root, changeDataDir))
+	require.NoError(t, err)
+	changeMetadata := *metadata
+	changeMetadata.Schema = *types.NewStruct(append(metadata.Schema.Fields,
+		types.NewStructField(ChangeTypeColumn, types.DataTypeString, false, nil))...)
+	cw, err := NewWriter(changeStore, &changeMetadata)
+	require.NoError(t, err)
+	require.NoError(t, cw.Write(
+		map[string]any{"id": 1, "name": "a", ChangeTypeColumn: ChangeUpdatePreimage},
+		map[string]any{"id": 1, "name": "b", ChangeTypeColumn: ChangeUpdatePostimage},
+	))
+	cdcs, err := cw.Close()
+	require.NoError(t, err)
+	require.Len(t, cdcs, 1)
+
+	w, err = tbl.NewWriter()
+	require.NoError(t, err)
+	require.NoError(t, w.Write(map[string]any{"id": 1, "name": "b"}, map[string]any{"id": 2, "name": "a"}))
+	adds, err = w.Close()
+	require.NoError(t, err)
+	tx = tbl.NewTransaction(WithOperation("UPDATE", nil))
+	tx.AddActions(toActions(adds)...)
+	tx.AddAction(actions.NewRemove(tbl.State.activeFiles()[0].Path, time.Now().UnixMilli(), true, false, nil, 0, nil))
+	tx.AddAction(actions.NewCDC(changeDataDir+"/"+cdcs[0].Path, nil, cdcs[0].Size, false, nil))
+	_, err = tx.Commit()
+	require.NoError(t, err)
+
+	rows := readChanges(t, tbl, 2, 2)
+	for _, row := range rows {
+		require.Equal(t, int64(2), row[CommitVersionColumn])
+		delete(row, CommitVersionColumn)
+		delete(row, CommitTimestampColumn)
+	}
+	require.Equal(t, []map[string]any{
+		{"id": int64(1), "name": "a", ChangeTypeColumn: ChangeUpdatePreimage},
+		{"id": int64(1), "name": "b", ChangeTypeColumn: ChangeUpdatePostimage},
+	}, rows)
+}
+
+func TestTable_ChangesInvalid(t *testing.T) {
+	tests := map[string]struct {
+		startVersion int64
+		endVersion   int64
+		// expire removes the first commit before reading the changes
+		expire         bool
+		readerFeatures []string
+		wantErr        error
+	}{
+		"end not committed":  {startVersion: 0, endVersion: 7, wantErr: ErrVersionNotFound},
+		"start after end":    {startVersion: 3, endVersion: 2},
+		"start after latest": {startVersion: 7, endVersion: -1},
+		"negative start":     {startVersion: -1, endVersion: 2},
+		"expired":            {startVersion: 0, endVersion: 2, expire: true, wantErr: ErrVersionExpired},
+		"reader features":    {startVersion: 0, endVersion: 2, readerFeatures: []string{"deletionVectors"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			if test.expire {
+				require.NoError(t, tbl.Storage.Delete(CommitURIFromVersion(0)))
+			}
+			if test.readerFeatures != nil {
+				tbl.State.MinReaderVersion, tbl.State.ReaderFeatures = 3, test.readerFeatures
+			}
+			_, err := tbl.Changes(test.startVersion, test.endVersion)
+			require.Error(t, err)
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+			}
+		})
+	}
+}
+
+// enableChangeDataFeed commits the metadata of the table with the change data feed enabled.
+func enableChangeDataFeed(t *testing.T, tbl *Table) {
+	t.Helper()
+	metadata := *tbl.State.CurrentMetadata
+	metadata.Configuration = map[string]string{"delta.enableChangeDataFeed": "true"}
+	action, err := metadata.MetadataAction()
+	require.NoError(t, err)
+	tx := tbl.NewTransaction()
+	tx.AddAction(action)
+	_, err = tx.Commit()
+	require.NoError(t, err)
+}
+
+func TestTable_WriteChangeDataFeed(t *testing.T) {
+	// the table has ids 0-59, named "a", on 2021-01-01 to 2021-01-03
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		disabled bool
+		change   func(tbl *Table) error
+		want     map[string][]int64
+		// wantNames are the names of the changed rows by change type, if not nil
+		wantNames map[string][]any
+	}{
+		"delete": {
+			change: func(tbl *Table) error {
+				_, err := tbl.Delete(Or(Lt(Col("id"), Lit(3)), Eq(Col("date"), Lit("2021-01-02"))))
+				return err
+			},
+			want: map[string][]int64{ChangeDelete: {0, 1, 2, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39}},
+		},
+		"update": {
+			change: func(tbl *Table) error {
+				_, err := tbl.Update(Eq(Col("id"), Lit(5)), map[string]Expr{"name": Lit("b")})
+				return err
+			},
+			want:      map[string][]int64{ChangeUpdatePreimage: {5}, ChangeUpdatePostimage: {5}},
+			wantNames: map[string][]any{ChangeUpdatePreimage: {"a"}, ChangeUpdatePostimage: {"b"}},
+		},
+		"merge": {
+			change: func(tbl *Table) error {
+				_, err := tbl.Merge([]map[string]any{
+					{"id": 5, "deleted": true},
+					{"id": 6, "name": "b"},
+					{"id": 100, "name": "c", "date": day},
+				}, "id").
+					WhenMatchedDelete(Eq(Col(MergeSource, "deleted"), Lit(true))).
+					WhenMatchedUpdate(nil, map[string]Expr{"name": Col(MergeSource, "name")}).
+					WhenNotMatchedInsert(nil, nil).
+					Execute()
+				return err
+			},
+			want: map[string][]int64{
+				ChangeDelete: {5}, ChangeUpdatePreimage: {6}, ChangeUpdatePostimage: {6}, ChangeInsert: {100},
+			},
+			wantNames: map[string][]any{
+				ChangeDelete: {"a"}, ChangeUpdatePreimage: {"a"}, ChangeUpdatePostimage: {"b"}, ChangeInsert: {"c"},
+			},
+		},
+		"disabled": {
+			disabled: true,
+			change: func(tbl *Table) error {
+				_, err := tbl.Update(Eq(Col("id"), Lit(5)), map[string]Expr{"name": Lit("b")})
+				return err
+			},
+			want: map[string][]int64{ChangeDelete: ids(0, 10), ChangeInsert: ids(0, 10)},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			if !test.disabled {
+				enableChangeDataFeed(t, tbl)
+			}
+			before := scanAll(t, tbl)
+			version := tbl.State.Version
+			require.NoError(t, test.change(tbl))
+			require.Equal(t, version+1, tbl.State.Version)
+
+			acts, err := tbl.peakNextCommit(version)
+			require.NoError(t, err)
+			var cdcs int
+			for _, action := range acts {
+				if cdc, ok := action.(*actions.CDC); ok {
+					require.True(t, strings.HasPrefix(cdc.Path, changeDataDir+"/"), cdc.Path)
+					require.False(t, cdc.DataChange)
+					cdcs++
+				}
+			}
+			require.Equal(t, test.disabled, cdcs == 0)
+
+			rows := readChanges(t, tbl, version+1, version+1)
+			got := changedIDs(rows)[version+1]
+			for _, ids := range got {
+				sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			}
+			require.Equal(t, test.want, got)
+			if test.wantNames != nil {
+				names := make(map[string][]any)
+				for _, row := range rows {
+					changeType := row[ChangeTypeColumn].(string)
+					names[changeType] = append(names[changeType], row["name"])
+				}
+				require.Equal(t, test.wantNames, names)
+			}
+
+			// change data files are not data files of the table
+			require.Len(t, scanAll(t, tbl), len(before)+len(test.want[ChangeInsert])-len(test.want[ChangeDelete]))
+		})
+	}
+}
@@ -1,19 +1,92 @@
 package deltalake
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
 )
 
+// Checkpoint is the content of the _last_checkpoint file, which points to the most recent
+// checkpoint of the table.
+// https://github.com/delta-io/delta/blob/master/PROTOCOL.md#last-checkpoint-file
 type Checkpoint struct {
 	// Version is the version of the delta table.
 	// When formatted as a string, it is left padded with 0s to 20 digits.
-	Version int64
-	// Size in bytes of the files in the version.
-	Size int64
+	Version int64 `json:"version"`
+	// Size is the number of actions stored in the checkpoint.
+	Size int64 `json:"size"`
 	// When formatted as a string, it is left padded with 0s to 10 digits.
-	Parts int
+	Parts int `json:"parts,omitempty"`
+	// SizeInBytes is the total size of the checkpoint files in bytes.
+	SizeInBytes int64 `json:"sizeInBytes,omitempty"`
+	// NumOfAddFiles is the number of add actions in the checkpoint.
+	NumOfAddFiles int64 `json:"numOfAddFiles,omitempty"`
 }
 
+// DefaultTombstoneRetentionMillis is how long remove actions are kept in checkpoints when
+// the table does not configure a retention, one week like delta.deletedFileRetentionDuration.
+const DefaultTombstoneRetentionMillis = 7 * 24 * 60 * 60 * 1000
+
+// checkpointSchema is the schema of the rows of checkpoint files. Each row holds a single
+// action in the column named after it.
+// https://github.com/delta-io/delta/blob/master/PROTOCOL.md#checkpoint-schema
+var checkpointSchema = types.NewStruct(
+	types.NewStructField(actions.TransactionAction, types.NewStruct(
+		types.NewStructField("appId", types.DataTypeString, true, nil),
+		types.NewStructField("version", types.DataTypeLong, true, nil),
+		types.NewStructField("lastUpdated", types.DataTypeLong, true, nil),
+	), true, nil),
+	types.NewStructField(actions.AddAction, types.NewStruct(
+		types.NewStructField("path", types.DataTypeString, true, nil),
+		types.NewStructField("partitionValues", types.NewMapType(types.DataTypeString, types.DataTypeString, true), true, nil),
+		types.NewStructField("size", types.DataTypeLong, true, nil),
+		types.NewStructField("modificationTime", types.DataTypeLong, true, nil),
+		types.NewStructField("dataChange", types.DataTypeBoolean, true, nil),
+		types.NewStructField("stats", types.DataTypeString, true, nil),
+		types.NewStructField("tags", types.NewMapType(types.DataTypeString, types.DataTypeString, true), true, nil),
+	), true, nil),
+	types.NewStructField(actions.RemoveAction, types.NewStruct(
+		types.NewStructField("path", types.DataTypeString, true, nil),
+		types.NewStructField("deletionTimestamp", types.DataTypeLong, true, nil),
+		types.NewStructField("dataChange", types.DataTypeBoolean, true, nil),
+		types.NewStructField("extendedFileMetadata", types.DataTypeBoolean, true, nil),
+		types.NewStructField("partitionValues", types.NewMapType(types.DataTypeString, types.DataTypeString, true), true, nil),
+		types.NewStructField("size", types.DataTypeLong, true, nil),
+		types.NewStructField("tags", types.NewMapType(types.DataTypeString, types.DataTypeString, true), true, nil),
+	), true, nil),
+	types.NewStructField(actions.MetadataAction, types.NewStruct(
+		types.NewStructField("id", types.DataTypeString, true, nil),
+		types.NewStructField("name", types.DataTypeString, true, nil),
+		types.NewStructField("description", types.DataTypeString, true, nil),
+		types.NewStructField("format", types.NewStruct(
+			types.NewStructField("provider", types.DataTypeString, true, nil),
+			types.NewStructField("options", types.NewMapType(types.DataTypeString, types.DataTypeString, true), true, nil),
+		), true, nil),
+		types.NewStructField("schemaString", types.DataTypeString, true, nil),
+		types.NewStructField("partitionColumns", types.NewArrayType(types.DataTypeString, true), true, nil),
+		types.NewStructField("configuration", types.NewMapType(types.DataTypeString, types.DataTypeString, true), true, nil),
+		types.NewStructField("createdTime", types.DataTypeLong, true, nil),
+	), true, nil),
+	types.NewStructField(actions.ProtocolAction, types.NewStruct(
+		types.NewStructField("minReaderVersion", types.DataTypeInteger, true, nil),
+		types.NewStructField("minWriterVersion", types.DataTypeInteger, true, nil),
+		types.NewStructField("readerFeatures", types.NewArrayType(types.DataTypeString, true), true, nil),
+		types.NewStructField("writerFeatures", types.NewArrayType(types.DataTypeString, true), true, nil),
+	), true, nil),
+)
+
 // ListCheckpointParts enumerates the paths of the parts of the checkpoint.
 // This does not check if the parts exist in the storage. It only returns the
 // paths that should be used.
@@ -56,3 +129,325 @@ func checkpointPath(version int64) string {
 func checkpointPartPath(version int64, part, parts int) string {
 	return fmt.Sprintf("%s/%020d.checkpoint.%010d.%010d.parquet", LogDirName, version, part, parts)
 }
+
+// DefaultCheckpointConcurrency is the default number of checkpoint parts written at once.
+const DefaultCheckpointConcurrency = 4
+
+// CheckpointOption configures how a checkpoint is written.
+type CheckpointOption func(*checkpointOptions)
+
+type checkpointOptions struct {
+	maxActionsPerPart int
+	concurrency       int
+}
+
+// WithMaxActionsPerPart splits the checkpoint into parts of at most n actions each. By
+// default, or if n is not positive, the checkpoint is written as a single file.
+func WithMaxActionsPerPart(n int) CheckpointOption {
+	return func(o *checkpointOptions) {
+		o.maxActionsPerPart = n
+	}
+}
+
+// WithCheckpointConcurrency sets the number of checkpoint parts written at once.
+// Defaults to DefaultCheckpointConcurrency.
+func WithCheckpointConcurrency(n int) CheckpointOption {
+	return func(o *checkpointOptions) {
+		o.concurrency = n
+	}
+}
+
+// CreateCheckpoint writes a checkpoint of the current version of the table and points
+// _last_checkpoint to it. The checkpoint contains the protocol, the metadata, the app
+// transaction versions, the files of the table and the remove actions that are still
+// within the tombstone retention period. Like Spark, the add and remove actions are written
+// with dataChange set to false.
+//
+// With WithMaxActionsPerPart the checkpoint is split into multiple parts that are written
+// in parallel. _last_checkpoint is only updated once all the parts have been written, and
+// the written parts are deleted if any part fails. It is never moved back to an older
+// version than the one it records, for example for a table loaded at an older version.
+// Tables whose writer version is not supported are refused.
+//
+// If the table enables delta.enableExpiredLogCleanup, the commits and checkpoints that are
+// older than the log retention and no longer needed are then deleted, see CleanupExpiredLogs.
+func (t *Table) CreateCheckpoint(opts ...CheckpointOption) (*Checkpoint, error) {
+	options := checkpointOptions{concurrency: DefaultCheckpointConcurrency}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+	if t.State.Version < 0 || t.State.CurrentMetadata == nil {
+		return nil, errors.New("table has no metadata")
+	}
+	if !t.Config.RequireFiles || !t.Config.RequireTombstones {
+		return nil, errors.New("checkpoints require a table state loaded with files and tombstones")
+	}
+	// the checkpoint would drop the parts of the state this library does not model
+	if err := t.State.checkWriterVersion(); err != nil {
+		return nil, err
+	}
+
+	acts, err := t.State.checkpointActions(time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	parts := 1
+	if n := options.maxActionsPerPart; n > 0 && len(acts) > n {
+		parts = (len(acts) + n - 1) / n
+	}
+	checkpoint := &Checkpoint{Version: t.State.Version, Size: int64(len(acts))}
+	if parts > 1 {
+		checkpoint.Parts = parts
+	}
+	for _, action := range acts {
+		if _, ok := action.(*actions.Add); ok {
+			checkpoint.NumOfAddFiles++
+		}
+	}
+	schema, err := parquetSchema(checkpointSchema.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	uris := ListCheckpointParts(checkpoint)
+	sizes := make([]int64, parts)
+	errs := make([]error, parts)
+	sem := make(chan struct{}, options.concurrency)
+	var wg sync.WaitGroup
+	for i := range uris {
+		// the actions are split evenly so that the parts differ by at most one action
+		from, to := i*len(acts)/parts, (i+1)*len(acts)/parts
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, acts []actions.Action) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			sizes[i], errs[i] = t.writeCheckpointPart(schema, uris[i], acts)
+		}(i, acts[from:to])
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		for i, uri := range uris {
+			if errs[i] == nil {
+				if err := t.Storage.Delete(uri); err != nil {
+					log.Warn().Err(err).Str("uri", uri).Msg("could not delete checkpoint part")
+				}
+			}
+		}
+		return nil, err
+	}
+	for _, size := range sizes {
+		checkpoint.SizeInBytes += size
+	}
+
+	if err := t.writeLastCheckpoint(checkpoint); err != nil {
+		return nil, err
+	}
+	t.LastCheckpoint = checkpoint
+
+	// the checkpoint is written, so failing to clean up the log only leaves more files behind
+	if t.State.CurrentMetadata.EnableLogExpiredCleanup() {
+		if _, err := t.cleanupExpiredLogs(time.Now(), DefaultLogCleanupConcurrency); err != nil {
+			log.Warn().Err(err).Int64("version", checkpoint.Version).Msg("could not clean up expired logs")
+		}
+	}
+	return checkpoint, nil
+}
+
+// writeCheckpointPart writes the actions to the checkpoint file at the uri and returns
+// its size in bytes.
+func (t *Table) writeCheckpointPart(schema *parquet.Schema, uri string, acts []actions.Action) (int64, error) {
+	encoder := newRowEncoder(schema)
+	buf := new(bytes.Buffer)
+	writer := parquet.NewWriter(buf, schema, parquet.Compression(&parquet.Snappy))
+	for i, action := range acts {
+		// actions are grouped by kind, each in its own row groups, so that readers
+		// that only need some of them can skip the others using the row group statistics
+		if i > 0 && checkpointRowGroupOf(action) != checkpointRowGroupOf(acts[i-1]) {
+			if err := writer.Flush(); err != nil {
+				return 0, err
+			}
+		}
+		record, err := checkpointRecord(action)
+		if err != nil {
+			return 0, err
+		}
+		values, err := normalizeRecord(checkpointSchema, record)
+		if err != nil {
+			return 0, err
+		}
+		row, err := encoder.encode(values)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := writer.WriteRows([]parquet.Row{row}); err != nil {
+			return 0, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	size := int64(buf.Len())
+	if err := t.Storage.Put(uri, buf); err != nil {
+		return 0, err
+	}
+	log.Debug().
+		Str("uri", uri).
+		Int("actions", len(acts)).
+		Int64("size", size).
+		Msg("wrote checkpoint")
+	return size, nil
+}
+
+// checkpointRowGroupOf returns the kind of row group the action is written in: file
+// actions are separated from the much fewer actions describing the table.
+func checkpointRowGroupOf(action actions.Action) string {
+	switch action.(type) {
+	case *actions.Add, *actions.Remove:
+		return action.Name()
+	}
+	return "table"
+}
+
+// writeLastCheckpoint points _last_checkpoint to the checkpoint.
+func (t *Table) writeLastCheckpoint(checkpoint *Checkpoint) error {
+	last, err := t.MostRecentCheckpoint()
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	if last != nil && last.Version > checkpoint.Version {
+		return fmt.Errorf("%s already records version %d, newer than %d", LastCheckpointFileName, last.Version, checkpoint.Version)
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return t.Storage.Put(path.Join(LogDirName, LastCheckpointFileName), bytes.NewReader(data))
+}
+
+// checkpointActions returns the actions of the table state to write to a checkpoint.
+// Remove actions deleted before the tombstone retention period preceding now
+// (milliseconds since epoch) are left out.
+func (s *TableState) checkpointActions(now int64) ([]actions.Action, error) {
+	metadata, err := s.CurrentMetadata.MetadataAction()
+	if err != nil {
+		return nil, err
+	}
+	acts := []actions.Action{
+		actions.NewProtocol(s.MinReaderVersion, s.MinWriterVersion, s.ReaderFeatures, s.WriterFeatures),
+		metadata,
+	}
+
+	appIDs := make([]string, 0, len(s.AppTransactionVersion))
+	for appID := range s.AppTransactionVersion {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Strings(appIDs)
+	for _, appID := range appIDs {
+		acts = append(acts, actions.NewTransaction(appID, s.AppTransactionVersion[appID], 0))
+	}
+
+	for _, add := range s.activeFiles() {
+		acts = append(acts, add)
+	}
+
+	retention := s.TombstoneRetentionMillis
+	if retention <= 0 {
+		retention = DefaultTombstoneRetentionMillis
+	}
+	removes := make([]*actions.Remove, 0, len(s.Tombstones))
+	for _, remove := range s.Tombstones {
+		if remove.DeletionTimestamp > now-retention {
+			removes = append(removes, remove)
+		}
+	}
+	sort.Slice(removes, func(i, j int) bool { return removes[i].Path < removes[j].Path })
+	for _, remove := range removes {
+		acts = append(acts, remove)
+	}
+	return acts, nil
+}
+
+// checkpointRecord returns the action as a record of the checkpoint schema.
+func checkpointRecord(action actions.Action) (map[string]any, error) {
+	switch a := action.(type) {
+	case *actions.Protocol:
+		return map[string]any{actions.ProtocolAction: map[string]any{
+			"minReaderVersion": a.MinReaderVersion,
+			"minWriterVersion": a.MinWriterVersion,
+			"readerFeatures":   nullIfNoFeatures(a.ReaderFeatures),
+			"writerFeatures":   nullIfNoFeatures(a.WriterFeatures),
+		}}, nil
+	case *actions.Metadata:
+		return map[string]any{actions.MetadataAction: map[string]any{
+			"id":          a.ID,
+			"name":        nullIfEmpty(a.TableName),
+			"description": nullIfEmpty(a.Description),
+			"format": map[string]any{
+				"provider": a.Format.Provider,
+				"options":  a.Format.Options,
+			},
+			"schemaString":     a.SchemaString,
+			"partitionColumns": a.PartitionColumns,
+			"configuration":    a.Configuration,
+			"createdTime":      a.CreatedTime,
+		}}, nil
+	case *actions.Transaction:
+		return map[string]any{actions.TransactionAction: map[string]any{
+			"appId":   a.AppID,
+			"version": a.Version,
+		}}, nil
+	case *actions.Add:
+		var stats any
+		if a.Stats != nil {
+			data, err := json.Marshal(a.Stats)
+			if err != nil {
+				return nil, err
+			}
+			stats = string(data)
+		}
+		return map[string]any{actions.AddAction: map[string]any{
+			"path":             a.Path,
+			"partitionValues":  a.PartitionValues,
+			"size":             a.Size,
+			"modificationTime": a.ModificationTime,
+			"dataChange":       false,
+			"stats":            stats,
+			"tags":             a.Tags,
+		}}, nil
+	case *actions.Remove:
+		return map[string]any{actions.RemoveAction: map[string]any{
+			"path":                 a.Path,
+			"deletionTimestamp":    a.DeletionTimestamp,
+			"dataChange":           false,
+			"extendedFileMetadata": a.ExtendedFileMetadata,
+			"partitionValues":      a.PartitionValues,
+			"size":                 a.Size,
+			"tags":                 a.Tags,
+		}}, nil
+	}
+	return nil, fmt.Errorf("unsupported checkpoint action %T", action)
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullIfNoFeatures returns nil for an empty list of table features, which are only
+// written for tables that use them.
+func nullIfNoFeatures(features []string) any {
+	if len(features) == 0 {
+		return nil
+	}
+	return features
+}
@@ -0,0 +1,312 @@
+package deltalake
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+	"deltalake/storage"
+)
+
+// removeTestCommit removes the first file of a test table and records the version 2 of
+// the transaction of the app "app".
+var removeTestCommit = testCommit{
+	actions: []actions.Action{actions.NewTransaction("app", 2, time.Now().UnixMilli())},
+	remove:  []int{0},
+}
+
+func TestTable_CreateCheckpoint(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl := writePartitionedTestTable(t, store, 1, []int{1, 1, 1}, removeTestCommit)
+
+	checkpoint, err := tbl.CreateCheckpoint()
+	require.NoError(t, err)
+	// protocol, metaData, txn, 2 adds and 1 remove
+	require.Equal(t, int64(4), checkpoint.Version)
+	require.Equal(t, int64(6), checkpoint.Size)
+	require.Equal(t, int64(2), checkpoint.NumOfAddFiles)
+	require.Equal(t, checkpoint, tbl.LastCheckpoint)
+
+	last, err := tbl.MostRecentCheckpoint()
+	require.NoError(t, err)
+	require.Equal(t, checkpoint, last)
+	info, err := store.Head(checkpointPath(4))
+	require.NoError(t, err)
+	require.Equal(t, checkpoint.SizeInBytes, info.Size)
+
+	// the checkpoint has the standard schema
+	obj, err := store.Get(checkpointPath(4))
+	require.NoError(t, err)
+	data, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	require.NoError(t, obj.Close())
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	for _, column := range [][]string{
+		{"add", "path"}, {"add", "partitionValues", "key_value", "key"}, {"add", "stats"},
+		{"remove", "path"}, {"remove", "deletionTimestamp"},
+		{"metaData", "schemaString"}, {"metaData", "partitionColumns", "list", "element"}, {"metaData", "format", "provider"},
+		{"protocol", "minReaderVersion"}, {"txn", "appId"},
+	} {
+		_, ok := file.Schema().Lookup(column...)
+		require.True(t, ok, "missing column %v", column)
+	}
+
+	// a table loaded from the checkpoint has the same state
+	loaded, err := LoadTable(store, nil)
+	require.NoError(t, err)
+	require.Equal(t, checkpoint, loaded.LastCheckpoint)
+	require.Equal(t, tbl.State.Version, loaded.State.Version)
+	require.Equal(t, tbl.State.MinReaderVersion, loaded.State.MinReaderVersion)
+	require.Equal(t, tbl.State.MinWriterVersion, loaded.State.MinWriterVersion)
+	require.Equal(t, tbl.State.CurrentMetadata.ID, loaded.State.CurrentMetadata.ID)
+	require.Equal(t, tbl.State.CurrentMetadata.Schema, loaded.State.CurrentMetadata.Schema)
+	require.Equal(t, tbl.State.AppTransactionVersion, loaded.State.AppTransactionVersion)
+	require.Equal(t, filePaths(tbl.State.Files), filePaths(loaded.State.Files))
+	require.Len(t, loaded.State.Tombstones, 1)
+	for _, add := range loaded.State.Files {
+		require.NotNil(t, add.Stats)
+		require.False(t, add.DataChange)
+	}
+	require.Len(t, scanAll(t, loaded), 2)
+}
+
+func TestTable_LoadCheckpointMatchesReplay(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl := writePartitionedTestTable(t, store, 1, []int{1, 1, 1}, removeTestCommit)
+	tbl.State.activeFiles()[0].Tags = map[string]string{"INSERTION_TIME": "1"}
+	replayed := tbl.State
+
+	_, err = tbl.CreateCheckpoint()
+	require.NoError(t, err)
+	loaded, err := LoadTable(store, nil)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.LastCheckpoint)
+
+	// checkpoints do not record whether files were data changes
+	files := func(state *TableState) []actions.Add {
+		var files []actions.Add
+		for _, add := range state.activeFiles() {
+			f := *add
+			f.DataChange = false
+			files = append(files, f)
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+		return files
+	}
+	tombstones := func(state *TableState) map[string]actions.Remove {
+		tombstones := make(map[string]actions.Remove)
+		for path, remove := range state.Tombstones {
+			r := *remove
+			r.DataChange = false
+			tombstones[path] = r
+		}
+		return tombstones
+	}
+
+	require.Equal(t, replayed.Version, loaded.State.Version)
+	require.Equal(t, replayed.MinReaderVersion, loaded.State.MinReaderVersion)
+	require.Equal(t, replayed.MinWriterVersion, loaded.State.MinWriterVersion)
+	require.Equal(t, replayed.CurrentMetadata, loaded.State.CurrentMetadata)
+	require.Equal(t, replayed.AppTransactionVersion, loaded.State.AppTransactionVersion)
+	require.Equal(t, files(replayed), files(loaded.State))
+	require.Equal(t, tombstones(replayed), tombstones(loaded.State))
+
+	got, err := loaded.State.FilesInPartitions(Eq(Col("date"), Lit("2021-01-02")))
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, "2021-01-02", got[0].PartitionValues["date"])
+}
+
+func TestTable_CreateCheckpointMultiPart(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl := writePartitionedTestTable(t, store, 1, []int{1, 1, 1}, removeTestCommit)
+
+	checkpoint, err := tbl.CreateCheckpoint(WithMaxActionsPerPart(4), WithCheckpointConcurrency(2))
+	require.NoError(t, err)
+	require.Equal(t, 2, checkpoint.Parts)
+	require.Equal(t, int64(6), checkpoint.Size)
+
+	var size int64
+	for _, uri := range ListCheckpointParts(checkpoint) {
+		info, err := store.Head(uri)
+		require.NoError(t, err)
+		size += info.Size
+	}
+	require.Equal(t, checkpoint.SizeInBytes, size)
+
+	loaded, err := LoadTable(store, nil)
+	require.NoError(t, err)
+	require.Equal(t, checkpoint, loaded.LastCheckpoint)
+	require.Equal(t, filePaths(tbl.State.Files), filePaths(loaded.State.Files))
+	require.Equal(t, tbl.State.CurrentMetadata.Schema, loaded.State.CurrentMetadata.Schema)
+	require.Equal(t, tbl.State.AppTransactionVersion, loaded.State.AppTransactionVersion)
+	require.Len(t, loaded.State.Tombstones, 1)
+}
+
+// failingStorage fails to write the paths in fail.
+type failingStorage struct {
+	storage.ObjectStorage
+	fail map[string]bool
+}
+
+func (s *failingStorage) Put(path string, data io.Reader) error {
+	if s.fail[path] {
+		return errors.New("put failed")
+	}
+	return s.ObjectStorage.Put(path, data)
+}
+
+func TestTable_CreateCheckpointPartFailure(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl := writePartitionedTestTable(t, store, 1, []int{1, 1, 1}, removeTestCommit)
+	_, err = tbl.CreateCheckpoint()
+	require.NoError(t, err)
+	previous := tbl.LastCheckpoint
+
+	tbl.Storage = &failingStorage{ObjectStorage: store, fail: map[string]bool{checkpointPartPath(4, 2, 3): true}}
+	_, err = tbl.CreateCheckpoint(WithMaxActionsPerPart(2))
+	require.Error(t, err)
+	require.Equal(t, previous, tbl.LastCheckpoint)
+
+	// _last_checkpoint still points to the previous checkpoint and no parts are left behind
+	last, err := tbl.MostRecentCheckpoint()
+	require.NoError(t, err)
+	require.Equal(t, previous, last)
+	for part := 1; part <= 3; part++ {
+		_, err := store.Head(checkpointPartPath(4, part, 3))
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	}
+}
+
+func TestTableState_CheckpointActionsExpiredTombstones(t *testing.T) {
+	state := NewTableState(WithVersion(1))
+	state.CurrentMetadata = NewTableMetadata("test", "", actions.DefaultFormat, testSchema(), nil, nil)
+	now := time.Now().UnixMilli()
+	state.Tombstones["old"] = actions.NewRemove("old", now-DefaultTombstoneRetentionMillis-1, true, false, nil, 0, nil)
+	state.Tombstones["new"] = actions.NewRemove("new", now-1, true, false, nil, 0, nil)
+
+	acts, err := state.checkpointActions(now)
+	require.NoError(t, err)
+	var removed []string
+	for _, action := range acts {
+		if remove, ok := action.(*actions.Remove); ok {
+			removed = append(removed, remove.Path)
+		}
+	}
+	require.Equal(t, []string{"new"}, removed)
+}
+
+func TestTableState_CheckpointActionsFeatures(t *testing.T) {
+	state := NewTableState(WithVersion(1), WithMinReaderVersion(3), WithMinWriterVersion(7))
+	state.ReaderFeatures, state.WriterFeatures = []string{"deletionVectors"}, []string{"deletionVectors"}
+	state.CurrentMetadata = NewTableMetadata("test", "", actions.DefaultFormat, testSchema(), nil, nil)
+
+	acts, err := state.checkpointActions(time.Now().UnixMilli())
+	require.NoError(t, err)
+	require.Equal(t, actions.NewProtocol(3, 7, []string{"deletionVectors"}, []string{"deletionVectors"}), acts[0])
+}
+
+func TestTable_CreateCheckpointRefused(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl := writePartitionedTestTable(t, store, 1, []int{1, 1, 1}, removeTestCommit)
+	checkpoint, err := tbl.CreateCheckpoint()
+	require.NoError(t, err)
+
+	// _last_checkpoint is not moved back to an older version
+	old, err := LoadTableAtVersion(store, nil, 2)
+	require.NoError(t, err)
+	_, err = old.CreateCheckpoint()
+	require.Error(t, err)
+	last, err := tbl.MostRecentCheckpoint()
+	require.NoError(t, err)
+	require.Equal(t, checkpoint, last)
+
+	// tables with an unsupported writer version are refused
+	tbl.State.MinReaderVersion, tbl.State.MinWriterVersion = 3, 7
+	_, err = tbl.CreateCheckpoint()
+	require.Error(t, err)
+}
+
+func filePaths(files []*actions.Add) []string {
+	paths := make([]string, len(files))
+	for i, add := range files {
+		paths[i] = add.Path
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestTableState_ReadCheckpointBatches(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl, err := CreateTable(store, &TableMetadata{Schema: testSchema(), PartitionColumns: []string{"date"}})
+	require.NoError(t, err)
+
+	// more files than fit in a single batch of rows
+	n := checkpointBatchSize*2 + 10
+	tx := tbl.NewTransaction()
+	for i := 0; i < n; i++ {
+		tx.AddAction(actions.NewAdd(fmt.Sprintf("part-%05d.parquet", i), int64(i), nil, true, 0, nil, nil))
+	}
+	_, err = tx.Commit()
+	require.NoError(t, err)
+	checkpoint, err := tbl.CreateCheckpoint()
+	require.NoError(t, err)
+
+	obj, err := storage.OpenReaderAt(store, checkpointPath(checkpoint.Version))
+	require.NoError(t, err)
+	defer obj.Close()
+	state := NewTableState(WithVersion(checkpoint.Version))
+	require.NoError(t, state.ReadCheckpoint(obj, obj.Size()))
+	require.Len(t, state.Files, n)
+	require.Equal(t, filePaths(tbl.State.Files), filePaths(state.Files))
+	require.NotNil(t, state.CurrentMetadata)
+}
+
+func TestNewTableStateFromCheckpoint_Concurrent(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl := writePartitionedTestTable(t, store, 1, []int{1, 1, 1}, removeTestCommit)
+	checkpoint, err := tbl.CreateCheckpoint(WithMaxActionsPerPart(1))
+	require.NoError(t, err)
+	require.Equal(t, 6, checkpoint.Parts)
+
+	var files [][]string
+	for _, concurrency := range []int{1, 3, 10} {
+		loaded, err := LoadTable(store, &TableConfig{RequireFiles: true, RequireTombstones: true, CheckpointConcurrency: concurrency})
+		require.NoError(t, err)
+		// the protocol is only in the first part and is not reset by the other parts
+		require.Equal(t, tbl.State.MinReaderVersion, loaded.State.MinReaderVersion)
+		require.Equal(t, tbl.State.MinWriterVersion, loaded.State.MinWriterVersion)
+		require.Equal(t, tbl.State.CurrentMetadata.Schema, loaded.State.CurrentMetadata.Schema)
+		require.Equal(t, tbl.State.AppTransactionVersion, loaded.State.AppTransactionVersion)
+		require.Len(t, loaded.State.Tombstones, 1)
+
+		paths := make([]string, len(loaded.State.Files))
+		for i, add := range loaded.State.Files {
+			paths[i] = add.Path
+		}
+		files = append(files, paths)
+	}
+	// files are merged in part order regardless of the concurrency
+	require.Equal(t, files[0], files[1])
+	require.Equal(t, files[0], files[2])
+
+	require.NoError(t, store.Delete(checkpointPartPath(checkpoint.Version, 3, 6)))
+	_, err = LoadTable(store, nil)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
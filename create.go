@@ -0,0 +1,191 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+const (
+	// DefaultMinReaderVersion is the reader protocol version of tables created by this library.
+	DefaultMinReaderVersion = 1
+	// DefaultMinWriterVersion is the writer protocol version of tables created by this library.
+	DefaultMinWriterVersion = 2
+)
+
+// ErrTableExists is returned when creating a table at a location that already has a delta log.
+var ErrTableExists = errors.New("table already exists")
+
+type CreateTableOptions struct {
+	// IfNotExists loads and returns the existing table instead of failing when a
+	// delta log already exists at the location.
+	IfNotExists bool
+	// Config is the config of the returned table. DefaultTableConfig is used when nil.
+	Config *TableConfig
+	// MinReaderVersion is the reader protocol version of the table.
+	MinReaderVersion int
+	// MinWriterVersion is the writer protocol version of the table.
+	MinWriterVersion int
+}
+
+type CreateTableOption func(*CreateTableOptions) error
+
+// WithIfNotExists returns the existing table instead of failing when the table already exists.
+func WithIfNotExists() CreateTableOption {
+	return func(o *CreateTableOptions) error {
+		o.IfNotExists = true
+		return nil
+	}
+}
+
+// WithCreateTableConfig sets the config of the returned table.
+func WithCreateTableConfig(config *TableConfig) CreateTableOption {
+	return func(o *CreateTableOptions) error {
+		o.Config = config
+		return nil
+	}
+}
+
+// WithProtocolVersions sets the reader and writer protocol versions of the table.
+func WithProtocolVersions(minReaderVersion, minWriterVersion int) CreateTableOption {
+	return func(o *CreateTableOptions) error {
+		if minReaderVersion < 1 || minWriterVersion < 1 {
+			return fmt.Errorf("invalid protocol versions %d/%d", minReaderVersion, minWriterVersion)
+		}
+		o.MinReaderVersion = minReaderVersion
+		o.MinWriterVersion = minWriterVersion
+		return nil
+	}
+}
+
+// CreateTable creates a new table by writing version 0 of the delta log with the protocol
+// and the given metadata. An error wrapping ErrTableExists is returned if a delta log already
+// exists at the location, unless WithIfNotExists is used.
+//
+// Missing metadata fields are filled in: a random ID, the default format, and the current
+// time as the creation time.
+func CreateTable(storage storage.ObjectStorage, metadata *TableMetadata, opts ...CreateTableOption) (*Table, error) {
+	o := &CreateTableOptions{
+		MinReaderVersion: DefaultMinReaderVersion,
+		MinWriterVersion: DefaultMinWriterVersion,
+	}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+
+	if metadata == nil {
+		return nil, errors.New("metadata is required")
+	}
+	if err := validateMetadata(metadata); err != nil {
+		return nil, err
+	}
+
+	table := NewTable(storage, o.Config)
+	listing, err := table.listLog()
+	if err != nil {
+		return nil, err
+	}
+	if listing.latestVersion() >= 0 {
+		if o.IfNotExists {
+			log.Debug().Str("table_uri", table.TableURI()).Msg("table already exists, loading it")
+			return LoadTable(storage, o.Config)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrTableExists, table.TableURI())
+	}
+
+	md := *metadata
+	if md.ID == "" {
+		md.ID = uuid.New().String()
+	}
+	if md.Format.Provider == "" {
+		md.Format = actions.DefaultFormat
+	}
+	if md.Format.Options == nil {
+		md.Format.Options = make(map[string]string)
+	}
+	if md.CreatedTime == 0 {
+		md.CreatedTime = time.Now().UnixMilli()
+	}
+	if md.PartitionColumns == nil {
+		md.PartitionColumns = make([]string, 0)
+	}
+	if md.Configuration == nil {
+		md.Configuration = make(map[string]string)
+	}
+
+	metadataAction, err := md.MetadataAction()
+	if err != nil {
+		return nil, err
+	}
+
+	partitionBy, err := json.Marshal(md.PartitionColumns)
+	if err != nil {
+		return nil, err
+	}
+	properties, err := json.Marshal(md.Configuration)
+	if err != nil {
+		return nil, err
+	}
+	tx := table.NewTransaction(WithOperation("CREATE TABLE", map[string]interface{}{
+		"isManaged":   "false",
+		"description": md.Description,
+		"partitionBy": string(partitionBy),
+		"properties":  string(properties),
+	}))
+	tx.AddActions(
+		actions.NewProtocol(o.MinReaderVersion, o.MinWriterVersion, nil, nil),
+		metadataAction,
+	)
+	if _, err := tx.Commit(); err != nil {
+		if errors.Is(err, ErrCommitConflict) { // created concurrently by another writer
+			if o.IfNotExists {
+				return LoadTable(storage, o.Config)
+			}
+			return nil, fmt.Errorf("%w: %s", ErrTableExists, table.TableURI())
+		}
+		return nil, err
+	}
+	return table, nil
+}
+
+// validateMetadata checks that the schema is valid and that the partition columns
+// are primitive columns of the schema.
+func validateMetadata(metadata *TableMetadata) error {
+	if len(metadata.Schema.Fields) == 0 {
+		return errors.New("schema must have at least one field")
+	}
+	if err := metadata.Schema.Validate(); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(metadata.PartitionColumns))
+	for _, column := range metadata.PartitionColumns {
+		field, err := metadata.Schema.GetFieldByName(column)
+		if err != nil {
+			return fmt.Errorf("partition column %s not found in schema", column)
+		}
+		if !types.IsPrimitiveType(field.Type) {
+			return fmt.Errorf("partition column %s has unsupported type %s", column, field.Type)
+		}
+		name := strings.ToLower(column)
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate partition column %s", column)
+		}
+		seen[name] = struct{}{}
+	}
+	if len(metadata.PartitionColumns) == len(metadata.Schema.Fields) {
+		return errors.New("cannot partition by all columns of the schema")
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package deltalake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+func TestCreateTable(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	metadata := NewTableMetadata("test", "a test table", actions.DefaultFormat, testSchema(), []string{"date"}, map[string]string{"delta.appendOnly": "false"})
+	tbl, err := CreateTable(store, metadata)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), tbl.State.Version)
+	require.Equal(t, DefaultMinReaderVersion, tbl.State.MinReaderVersion)
+	require.Equal(t, DefaultMinWriterVersion, tbl.State.MinWriterVersion)
+
+	loaded, err := LoadTable(store, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), loaded.State.Version)
+	require.Equal(t, metadata.ID, loaded.State.CurrentMetadata.ID)
+	require.Equal(t, metadata.Name, loaded.State.CurrentMetadata.Name)
+	require.Equal(t, []string{"date"}, loaded.State.CurrentMetadata.PartitionColumns)
+	require.Equal(t, metadata.Schema, loaded.State.CurrentMetadata.Schema)
+
+	_, err = CreateTable(store, metadata)
+	require.ErrorIs(t, err, ErrTableExists)
+
+	existing, err := CreateTable(store, metadata, WithIfNotExists())
+	require.NoError(t, err)
+	require.Equal(t, metadata.ID, existing.State.CurrentMetadata.ID)
+}
+
+func TestCreateTable_Defaults(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	tbl, err := CreateTable(store, &TableMetadata{Schema: testSchema()})
+	require.NoError(t, err)
+	md := tbl.State.CurrentMetadata
+	require.NotEmpty(t, md.ID)
+	require.Equal(t, "parquet", md.Format.Provider)
+	require.NotZero(t, md.CreatedTime)
+}
+
+func TestCreateTable_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		metadata *TableMetadata
+	}{
+		"nil metadata":    {metadata: nil},
+		"empty schema":    {metadata: &TableMetadata{}},
+		"invalid field":   {metadata: &TableMetadata{Schema: *types.NewStruct(types.NewStructField("", types.DataTypeLong, false, nil))}},
+		"deprecated bool": {metadata: &TableMetadata{Schema: *types.NewStruct(types.NewStructField("active", types.DataTypeBool, true, nil))}},
+		"missing partition column": {metadata: &TableMetadata{
+			Schema:           testSchema(),
+			PartitionColumns: []string{"missing"},
+		}},
+		"duplicate partition column": {metadata: &TableMetadata{
+			Schema:           testSchema(),
+			PartitionColumns: []string{"date", "date"},
+		}},
+		"complex partition column": {metadata: &TableMetadata{
+			Schema: *types.NewStruct(
+				types.NewStructField("id", types.DataTypeLong, false, nil),
+				types.NewStructField("tags", types.NewArrayType(types.DataTypeString, true), true, nil),
+			),
+			PartitionColumns: []string{"tags"},
+		}},
+		"all columns partitioned": {metadata: &TableMetadata{
+			Schema:           *types.NewStruct(types.NewStructField("date", types.DataTypeDate, false, nil)),
+			PartitionColumns: []string{"date"},
+		}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, err := storage.NewLocalStorage(t.TempDir())
+			require.NoError(t, err)
+			_, err = CreateTable(store, test.metadata)
+			require.Error(t, err)
+
+			infos, err := store.List(LogDirName)
+			require.NoError(t, err)
+			require.Empty(t, infos, "no log should be written for invalid metadata")
+		})
+	}
+}
@@ -0,0 +1,633 @@
+package deltalake
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/deprecated"
+
+	"deltalake/types"
+)
+
+// parquetSchema returns the parquet schema of data files holding the given columns.
+func parquetSchema(fields []*types.StructField) (*parquet.Schema, error) {
+	group, err := parquetGroup(fields)
+	if err != nil {
+		return nil, err
+	}
+	return parquet.NewSchema("spark_schema", group), nil
+}
+
+func parquetGroup(fields []*types.StructField) (parquet.Group, error) {
+	group := make(parquet.Group, len(fields))
+	for _, field := range fields {
+		node, err := parquetNode(field.Type, field)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", field.Name, err)
+		}
+		if field.Nullable {
+			node = parquet.Optional(node)
+		}
+		group[field.Name] = node
+	}
+	return group, nil
+}
+
+// parquetNode returns the parquet node for the delta type.
+// The field is used to resolve the inner types of complex types.
+func parquetNode(dt types.DataType, field *types.StructField) (parquet.Node, error) {
+	switch dt {
+	case types.DataTypeLong:
+		return parquet.Int(64), nil
+	case types.DataTypeInteger:
+		return parquet.Int(32), nil
+	case types.DataTypeShort:
+		return parquet.Int(16), nil
+	case types.DataTypeByte:
+		return parquet.Int(8), nil
+	case types.DataTypeDouble:
+		return parquet.Leaf(parquet.DoubleType), nil
+	case types.DataTypeFloat:
+		return parquet.Leaf(parquet.FloatType), nil
+	case types.DataTypeString:
+		return parquet.String(), nil
+	case types.DataTypeBinary:
+		return parquet.Leaf(parquet.ByteArrayType), nil
+	case types.DataTypeBoolean, types.DataTypeBool:
+		return parquet.Leaf(parquet.BooleanType), nil
+	case types.DataTypeDate:
+		return parquet.Date(), nil
+	case types.DataTypeTimestamp:
+		return parquet.Timestamp(parquet.Microsecond), nil
+	case "struct":
+		if field == nil || field.StructType() == nil {
+			return nil, fmt.Errorf("missing struct fields")
+		}
+		return parquetGroup(field.StructType().Fields)
+	case "array":
+		if field == nil || field.ArrayType() == nil {
+			return nil, fmt.Errorf("missing array element type")
+		}
+		element, err := parquetNode(field.ArrayType().ElementType, nil)
+		if err != nil {
+			return nil, err
+		}
+		if field.ArrayType().ContainsNull {
+			element = parquet.Optional(element)
+		}
+		return parquet.List(element), nil
+	case "map":
+		if field == nil || field.MapType() == nil {
+			return nil, fmt.Errorf("missing map key and value types")
+		}
+		key, err := parquetNode(field.MapType().KeyType, nil)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parquetNode(field.MapType().ValueType, nil)
+		if err != nil {
+			return nil, err
+		}
+		if field.MapType().ValueContainsNull {
+			value = parquet.Optional(value)
+		}
+		return parquet.Map(key, value), nil
+	}
+	if precision, scale, ok := dt.Decimal(); ok {
+		return parquet.Decimal(scale, precision, decimalPhysicalType(precision)), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", dt)
+}
+
+// decimalPhysicalType returns the physical type of decimals with the precision, like
+// Spark: INT32 and INT64 when the unscaled values fit, and otherwise a fixed length byte
+// array of the fewest bytes that can hold them.
+func decimalPhysicalType(precision int) parquet.Type {
+	switch {
+	case precision <= 9:
+		return parquet.Int32Type
+	case precision <= 18:
+		return parquet.Int64Type
+	}
+	return parquet.FixedLenByteArrayType(decimalByteLength(precision))
+}
+
+// rowEncoder encodes normalized records into parquet rows following the
+// record shredding algorithm: every leaf column receives one value per
+// (possibly null or empty) occurrence, annotated with repetition and definition levels.
+type rowEncoder struct {
+	schema  *parquet.Schema
+	columns [][]parquet.Value
+}
+
+func newRowEncoder(schema *parquet.Schema) *rowEncoder {
+	return &rowEncoder{
+		schema:  schema,
+		columns: make([][]parquet.Value, len(schema.Columns())),
+	}
+}
+
+// encode returns the parquet row for the record.
+func (e *rowEncoder) encode(record map[string]any) (parquet.Row, error) {
+	for i := range e.columns {
+		e.columns[i] = e.columns[i][:0]
+	}
+	if _, err := e.encodeGroup(e.schema, record, 0, 0, 0, 0); err != nil {
+		return nil, err
+	}
+
+	row := make(parquet.Row, 0, len(e.columns))
+	for _, values := range e.columns {
+		row = append(row, values...)
+	}
+	return row, nil
+}
+
+// encodeNode encodes the value of the node into the columns starting at column and
+// returns the index of the column after the last leaf of the node.
+// rep is the repetition level of the first value, def the definition level of the
+// parent and depth the number of repeated ancestors.
+func (e *rowEncoder) encodeNode(node parquet.Node, value any, column, rep, def, depth int) (int, error) {
+	if node.Optional() {
+		if value == nil {
+			return e.encodeNulls(node, column, rep, def), nil
+		}
+		return e.encodeNode(parquet.Required(node), value, column, rep, def+1, depth)
+	}
+
+	if value == nil {
+		return 0, fmt.Errorf("missing value for required column %v", e.schema.Columns()[column])
+	}
+
+	switch {
+	case node.Leaf():
+		v, err := parquetValue(node, value)
+		if err != nil {
+			return 0, fmt.Errorf("column %v: %w", e.schema.Columns()[column], err)
+		}
+		e.columns[column] = append(e.columns[column], v.Level(rep, def, column))
+		return column + 1, nil
+	case isLogicalList(node):
+		list, ok := value.([]any)
+		if !ok {
+			return 0, fmt.Errorf("column %v: expected []any, got %T", e.schema.Columns()[column], value)
+		}
+		repeated := node.Fields()[0] // repeated group list { element }
+		element := repeated.Fields()[0]
+		if len(list) == 0 {
+			return e.encodeNulls(repeated, column, rep, def), nil
+		}
+		next := column
+		for i, item := range list {
+			r := rep
+			if i > 0 {
+				r = depth + 1
+			}
+			var err error
+			if next, err = e.encodeNode(element, item, column, r, def+1, depth+1); err != nil {
+				return 0, err
+			}
+		}
+		return next, nil
+	case isLogicalMap(node):
+		m, ok := value.(map[any]any)
+		if !ok {
+			return 0, fmt.Errorf("column %v: expected map[any]any, got %T", e.schema.Columns()[column], value)
+		}
+		repeated := node.Fields()[0] // repeated group key_value { key, value }
+		key, val := repeated.Fields()[0], repeated.Fields()[1]
+		if len(m) == 0 {
+			return e.encodeNulls(repeated, column, rep, def), nil
+		}
+		next := column
+		i := 0
+		for k, v := range m {
+			r := rep
+			if i > 0 {
+				r = depth + 1
+			}
+			var err error
+			if next, err = e.encodeNode(key, k, column, r, def+1, depth+1); err != nil {
+				return 0, err
+			}
+			if next, err = e.encodeNode(val, v, next, r, def+1, depth+1); err != nil {
+				return 0, err
+			}
+			i++
+		}
+		return next, nil
+	default:
+		record, ok := value.(map[string]any)
+		if !ok {
+			return 0, fmt.Errorf("column %v: expected map[string]any, got %T", e.schema.Columns()[column], value)
+		}
+		return e.encodeGroup(node, record, column, rep, def, depth)
+	}
+}
+
+func (e *rowEncoder) encodeGroup(node parquet.Node, record map[string]any, column, rep, def, depth int) (int, error) {
+	var err error
+	for _, field := range node.Fields() {
+		if column, err = e.encodeNode(field, record[field.Name()], column, rep, def, depth); err != nil {
+			return 0, err
+		}
+	}
+	return column, nil
+}
+
+// encodeNulls adds a null value to every leaf column of the node and returns the
+// index of the column after the last leaf of the node.
+func (e *rowEncoder) encodeNulls(node parquet.Node, column, rep, def int) int {
+	n := numLeaves(node)
+	for i := column; i < column+n; i++ {
+		e.columns[i] = append(e.columns[i], parquet.Value{}.Level(rep, def, i))
+	}
+	return column + n
+}
+
+// numLeaves returns the number of leaf columns of the node.
+func numLeaves(node parquet.Node) int {
+	if node.Leaf() {
+		return 1
+	}
+	n := 0
+	for _, field := range node.Fields() {
+		n += numLeaves(field)
+	}
+	return n
+}
+
+func isLogicalList(node parquet.Node) bool {
+	lt := node.Type().LogicalType()
+	return lt != nil && lt.List != nil
+}
+
+func isLogicalMap(node parquet.Node) bool {
+	lt := node.Type().LogicalType()
+	return lt != nil && (lt.Map != nil)
+}
+
+// parquetValue converts a normalized value to the physical value of the leaf node.
+func parquetValue(node parquet.Node, value any) (parquet.Value, error) {
+	lt := node.Type().LogicalType()
+	switch v := value.(type) {
+	case time.Time:
+		switch {
+		case lt != nil && lt.Date != nil:
+			return parquet.Int32Value(int32(v.Unix() / secondsPerDay)), nil
+		case lt != nil && lt.Timestamp != nil:
+			return parquet.Int64Value(v.UnixMicro()), nil
+		}
+		return parquet.Value{}, fmt.Errorf("cannot write time to %s", node.Type())
+	case int8:
+		return parquet.Int32Value(int32(v)), nil
+	case int16:
+		return parquet.Int32Value(int32(v)), nil
+	case int32:
+		return parquet.Int32Value(v), nil
+	case int64:
+		return parquet.Int64Value(v), nil
+	case float32:
+		return parquet.FloatValue(v), nil
+	case float64:
+		return parquet.DoubleValue(v), nil
+	case bool:
+		return parquet.BooleanValue(v), nil
+	case string:
+		return parquet.ByteArrayValue([]byte(v)), nil
+	case []byte:
+		return parquet.ByteArrayValue(v), nil
+	case *big.Rat:
+		if lt == nil || lt.Decimal == nil {
+			return parquet.Value{}, fmt.Errorf("cannot write decimal to %s", node.Type())
+		}
+		unscaled, err := unscaledDecimal(v, int(lt.Decimal.Scale))
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		switch node.Type().Kind() {
+		case parquet.Int32:
+			return parquet.Int32Value(int32(unscaled.Int64())), nil
+		case parquet.Int64:
+			return parquet.Int64Value(unscaled.Int64()), nil
+		}
+		return parquet.FixedLenByteArrayValue(decimalBytes(unscaled, node.Type().Length())), nil
+	}
+	return parquet.Value{}, fmt.Errorf("unsupported value type %T", value)
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+// rowDecoder decodes parquet rows into records of the delta schema by assembling the
+// values of the leaf columns back into (possibly nested) values.
+type rowDecoder struct {
+	schema  *parquet.Schema
+	columns [][]parquet.Value
+	pos     []int
+}
+
+func newRowDecoder(schema *parquet.Schema) *rowDecoder {
+	n := len(schema.Columns())
+	return &rowDecoder{
+		schema:  schema,
+		columns: make([][]parquet.Value, n),
+		pos:     make([]int, n),
+	}
+}
+
+// decode returns the record of the fields read from the row. Fields that are not in
+// the parquet schema are set to nil.
+func (d *rowDecoder) decode(fields []*types.StructField, row parquet.Row) (map[string]any, error) {
+	for i := range d.columns {
+		d.columns[i] = d.columns[i][:0]
+		d.pos[i] = 0
+	}
+	for _, v := range row {
+		if c := v.Column(); c >= 0 && c < len(d.columns) {
+			d.columns[c] = append(d.columns[c], v)
+		}
+	}
+	return d.decodeGroup(d.schema, fields, 0, 0, 0)
+}
+
+func (d *rowDecoder) decodeGroup(node parquet.Node, fields []*types.StructField, column, def, depth int) (map[string]any, error) {
+	children := node.Fields()
+	offsets := make([]int, len(children))
+	for i, child := range children {
+		offsets[i] = column
+		column += numLeaves(child)
+	}
+
+	record := make(map[string]any, len(fields))
+	for _, field := range fields {
+		i := childIndex(children, field.Name)
+		if i < 0 {
+			record[field.Name] = nil
+			continue
+		}
+		v, err := d.decodeNode(children[i], field, offsets[i], def, depth)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", field.Name, err)
+		}
+		record[field.Name] = v
+	}
+	return record, nil
+}
+
+// decodeNode decodes the next value of the node whose first leaf is column.
+// def is the definition level of the parent and depth the number of repeated ancestors.
+func (d *rowDecoder) decodeNode(node parquet.Node, field *types.StructField, column, def, depth int) (any, error) {
+	if node.Optional() {
+		if d.peek(column).DefinitionLevel() <= def {
+			d.skip(node, column)
+			return nil, nil
+		}
+		def++
+	}
+
+	switch field.Type {
+	case "struct":
+		if node.Leaf() || field.StructType() == nil {
+			return nil, fmt.Errorf("cannot read %s as struct", node.Type())
+		}
+		return d.decodeGroup(node, field.StructType().Fields, column, def, depth)
+	case "array":
+		element, ok := listElement(node)
+		if !ok || field.ArrayType() == nil {
+			return nil, fmt.Errorf("cannot read %s as array", node.Type())
+		}
+		list := make([]any, 0)
+		if d.peek(column).DefinitionLevel() <= def {
+			d.skip(node, column)
+			return list, nil
+		}
+		elementField := types.NewStructField("element", field.ArrayType().ElementType, field.ArrayType().ContainsNull, nil)
+		for {
+			v, err := d.decodeNode(element, elementField, column, def+1, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			if !d.repeats(column, depth+1) {
+				return list, nil
+			}
+		}
+	case "map":
+		key, value, valueOffset, ok := mapKeyValue(node)
+		if !ok || field.MapType() == nil {
+			return nil, fmt.Errorf("cannot read %s as map", node.Type())
+		}
+		m := make(map[any]any)
+		if d.peek(column).DefinitionLevel() <= def {
+			d.skip(node, column)
+			return m, nil
+		}
+		keyField := types.NewStructField("key", field.MapType().KeyType, false, nil)
+		valueField := types.NewStructField("value", field.MapType().ValueType, field.MapType().ValueContainsNull, nil)
+		keyColumn, valueColumn := column, column+valueOffset
+		if valueOffset == 0 {
+			keyColumn = column + numLeaves(value)
+		}
+		for {
+			k, err := d.decodeNode(key, keyField, keyColumn, def+1, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.decodeNode(value, valueField, valueColumn, def+1, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+			if !d.repeats(keyColumn, depth+1) {
+				return m, nil
+			}
+		}
+	}
+
+	if !node.Leaf() {
+		return nil, fmt.Errorf("cannot read %s as %s", node.Type(), field.Type)
+	}
+	v := d.peek(column)
+	d.pos[column]++
+	return leafValue(node, field.Type, v)
+}
+
+// listElement returns the element node of a list node. Both the standard three-level
+// layout and the legacy two-level layout, where the repeated node is the element, are
+// supported.
+// https://github.com/apache/parquet-format/blob/master/LogicalTypes.md#lists
+func listElement(node parquet.Node) (parquet.Node, bool) {
+	fields := node.Fields()
+	if node.Leaf() || len(fields) != 1 || !fields[0].Repeated() {
+		return nil, false
+	}
+	repeated := fields[0]
+	if repeated.Leaf() || len(repeated.Fields()) != 1 || repeated.Name() == "array" || repeated.Name() == "bag" {
+		return repeated, true
+	}
+	return repeated.Fields()[0], true
+}
+
+// mapKeyValue returns the key and value nodes of a map node, and the offset of the first
+// leaf column of the value from the first leaf column of the map.
+// https://github.com/apache/parquet-format/blob/master/LogicalTypes.md#maps
+func mapKeyValue(node parquet.Node) (key, value parquet.Node, valueOffset int, ok bool) {
+	fields := node.Fields()
+	if node.Leaf() || len(fields) != 1 || !fields[0].Repeated() || len(fields[0].Fields()) != 2 {
+		return nil, nil, 0, false
+	}
+	keyValue := fields[0].Fields()
+	k, v := childIndex(keyValue, "key"), childIndex(keyValue, "value")
+	if k < 0 || v < 0 || k == v {
+		k, v = 0, 1
+	}
+	if v > k {
+		valueOffset = numLeaves(keyValue[k])
+	}
+	return keyValue[k], keyValue[v], valueOffset, true
+}
+
+// peek returns the next value of the column, or a null value if there is none left.
+func (d *rowDecoder) peek(column int) parquet.Value {
+	if d.pos[column] < len(d.columns[column]) {
+		return d.columns[column][d.pos[column]]
+	}
+	return parquet.Value{}
+}
+
+// repeats returns true if the next value of the column continues the repeated
+// node at the repetition level.
+func (d *rowDecoder) repeats(column, level int) bool {
+	return d.pos[column] < len(d.columns[column]) && d.columns[column][d.pos[column]].RepetitionLevel() == level
+}
+
+// skip consumes one value of every leaf column of the node.
+func (d *rowDecoder) skip(node parquet.Node, column int) {
+	for i := column; i < column+numLeaves(node); i++ {
+		d.pos[i]++
+	}
+}
+
+// childIndex returns the index of the field named name, preferring an exact match
+// over a case-insensitive match, or -1 if there is none.
+func childIndex(fields []parquet.Field, name string) int {
+	index := -1
+	for i, field := range fields {
+		if field.Name() == name {
+			return i
+		}
+		if index < 0 && strings.EqualFold(field.Name(), name) {
+			index = i
+		}
+	}
+	return index
+}
+
+// julianDayOfUnixEpoch is the julian day of 1970-01-01, used to decode INT96 timestamps.
+const julianDayOfUnixEpoch = 2440588
+
+// leafValue converts the physical value of a leaf node to the Go type of the delta type.
+func leafValue(node parquet.Node, dt types.DataType, v parquet.Value) (any, error) {
+	if v.IsNull() {
+		return nil, nil
+	}
+	if _, scale, ok := dt.Decimal(); ok {
+		var unscaled *big.Int
+		switch v.Kind() {
+		case parquet.Int32:
+			unscaled = big.NewInt(int64(v.Int32()))
+		case parquet.Int64:
+			unscaled = big.NewInt(v.Int64())
+		case parquet.ByteArray, parquet.FixedLenByteArray:
+			unscaled = decimalFromBytes(v.ByteArray())
+		default:
+			return nil, fmt.Errorf("cannot read %s as %s", v.Kind(), dt)
+		}
+		return new(big.Rat).SetFrac(unscaled, pow10(scale)), nil
+	}
+
+	switch dt {
+	case types.DataTypeLong, types.DataTypeInteger, types.DataTypeShort, types.DataTypeByte:
+		var i int64
+		switch v.Kind() {
+		case parquet.Int32:
+			i = int64(v.Int32())
+		case parquet.Int64:
+			i = v.Int64()
+		default:
+			return nil, fmt.Errorf("cannot read %s as %s", v.Kind(), dt)
+		}
+		switch dt {
+		case types.DataTypeInteger:
+			return int32(i), nil
+		case types.DataTypeShort:
+			return int16(i), nil
+		case types.DataTypeByte:
+			return int8(i), nil
+		}
+		return i, nil
+	case types.DataTypeDouble, types.DataTypeFloat:
+		var f float64
+		switch v.Kind() {
+		case parquet.Float:
+			f = float64(v.Float())
+		case parquet.Double:
+			f = v.Double()
+		default:
+			return nil, fmt.Errorf("cannot read %s as %s", v.Kind(), dt)
+		}
+		if dt == types.DataTypeFloat {
+			return float32(f), nil
+		}
+		return f, nil
+	case types.DataTypeString:
+		if v.Kind() == parquet.ByteArray || v.Kind() == parquet.FixedLenByteArray {
+			return string(v.ByteArray()), nil
+		}
+	case types.DataTypeBinary:
+		if v.Kind() == parquet.ByteArray || v.Kind() == parquet.FixedLenByteArray {
+			return append([]byte(nil), v.ByteArray()...), nil
+		}
+	case types.DataTypeBoolean, types.DataTypeBool:
+		if v.Kind() == parquet.Boolean {
+			return v.Boolean(), nil
+		}
+	case types.DataTypeDate:
+		if v.Kind() == parquet.Int32 {
+			return time.Unix(int64(v.Int32())*secondsPerDay, 0).UTC(), nil
+		}
+	case types.DataTypeTimestamp:
+		switch v.Kind() {
+		case parquet.Int64:
+			return timestampValue(node, v.Int64()), nil
+		case parquet.Int96:
+			i := v.Int96()
+			nanos := int64(uint64(i[1])<<32 | uint64(i[0]))
+			days := int64(i[2]) - julianDayOfUnixEpoch
+			return time.Unix(days*secondsPerDay, nanos).UTC(), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", dt)
+	}
+	return nil, fmt.Errorf("cannot read %s as %s", v.Kind(), dt)
+}
+
+// timestampValue converts an INT64 timestamp to a time using the unit of the node.
+// Timestamps without a unit are assumed to be in microseconds.
+func timestampValue(node parquet.Node, ts int64) time.Time {
+	if lt := node.Type().LogicalType(); lt != nil && lt.Timestamp != nil {
+		switch {
+		case lt.Timestamp.Unit.Millis != nil:
+			return time.UnixMilli(ts).UTC()
+		case lt.Timestamp.Unit.Nanos != nil:
+			return time.Unix(0, ts).UTC()
+		}
+	}
+	if ct := node.Type().ConvertedType(); ct != nil && *ct == deprecated.TimestampMillis {
+		return time.UnixMilli(ts).UTC()
+	}
+	return time.UnixMicro(ts).UTC()
+}
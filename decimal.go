@@ -0,0 +1,124 @@
+package deltalake
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"deltalake/types"
+)
+
+// parseDecimal parses the string representation of a value of the decimal type, like
+// "12.30" for decimal(4,2). An error is returned if the value does not fit the precision
+// and scale of the type.
+func parseDecimal(dt types.DataType, s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	if err := checkDecimal(dt, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// checkDecimal returns an error if the value does not fit the precision and scale of
+// the decimal type.
+func checkDecimal(dt types.DataType, r *big.Rat) error {
+	precision, scale, ok := dt.Decimal()
+	if !ok {
+		return fmt.Errorf("invalid decimal type %s", dt)
+	}
+	// the unscaled value must be an integer with at most precision digits
+	unscaled, err := unscaledDecimal(r, scale)
+	if err != nil {
+		return err
+	}
+	if new(big.Int).Abs(unscaled).Cmp(pow10(precision)) >= 0 {
+		return fmt.Errorf("decimal %s overflows %s", r.FloatString(scale), dt)
+	}
+	return nil
+}
+
+// formatDecimal returns the string representation of a value of the decimal type, with
+// as many digits after the decimal point as the scale of the type.
+func formatDecimal(dt types.DataType, r *big.Rat) (string, error) {
+	if err := checkDecimal(dt, r); err != nil {
+		return "", err
+	}
+	_, scale, _ := dt.Decimal()
+	return r.FloatString(scale), nil
+}
+
+// convertDecimal converts a *big.Rat, a string or a number to a value of the decimal type.
+func convertDecimal(dt types.DataType, rv reflect.Value) (any, error) {
+	switch {
+	case rv.Type() == ratType: // pointers are dereferenced by convertValue
+		v := rv.Interface().(big.Rat)
+		r := new(big.Rat).Set(&v)
+		if err := checkDecimal(dt, r); err != nil {
+			return nil, err
+		}
+		return r, nil
+	case rv.Kind() == reflect.String:
+		return parseDecimal(dt, rv.String())
+	case rv.Kind() == reflect.Float32 || rv.Kind() == reflect.Float64:
+		return parseDecimal(dt, strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits()))
+	case isInteger(rv.Kind()):
+		r := toRat(rv)
+		if err := checkDecimal(dt, r); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("cannot use %s as %s", rv.Type(), dt)
+}
+
+// unscaledDecimal returns the unscaled integer of a decimal value with the scale, for
+// example 1230 for 12.30 with scale 2.
+func unscaledDecimal(r *big.Rat, scale int) (*big.Int, error) {
+	unscaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(scale)))
+	if !unscaled.IsInt() {
+		return nil, fmt.Errorf("decimal %s has more than %d digits after the decimal point", r.FloatString(scale+1), scale)
+	}
+	return unscaled.Num(), nil
+}
+
+// decimalByteLength returns the number of bytes needed to store the unscaled values of
+// decimals with the precision as big-endian two's complement integers.
+func decimalByteLength(precision int) int {
+	limit := pow10(precision)
+	n := 1
+	for new(big.Int).Lsh(big.NewInt(1), uint(8*n-1)).Cmp(limit) < 0 {
+		n++
+	}
+	return n
+}
+
+// decimalBytes returns the unscaled value as a big-endian two's complement integer of
+// n bytes.
+func decimalBytes(unscaled *big.Int, n int) []byte {
+	b := make([]byte, n)
+	if unscaled.Sign() >= 0 {
+		return unscaled.FillBytes(b)
+	}
+	// the two's complement of a negative value is 2^(8n) + value
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+	return new(big.Int).Add(modulus, unscaled).FillBytes(b)
+}
+
+// decimalFromBytes returns the unscaled value of a big-endian two's complement integer.
+func decimalFromBytes(b []byte) *big.Int {
+	i := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		i.Sub(i, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return i
+}
+
+var ratType = reflect.TypeOf(big.Rat{})
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,179 @@
+package deltalake
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/types"
+)
+
+// DeleteMetrics reports the work done by Delete. The files added are written with the rows
+// kept from rewritten files, and the files removed are entirely deleted or rewritten.
+type DeleteMetrics struct {
+	// Version is the version committed by Delete, or the current version if no row matched.
+	Version int64
+	FileMetrics
+	// NumDeletedRows is the number of rows deleted.
+	NumDeletedRows int64
+	// NumCopiedRows is the number of rows kept from rewritten files.
+	NumCopiedRows int64
+}
+
+// operationMetrics returns the metrics of the DELETE commitInfo, with the row counts.
+func (m *DeleteMetrics) operationMetrics() map[string]interface{} {
+	metrics := m.FileMetrics.operationMetrics()
+	metrics["numDeletedRows"] = strconv.FormatInt(m.NumDeletedRows, 10)
+	metrics["numCopiedRows"] = strconv.FormatInt(m.NumCopiedRows, 10)
+	return metrics
+}
+
+// Delete deletes the rows of the table matching the predicate, or all the rows if the
+// predicate is nil. Rows for which the predicate is null are kept, as in SQL.
+//
+// Files that cannot contain matching rows, according to their partition values and
+// statistics, are not read. Files whose partition values and statistics prove that all
+// their rows match are removed without being read, unless the change data feed of the
+// table is enabled or the predicate uses float or double columns, whose statistics may
+// leave out NaN and infinities. The other files are read and, if some of their rows match,
+// replaced by a file of the rows that do not match. Removed and added files, and the change
+// data files of the deleted rows if the change data feed is enabled, are committed in a
+// single DELETE commit. Nothing is committed if no row matches.
+//
+// The commit fails with ErrCommitConflict if a concurrent commit removed any of the
+// removed files. Rows appended concurrently are not deleted. Tables whose writer version is
+// not supported are refused before any file is written.
+func (t *Table) Delete(pred Expr) (*DeleteMetrics, error) {
+	if t.State.CurrentMetadata == nil {
+		return nil, errors.New("table has no metadata")
+	}
+	if !t.Config.RequireFiles {
+		return nil, errors.New("delete requires a table state loaded with files")
+	}
+	// the commit would be refused after the rewritten files are written
+	if err := t.State.checkWriterVersion(); err != nil {
+		return nil, err
+	}
+	match := pred
+	if match == nil {
+		match = Lit(true)
+	} else if dt, err := exprType(&t.State.CurrentMetadata.Schema, pred); err != nil {
+		return nil, err
+	} else if dt != types.DataTypeBoolean && dt != types.DataTypeNull {
+		return nil, fmt.Errorf("predicate %s is of type %s, not boolean", pred, dt)
+	}
+
+	files, err := t.State.FilesMatching(match)
+	if err != nil {
+		return nil, err
+	}
+	metrics := &DeleteMetrics{Version: t.State.Version}
+	tx := t.NewTransaction(WithOperation("DELETE", map[string]interface{}{
+		"predicate": predicateParameter(pred),
+	}))
+	tx.AddReadPredicate(match)
+	changes, err := t.newChangeWriter()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	for _, add := range files {
+		f := &fileSkipping{metadata: t.State.CurrentMetadata, add: add}
+		all, err := f.mustMatch(match)
+		if err != nil {
+			return nil, err
+		}
+		numRecords, ok := add.NumRecords()
+		// the deleted rows must be read to be written to change data files
+		if !all || !ok || changes != nil {
+			adds, deleted, copied, err := t.rewriteFileWithout(add, match, changes)
+			if err != nil {
+				return nil, err
+			}
+			if deleted == 0 {
+				continue
+			}
+			for _, a := range adds {
+				tx.AddAction(a)
+				metrics.fileAdded(a)
+			}
+			numRecords = deleted
+			metrics.NumCopiedRows += copied
+		}
+		tx.AddAction(actions.NewRemove(add.Path, now, true, true, add.PartitionValues, add.Size, add.Tags))
+		metrics.fileRemoved(add)
+		metrics.NumDeletedRows += numRecords
+	}
+
+	log.Debug().
+		Int("candidates", len(files)).
+		Int64("filesRemoved", metrics.NumFilesRemoved).
+		Int64("deletedRows", metrics.NumDeletedRows).
+		Msg("delete")
+	if metrics.NumFilesRemoved == 0 {
+		return metrics, nil
+	}
+	cdcs, err := closeChangeWriter(changes)
+	if err != nil {
+		return nil, err
+	}
+	for _, cdc := range cdcs {
+		tx.AddAction(cdc)
+	}
+
+	tx.SetOperationMetrics(metrics.operationMetrics())
+	version, err := tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	metrics.Version = version
+	return metrics, nil
+}
+
+// rewriteFileWithout reads the rows of the file and writes those not matching the
+// predicate to a new data file, and those matching to the change data writer, unless it is
+// nil. It returns the add actions of the written files, none if all the rows match or none
+// match, and the number of deleted and copied rows.
+func (t *Table) rewriteFileWithout(add *actions.Add, pred Expr, changes *Writer) ([]*actions.Add, int64, int64, error) {
+	scanner := newScanner(t.Storage, t.State.CurrentMetadata, []*actions.Add{add})
+	defer scanner.Close()
+	writer, err := t.NewWriter()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	var deleted, copied int64
+	for scanner.Next() {
+		row := scanner.Row()
+		ok, err := Matches(pred, row)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if ok {
+			if err := writeChange(changes, ChangeDelete, row); err != nil {
+				return nil, 0, 0, err
+			}
+			deleted++
+			continue
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, 0, 0, err
+		}
+		copied++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+	// the writer only writes files when closed
+	if deleted == 0 || copied == 0 {
+		return nil, deleted, 0, nil
+	}
+	adds, err := writer.Close()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return adds, deleted, copied, nil
+}
@@ -0,0 +1,166 @@
+package deltalake
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+func TestTable_Delete(t *testing.T) {
+	// the table has ids 0-29 in three files on 2021-01-01, 30-39 on 2021-01-02 and 40-59
+	// in two files on 2021-01-03
+	tests := map[string]struct {
+		pred        Expr
+		wantMetrics DeleteMetrics
+		wantCommit  bool
+	}{
+		"partition": {
+			pred:        Eq(Col("date"), Lit("2021-01-02")),
+			wantMetrics: DeleteMetrics{FileMetrics: FileMetrics{NumFilesRemoved: 1}, NumDeletedRows: 10},
+			wantCommit:  true,
+		},
+		"whole files by stats": {
+			pred:        Lt(Col("id"), Lit(20)),
+			wantMetrics: DeleteMetrics{FileMetrics: FileMetrics{NumFilesRemoved: 2}, NumDeletedRows: 20},
+			wantCommit:  true,
+		},
+		"single row": {
+			pred:        Eq(Col("id"), Lit(5)),
+			wantMetrics: DeleteMetrics{FileMetrics: FileMetrics{NumFilesRemoved: 1, NumFilesAdded: 1}, NumDeletedRows: 1, NumCopiedRows: 9},
+			wantCommit:  true,
+		},
+		"whole and partial files": {
+			pred:        Or(Lt(Col("id"), Lit(15)), Eq(Col("date"), Lit("2021-01-03"))),
+			wantMetrics: DeleteMetrics{FileMetrics: FileMetrics{NumFilesRemoved: 4, NumFilesAdded: 1}, NumDeletedRows: 35, NumCopiedRows: 5},
+			wantCommit:  true,
+		},
+		"all rows": {
+			wantMetrics: DeleteMetrics{FileMetrics: FileMetrics{NumFilesRemoved: 6}, NumDeletedRows: 60},
+			wantCommit:  true,
+		},
+		"no match": {
+			pred: Gt(Col("id"), Lit(100)),
+		},
+		"no match in candidate file": {
+			pred: And(Gt(Col("id"), Lit(0)), Lt(Col("id"), Lit(1))),
+		},
+		"null is kept": {
+			pred: Ne(Col("name"), Lit("a")),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			version := tbl.State.Version
+			rows := scanAll(t, tbl)
+			var want []int64
+			for _, row := range rows {
+				if test.pred != nil {
+					ok, err := Matches(test.pred, row)
+					require.NoError(t, err)
+					if !ok {
+						want = append(want, row["id"].(int64))
+					}
+				}
+			}
+
+			metrics, err := tbl.Delete(test.pred)
+			require.NoError(t, err)
+			got := *metrics
+			got.Version, got.NumBytesAdded, got.NumBytesRemoved = 0, 0, 0
+			require.Equal(t, test.wantMetrics, got)
+			ids := sortedIDs(scanAll(t, tbl))
+			if len(want) == 0 {
+				require.Empty(t, ids)
+			} else {
+				require.Equal(t, sortedIDs(rowsWithIDs(want)), ids)
+			}
+
+			if !test.wantCommit {
+				require.Equal(t, version, metrics.Version)
+				require.Equal(t, version, tbl.State.Version)
+				return
+			}
+			require.Equal(t, version+1, metrics.Version)
+
+			acts, err := tbl.peakNextCommit(version)
+			require.NoError(t, err)
+			for _, action := range acts {
+				switch a := action.(type) {
+				case *actions.Add:
+					require.True(t, a.DataChange)
+				case *actions.Remove:
+					require.True(t, a.DataChange)
+				}
+			}
+			history, err := tbl.History(1)
+			require.NoError(t, err)
+			require.Equal(t, "DELETE", history[0].CommitInfo.Operation)
+			require.Equal(t, predicateParameter(test.pred), history[0].CommitInfo.OperationParameters["predicate"])
+			require.Equal(t, metrics.operationMetrics(), history[0].CommitInfo.OperationMetrics)
+		})
+	}
+}
+
+// rowsWithIDs returns rows with the ids.
+func rowsWithIDs(ids []int64) []map[string]any {
+	rows := make([]map[string]any, len(ids))
+	for i, id := range ids {
+		rows[i] = map[string]any{"id": id}
+	}
+	return rows
+}
+
+func TestTable_DeleteNonFinite(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	schema := *types.NewStruct(types.NewStructField("x", types.DataTypeDouble, true, nil))
+	tbl, err := CreateTable(store, &TableMetadata{Schema: schema})
+	require.NoError(t, err)
+	w, err := tbl.NewWriter()
+	require.NoError(t, err)
+	require.NoError(t, w.Write(
+		map[string]any{"x": 1.0},
+		map[string]any{"x": math.Inf(1)},
+		map[string]any{"x": math.NaN()},
+	))
+	adds, err := w.Close()
+	require.NoError(t, err)
+	tx := tbl.NewTransaction()
+	tx.AddActions(toActions(adds)...)
+	_, err = tx.Commit()
+	require.NoError(t, err)
+
+	// the infinite and NaN rows do not match and are kept
+	metrics, err := tbl.Delete(Le(Col("x"), Lit(100.0)))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), metrics.NumDeletedRows)
+	require.Equal(t, int64(2), metrics.NumCopiedRows)
+	rows := scanAll(t, tbl)
+	require.Len(t, rows, 2)
+	for _, row := range rows {
+		x := row["x"].(float64)
+		require.True(t, math.IsInf(x, 1) || math.IsNaN(x))
+	}
+
+	_, err = tbl.Delete(Col("x"))
+	require.Error(t, err)
+}
+
+func TestTable_DeleteUnsupportedProtocol(t *testing.T) {
+	tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+	tbl.State.MinWriterVersion = 3
+	objects, err := tbl.Storage.List("")
+	require.NoError(t, err)
+
+	_, err = tbl.Delete(Eq(Col("id"), Lit(1)))
+	require.Error(t, err)
+	after, err := tbl.Storage.List("")
+	require.NoError(t, err)
+	require.Len(t, after, len(objects))
+}
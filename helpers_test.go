@@ -0,0 +1,102 @@
+package deltalake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+func testSchema() types.StructType {
+	return *types.NewStruct(
+		types.NewStructField("id", types.DataTypeLong, false, nil),
+		types.NewStructField("name", types.DataTypeString, true, nil),
+		types.NewStructField("date", types.DataTypeDate, true, nil),
+	)
+}
+
+// testCommit is a commit of a test table, of data files of the rows, other actions and the
+// removal of files of earlier commits.
+type testCommit struct {
+	rows    []map[string]any
+	actions []actions.Action
+	// remove are the indexes of the data files to remove, in the order they were written
+	remove []int
+}
+
+// writeTestTable creates a table with the metadata in the storage, or in a temporary
+// directory if store is nil, and commits each of the commits in its own version. The
+// metadata defaults to an unpartitioned table of testSchema.
+func writeTestTable(t *testing.T, store storage.ObjectStorage, metadata *TableMetadata, commits ...testCommit) *Table {
+	t.Helper()
+	if store == nil {
+		var err error
+		store, err = storage.NewLocalStorage(t.TempDir())
+		require.NoError(t, err)
+	}
+	if metadata == nil {
+		metadata = NewTableMetadata("test", "", actions.DefaultFormat, testSchema(), nil, nil)
+	}
+	tbl, err := CreateTable(store, metadata)
+	require.NoError(t, err)
+
+	var written []*actions.Add
+	for _, commit := range commits {
+		tx := tbl.NewTransaction()
+		if len(commit.rows) > 0 {
+			w, err := tbl.NewWriter()
+			require.NoError(t, err)
+			for _, row := range commit.rows {
+				require.NoError(t, w.Write(row))
+			}
+			adds, err := w.Close()
+			require.NoError(t, err)
+			tx.AddActions(toActions(adds)...)
+			written = append(written, adds...)
+		}
+		tx.AddActions(commit.actions...)
+		for _, i := range commit.remove {
+			add := written[i]
+			tx.AddAction(actions.NewRemove(add.Path, time.Now().UnixMilli(), true, true, add.PartitionValues, add.Size, nil))
+		}
+		_, err = tx.Commit()
+		require.NoError(t, err)
+	}
+	return tbl
+}
+
+// writePartitionedTestTable creates a table of testSchema partitioned by date in the storage,
+// or in a temporary directory if store is nil, with a commit of a file of rowsPerFile rows for
+// each of filesPerDay[i] files on the i-th day from 2021-01-01, followed by the other
+// commits. The rows are named "a" and have consecutive ids from 0.
+func writePartitionedTestTable(t *testing.T, store storage.ObjectStorage, rowsPerFile int, filesPerDay []int, commits ...testCommit) *Table {
+	t.Helper()
+	metadata := NewTableMetadata("test", "", actions.DefaultFormat, testSchema(), []string{"date"}, nil)
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	var files []testCommit
+	id := 0
+	for i, n := range filesPerDay {
+		for j := 0; j < n; j++ {
+			var rows []map[string]any
+			for k := 0; k < rowsPerFile; k++ {
+				rows = append(rows, map[string]any{"id": id, "name": "a", "date": day.AddDate(0, 0, i)})
+				id++
+			}
+			files = append(files, testCommit{rows: rows})
+		}
+	}
+	return writeTestTable(t, store, metadata, append(files, commits...)...)
+}
+
+// toActions returns the add actions as actions.
+func toActions(adds []*actions.Add) []actions.Action {
+	out := make([]actions.Action, len(adds))
+	for i, add := range adds {
+		out[i] = add
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package deltalake
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+)
+
+// HistoryEntry describes a commit of the table.
+type HistoryEntry struct {
+	// Version is the version the commit created.
+	Version int64
+	// Timestamp is the time of the commit, from its commitInfo or else the modification
+	// time of the commit file.
+	Timestamp time.Time
+	// CommitInfo is the commitInfo of the commit, empty if the commit has none.
+	CommitInfo actions.CommitInfo
+}
+
+// History returns the commits of the table, newest first, like DESCRIBE HISTORY.
+// At most limit entries are returned, or all of them if limit is not positive.
+// The commit files are read directly, so the history includes commits newer than the
+// loaded state but not commits that have been removed by log cleanup.
+func (t *Table) History(limit int) ([]HistoryEntry, error) {
+	listing, err := t.listLog()
+	if err != nil {
+		return nil, err
+	}
+	n := len(listing.commits)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+
+	entries := make([]HistoryEntry, 0, n)
+	for i := len(listing.commits) - 1; i >= 0 && len(entries) < n; i-- {
+		commit := listing.commits[i]
+		entry := HistoryEntry{Version: commit.Version}
+		commitInfo, err := t.readCommitInfo(commit.Version)
+		if err != nil {
+			return nil, err
+		}
+		if commitInfo != nil {
+			entry.CommitInfo = *commitInfo
+		}
+		ts := entry.CommitInfo.Timestamp
+		if ts == 0 {
+			if ts, err = t.commitTimestamp(commit); err != nil {
+				return nil, err
+			}
+		} else {
+			t.VersionTimestamps[commit.Version] = ts
+		}
+		entry.Timestamp = time.UnixMilli(ts).UTC()
+		entries = append(entries, entry)
+	}
+	log.Debug().Int("limit", limit).Int("entries", len(entries)).Msg("read history")
+	return entries, nil
+}
@@ -0,0 +1,95 @@
+package deltalake
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+	"deltalake/storage"
+)
+
+func TestTable_History(t *testing.T) {
+	tests := map[string]struct {
+		limit          int
+		wantVersions   []int64
+		wantOperations []string
+	}{
+		"all":          {limit: 0, wantVersions: []int64{4, 3, 2, 1, 0}, wantOperations: []string{"DELETE", "UPDATE", "WRITE", "MERGE", "WRITE"}},
+		"limited":      {limit: 2, wantVersions: []int64{4, 3}, wantOperations: []string{"DELETE", "UPDATE"}},
+		"beyond start": {limit: 10, wantVersions: []int64{4, 3, 2, 1, 0}, wantOperations: []string{"DELETE", "UPDATE", "WRITE", "MERGE", "WRITE"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, err := storage.NewLocalStorage("testdata/simple_table")
+			require.NoError(t, err)
+			tbl, err := LoadTable(store, nil)
+			require.NoError(t, err)
+
+			history, err := tbl.History(test.limit)
+			require.NoError(t, err)
+			var versions []int64
+			var operations []string
+			for _, entry := range history {
+				versions = append(versions, entry.Version)
+				operations = append(operations, entry.CommitInfo.Operation)
+			}
+			require.Equal(t, test.wantVersions, versions)
+			require.Equal(t, test.wantOperations, operations)
+		})
+	}
+}
+
+func TestTable_HistoryCommitInfo(t *testing.T) {
+	store, err := storage.NewLocalStorage("testdata/simple_table")
+	require.NoError(t, err)
+	tbl, err := LoadTable(store, nil)
+	require.NoError(t, err)
+
+	history, err := tbl.History(1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	entry := history[0]
+	require.Equal(t, time.UnixMilli(1587968626537).UTC(), entry.Timestamp)
+	require.Equal(t, int64(3), *entry.CommitInfo.ReadVersion)
+	require.False(t, *entry.CommitInfo.IsBlindAppend)
+	require.Contains(t, entry.CommitInfo.OperationParameters, "predicate")
+	require.Equal(t, int64(1587968626537), tbl.VersionTimestamps[4])
+}
+
+func TestTable_HistoryWithoutCommitInfo(t *testing.T) {
+	path := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(path, LogDirName), 0o755))
+	commit := `{"protocol":{"minReaderVersion":1,"minWriterVersion":2}}` + "\n" +
+		`{"metaData":{"id":"1","format":{"provider":"parquet","options":{}},"schemaString":"{\"type\":\"struct\",\"fields\":[]}","partitionColumns":[],"configuration":{}}}`
+	commitPath := filepath.Join(path, CommitURIFromVersion(0))
+	require.NoError(t, os.WriteFile(commitPath, []byte(commit), 0o644))
+	modified := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(commitPath, modified, modified))
+
+	store, err := storage.NewLocalStorage(path)
+	require.NoError(t, err)
+	tbl, err := LoadTable(store, nil)
+	require.NoError(t, err)
+	tx := tbl.NewTransaction(WithOperation("DELETE", map[string]interface{}{"predicate": "id = 1"}))
+	tx.AddAction(actions.NewAdd("a.parquet", 1, nil, true, 1, nil, nil))
+	_, err = tx.Commit()
+	require.NoError(t, err)
+
+	history, err := tbl.History(0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	require.Equal(t, int64(1), history[0].Version)
+	require.Equal(t, "DELETE", history[0].CommitInfo.Operation)
+	require.Equal(t, "id = 1", history[0].CommitInfo.OperationParameters["predicate"])
+	require.Equal(t, engineInfo, history[0].CommitInfo.EngineInfo)
+
+	// the first commit has no commitInfo, so the modification time of the file is used
+	require.Equal(t, int64(0), history[1].Version)
+	require.Equal(t, actions.CommitInfo{}, history[1].CommitInfo)
+	require.Equal(t, modified, history[1].Timestamp)
+}
@@ -0,0 +1,269 @@
+package deltalake
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"deltalake/storage"
+)
+
+// logFile is a file in the delta log that is either a commit or a checkpoint (part).
+type logFile struct {
+	// Path is the path of the file relative to the root of the table.
+	Path string
+	// Version is the version of the table the file belongs to.
+	Version int64
+	// Checkpoint is true if the file is a checkpoint (part), false if it is a commit.
+	Checkpoint bool
+	// Part is the part number of a multi-part checkpoint. Zero for single part checkpoints and commits.
+	Part int
+	// Parts is the total number of parts of a multi-part checkpoint. Zero for single part checkpoints and commits.
+	Parts int
+	// Info is the object info returned by the storage.
+	Info storage.ObjectInfo
+}
+
+// parseLogFile parses the name of a file in the delta log.
+// It returns false if the name is not a commit or checkpoint file.
+//
+//	"00000000000000000010.json"
+//	"00000000000000000010.checkpoint.parquet"
+//	"00000000000000000010.checkpoint.0000000001.0000000003.parquet"
+func parseLogFile(name string) (logFile, bool) {
+	base := path.Base(name)
+	parts := strings.Split(base, ".")
+	if len(parts) < 2 || len(parts[0]) != 20 {
+		return logFile{}, false
+	}
+	version, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return logFile{}, false
+	}
+
+	f := logFile{Path: path.Join(LogDirName, base), Version: version}
+	switch {
+	case len(parts) == 2 && parts[1] == "json":
+		return f, true
+	case len(parts) == 3 && parts[1] == "checkpoint" && parts[2] == "parquet":
+		f.Checkpoint = true
+		return f, true
+	case len(parts) == 5 && parts[1] == "checkpoint" && parts[4] == "parquet":
+		part, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return logFile{}, false
+		}
+		total, err := strconv.Atoi(parts[3])
+		if err != nil {
+			return logFile{}, false
+		}
+		f.Checkpoint = true
+		f.Part = part
+		f.Parts = total
+		return f, true
+	}
+	return logFile{}, false
+}
+
+// logListing is the content of the delta log directory.
+type logListing struct {
+	// commits are the commit files sorted by version.
+	commits []logFile
+	// checkpoints are the complete checkpoints sorted by version.
+	// Multi-part checkpoints with missing parts are ignored.
+	checkpoints []*Checkpoint
+	// checkpointFiles are all checkpoint files (including parts) sorted by version.
+	checkpointFiles []logFile
+}
+
+// listLog lists the commits and checkpoints in the delta log.
+func (t *Table) listLog() (*logListing, error) {
+	infos, err := t.Storage.List(LogDirName)
+	if err != nil {
+		return nil, err
+	}
+
+	listing := &logListing{}
+	parts := make(map[[2]int64]int) // (version, parts) -> number of parts found
+	for _, info := range infos {
+		f, ok := parseLogFile(info.Path)
+		if !ok {
+			continue
+		}
+		f.Info = info
+		if !f.Checkpoint {
+			listing.commits = append(listing.commits, f)
+			continue
+		}
+		listing.checkpointFiles = append(listing.checkpointFiles, f)
+		parts[[2]int64{f.Version, int64(f.Parts)}]++
+	}
+
+	for key, found := range parts {
+		version, total := key[0], int(key[1])
+		if total > 1 && found != total { // incomplete multi-part checkpoint
+			continue
+		}
+		listing.checkpoints = append(listing.checkpoints, &Checkpoint{Version: version, Parts: total})
+	}
+
+	sort.Slice(listing.commits, func(i, j int) bool {
+		return listing.commits[i].Version < listing.commits[j].Version
+	})
+	sort.Slice(listing.checkpointFiles, func(i, j int) bool {
+		a, b := listing.checkpointFiles[i], listing.checkpointFiles[j]
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Part < b.Part
+	})
+	sort.Slice(listing.checkpoints, func(i, j int) bool {
+		a, b := listing.checkpoints[i], listing.checkpoints[j]
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Parts < b.Parts
+	})
+	return listing, nil
+}
+
+// latestVersion returns the latest version found in the log, or -1 if the log is empty.
+func (l *logListing) latestVersion() int64 {
+	latest := int64(-1)
+	if len(l.commits) > 0 {
+		latest = l.commits[len(l.commits)-1].Version
+	}
+	if len(l.checkpoints) > 0 && l.checkpoints[len(l.checkpoints)-1].Version > latest {
+		latest = l.checkpoints[len(l.checkpoints)-1].Version
+	}
+	return latest
+}
+
+// checkpointAtOrBefore returns the newest complete checkpoint with a version less than
+// or equal to the given version, or nil if there is none.
+func (l *logListing) checkpointAtOrBefore(version int64) *Checkpoint {
+	var found *Checkpoint
+	for _, checkpoint := range l.checkpoints {
+		if checkpoint.Version > version {
+			break
+		}
+		found = checkpoint
+	}
+	return found
+}
+
+// hasCommits returns true if every commit in the range [from, to] is present in the log.
+func (l *logListing) hasCommits(from, to int64) bool {
+	if from > to {
+		return true
+	}
+	i := sort.Search(len(l.commits), func(i int) bool {
+		return l.commits[i].Version >= from
+	})
+	for v := from; v <= to; v++ {
+		if i >= len(l.commits) || l.commits[i].Version != v {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// DefaultLogRetentionMillis is how long commits are kept in the log when the table does not
+// configure a retention, 30 days like delta.logRetentionDuration.
+const DefaultLogRetentionMillis = 30 * 24 * 60 * 60 * 1000
+
+// DefaultLogCleanupConcurrency is the number of log files deleted at once by
+// CleanupExpiredLogs and after writing a checkpoint.
+const DefaultLogCleanupConcurrency = 8
+
+// LogCleanupOption configures CleanupExpiredLogs.
+type LogCleanupOption func(*logCleanupOptions)
+
+type logCleanupOptions struct {
+	concurrency int
+}
+
+// WithLogCleanupConcurrency sets the number of log files deleted at once.
+// Defaults to DefaultLogCleanupConcurrency.
+func WithLogCleanupConcurrency(n int) LogCleanupOption {
+	return func(o *logCleanupOptions) {
+		o.concurrency = n
+	}
+}
+
+// CleanupExpiredLogs deletes the commits and checkpoints that are older than the log retention
+// of the table and returns their paths. Only files of versions before a checkpoint that
+// can reconstruct every retained version are deleted, so the versions committed within the
+// retention can still be loaded.
+func (t *Table) CleanupExpiredLogs(opts ...LogCleanupOption) ([]string, error) {
+	options := logCleanupOptions{concurrency: DefaultLogCleanupConcurrency}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+	return t.cleanupExpiredLogs(time.Now(), options.concurrency)
+}
+
+func (t *Table) cleanupExpiredLogs(now time.Time, concurrency int) ([]string, error) {
+	retention := t.State.LogRetentionMillis
+	if retention <= 0 {
+		retention = DefaultLogRetentionMillis
+	}
+	listing, err := t.listLog()
+	if err != nil {
+		return nil, err
+	}
+	cutoff := now.Add(-time.Duration(retention) * time.Millisecond)
+	expired := listing.expiredFiles(cutoff)
+
+	paths := make([]string, len(expired))
+	for i, f := range expired {
+		paths[i] = f.Path
+	}
+	log.Debug().
+		Time("cutoff", cutoff).
+		Int("files", len(paths)).
+		Msg("cleaning up expired logs")
+	if err := t.deleteFiles(paths, concurrency); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// expiredFiles returns the commits and checkpoint files that can be deleted because every
+// version from the first one modified after the cutoff can be reconstructed without them.
+// These are the files of the versions before the newest checkpoint that is not newer than
+// the first retained version.
+func (l *logListing) expiredFiles(cutoff time.Time) []logFile {
+	lastExpired := int64(-1)
+	for _, commit := range l.commits {
+		if !commit.Info.LastModified.Before(cutoff) {
+			break
+		}
+		lastExpired = commit.Version
+	}
+	checkpoint := l.checkpointAtOrBefore(lastExpired + 1)
+	if checkpoint == nil {
+		return nil
+	}
+
+	var expired []logFile
+	for _, f := range l.commits {
+		if f.Version < checkpoint.Version {
+			expired = append(expired, f)
+		}
+	}
+	for _, f := range l.checkpointFiles {
+		if f.Version < checkpoint.Version {
+			expired = append(expired, f)
+		}
+	}
+	return expired
+}
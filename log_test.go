@@ -0,0 +1,127 @@
+package deltalake
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/storage"
+)
+
+func TestLogListing_ExpiredFiles(t *testing.T) {
+	now := time.Now()
+	old := now.AddDate(0, -2, 0)
+	cutoff := now.AddDate(0, -1, 0)
+	commit := func(version int64, modified time.Time) logFile {
+		return logFile{Path: CommitURIFromVersion(version), Version: version, Info: storage.ObjectInfo{LastModified: modified}}
+	}
+	checkpointFile := func(version int64) logFile {
+		return logFile{Path: checkpointPath(version), Version: version, Checkpoint: true}
+	}
+
+	tests := map[string]struct {
+		listing *logListing
+		want    []string
+	}{
+		"no checkpoint": {
+			listing: &logListing{commits: []logFile{commit(0, old), commit(1, old), commit(2, now)}},
+		},
+		"checkpoint after retained versions": {
+			// version 2 is retained and needs the commits before it
+			listing: &logListing{
+				commits:         []logFile{commit(0, old), commit(1, old), commit(2, now), commit(3, now)},
+				checkpoints:     []*Checkpoint{{Version: 3}},
+				checkpointFiles: []logFile{checkpointFile(3)},
+			},
+		},
+		"checkpoint at first retained version": {
+			listing: &logListing{
+				commits:         []logFile{commit(0, old), commit(1, old), commit(2, now), commit(3, now)},
+				checkpoints:     []*Checkpoint{{Version: 2}},
+				checkpointFiles: []logFile{checkpointFile(2)},
+			},
+			want: []string{CommitURIFromVersion(0), CommitURIFromVersion(1)},
+		},
+		"older checkpoints": {
+			listing: &logListing{
+				commits:         []logFile{commit(2, old), commit(3, old), commit(4, old), commit(5, old), commit(6, now)},
+				checkpoints:     []*Checkpoint{{Version: 2}, {Version: 4}, {Version: 6}},
+				checkpointFiles: []logFile{checkpointFile(2), checkpointFile(4), checkpointFile(6)},
+			},
+			want: []string{
+				CommitURIFromVersion(2), CommitURIFromVersion(3), CommitURIFromVersion(4), CommitURIFromVersion(5),
+				checkpointPath(2), checkpointPath(4),
+			},
+		},
+		"all expired": {
+			listing: &logListing{
+				commits:         []logFile{commit(0, old), commit(1, old), commit(2, old)},
+				checkpoints:     []*Checkpoint{{Version: 1}},
+				checkpointFiles: []logFile{checkpointFile(1)},
+			},
+			want: []string{CommitURIFromVersion(0)},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			for _, f := range test.listing.expiredFiles(cutoff) {
+				got = append(got, f.Path)
+			}
+			require.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestTable_CreateCheckpointCleansUpExpiredLogs(t *testing.T) {
+	tests := map[string]struct {
+		configuration map[string]string
+		wantDeleted   bool
+	}{
+		"default":  {configuration: map[string]string{}},
+		"enabled":  {configuration: map[string]string{"delta.enableExpiredLogCleanup": "true"}, wantDeleted: true},
+		"disabled": {configuration: map[string]string{"delta.enableExpiredLogCleanup": "false"}},
+		"retained": {configuration: map[string]string{"delta.enableExpiredLogCleanup": "true", "delta.logRetentionDuration": "interval 90 days"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := storage.NewLocalStorage(dir)
+			require.NoError(t, err)
+			tbl := writePartitionedTestTable(t, store, 1, []int{1, 1, 1}, removeTestCommit)
+			require.Equal(t, int64(4), tbl.State.Version)
+			tbl.State.CurrentMetadata.Configuration = test.configuration
+			tbl.State.LogRetentionMillis = tbl.State.CurrentMetadata.LogRetentionMillis()
+
+			old := time.Now().AddDate(0, -2, 0)
+			for version := int64(0); version < 4; version++ {
+				require.NoError(t, os.Chtimes(filepath.Join(dir, CommitURIFromVersion(version)), old, old))
+			}
+
+			_, err = tbl.CreateCheckpoint()
+			require.NoError(t, err)
+
+			for version := int64(0); version < 4; version++ {
+				_, err := os.Stat(filepath.Join(dir, CommitURIFromVersion(version)))
+				require.Equal(t, test.wantDeleted, os.IsNotExist(err), "version %d", version)
+			}
+			require.FileExists(t, filepath.Join(dir, CommitURIFromVersion(4)))
+
+			loaded, err := LoadTable(store, nil)
+			require.NoError(t, err)
+			require.Equal(t, int64(4), loaded.State.Version)
+			require.Len(t, loaded.State.Files, 2)
+
+			_, err = LoadTableAtVersion(store, nil, 2)
+			if test.wantDeleted {
+				require.True(t, errors.Is(err, ErrVersionExpired), "got %v", err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
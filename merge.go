@@ -0,0 +1,628 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/types"
+)
+
+// Names of the records of the source and target rows in the expressions of a merge.
+const (
+	MergeSource = "source"
+	MergeTarget = "target"
+)
+
+// ErrMergeDuplicateSource is returned by a merge when a target row matches more than one
+// source row and there are matched clauses, as it is ambiguous which one applies.
+var ErrMergeDuplicateSource = errors.New("target row matches multiple source rows")
+
+// MergeMetrics reports the work done by a merge.
+type MergeMetrics struct {
+	// Version is the version committed by the merge, or the current version if the merge
+	// did not change the table.
+	Version int64
+	// NumSourceRows is the number of rows of the source.
+	NumSourceRows int64
+	// NumTargetRowsInserted is the number of source rows inserted.
+	NumTargetRowsInserted int64
+	// NumTargetRowsUpdated is the number of target rows updated.
+	NumTargetRowsUpdated int64
+	// NumTargetRowsDeleted is the number of target rows deleted.
+	NumTargetRowsDeleted int64
+	// NumTargetRowsCopied is the number of rows of rewritten files that were not changed.
+	NumTargetRowsCopied int64
+	// NumTargetFilesAdded is the number of files written.
+	NumTargetFilesAdded int64
+	// NumTargetFilesRemoved is the number of files rewritten.
+	NumTargetFilesRemoved int64
+	// NumTargetBytesAdded is the total size of the files written.
+	NumTargetBytesAdded int64
+	// NumTargetBytesRemoved is the total size of the files removed.
+	NumTargetBytesRemoved int64
+}
+
+// operationMetrics returns the metrics of the MERGE commitInfo, which Spark names after the
+// source and target rows and files rather than like the other operations.
+func (m *MergeMetrics) operationMetrics() map[string]interface{} {
+	return map[string]interface{}{
+		"numSourceRows":         strconv.FormatInt(m.NumSourceRows, 10),
+		"numTargetRowsInserted": strconv.FormatInt(m.NumTargetRowsInserted, 10),
+		"numTargetRowsUpdated":  strconv.FormatInt(m.NumTargetRowsUpdated, 10),
+		"numTargetRowsDeleted":  strconv.FormatInt(m.NumTargetRowsDeleted, 10),
+		"numTargetRowsCopied":   strconv.FormatInt(m.NumTargetRowsCopied, 10),
+		"numOutputRows":         strconv.FormatInt(m.NumTargetRowsInserted+m.NumTargetRowsUpdated+m.NumTargetRowsCopied, 10),
+		"numTargetFilesAdded":   strconv.FormatInt(m.NumTargetFilesAdded, 10),
+		"numTargetFilesRemoved": strconv.FormatInt(m.NumTargetFilesRemoved, 10),
+		"numTargetBytesAdded":   strconv.FormatInt(m.NumTargetBytesAdded, 10),
+		"numTargetBytesRemoved": strconv.FormatInt(m.NumTargetBytesRemoved, 10),
+	}
+}
+
+type mergeAction string
+
+const (
+	mergeUpdate mergeAction = "update"
+	mergeDelete mergeAction = "delete"
+	mergeInsert mergeAction = "insert"
+)
+
+// mergeClause is an action applied to the rows matching its condition, or to all the
+// rows if the condition is nil.
+type mergeClause struct {
+	action      mergeAction
+	cond        Expr
+	assignments map[string]Expr // nil to copy all the columns of the source
+}
+
+// MarshalJSON formats the clause as in the operation parameters of a MERGE commitInfo.
+func (c *mergeClause) MarshalJSON() ([]byte, error) {
+	clause := map[string]string{"actionType": string(c.action)}
+	if c.cond != nil {
+		clause["predicate"] = c.cond.String()
+	}
+	return json.Marshal(clause)
+}
+
+// MergeBuilder merges a source of rows into a table. Source rows are joined to the rows of
+// the table, the target, on key columns, and the clauses apply to matched rows, to source
+// rows not matched by any target row and to target rows not matched by any source row:
+//
+//	metrics, err := table.Merge(rows, "id").
+//		WhenMatchedDelete(Col(MergeSource, "deleted")).
+//		WhenMatchedUpdate(nil, map[string]Expr{"count": Add(Col(MergeTarget, "count"), Col(MergeSource, "count"))}).
+//		WhenNotMatchedInsert(nil, nil).
+//		Execute()
+//
+// Conditions and assignments reference the columns of the source row as Col(MergeSource,
+// name) and those of the target row as Col(MergeTarget, name). Clauses of the same kind
+// are tried in order and only the first one whose condition matches applies.
+type MergeBuilder struct {
+	table              *Table
+	source             []map[string]any
+	keys               []string
+	matched            []*mergeClause
+	notMatched         []*mergeClause
+	notMatchedBySource []*mergeClause
+	err                error
+}
+
+// Merge returns a builder merging the source rows into the table, joining them to the rows
+// of the table with equal values of the key columns, which must be primitive columns of the
+// table. Rows with a null key never match.
+func (t *Table) Merge(source []map[string]any, keys ...string) *MergeBuilder {
+	return &MergeBuilder{table: t, source: source, keys: keys}
+}
+
+// WhenMatchedUpdate sets the columns of the target rows matching a source row and the
+// condition to the values of the assignments. If assignments is nil, all the columns of the
+// table are set to the columns of the source row with the same names.
+func (b *MergeBuilder) WhenMatchedUpdate(cond Expr, assignments map[string]Expr) *MergeBuilder {
+	b.matched = append(b.matched, b.clause(mergeUpdate, cond, assignments))
+	return b
+}
+
+// WhenMatchedDelete deletes the target rows matching a source row and the condition.
+func (b *MergeBuilder) WhenMatchedDelete(cond Expr) *MergeBuilder {
+	b.matched = append(b.matched, b.clause(mergeDelete, cond, nil))
+	return b
+}
+
+// WhenNotMatchedInsert inserts the source rows matching no target row and matching the
+// condition, with the values of the assignments, which may only reference the source row.
+// If assignments is nil, the columns of the source row with the names of the columns of
+// the table are inserted.
+func (b *MergeBuilder) WhenNotMatchedInsert(cond Expr, assignments map[string]Expr) *MergeBuilder {
+	b.notMatched = append(b.notMatched, b.clause(mergeInsert, cond, assignments))
+	return b
+}
+
+// WhenNotMatchedBySourceDelete deletes the target rows matching no source row and matching
+// the condition, which may only reference the target row.
+func (b *MergeBuilder) WhenNotMatchedBySourceDelete(cond Expr) *MergeBuilder {
+	b.notMatchedBySource = append(b.notMatchedBySource, b.clause(mergeDelete, cond, nil))
+	return b
+}
+
+// clause returns the clause with the assignments resolved against the schema of the table.
+// Errors are returned by Execute.
+func (b *MergeBuilder) clause(action mergeAction, cond Expr, assignments map[string]Expr) *mergeClause {
+	c := &mergeClause{action: action, cond: cond}
+	if assignments == nil || b.table.State.CurrentMetadata == nil {
+		return c
+	}
+	c.assignments = make(map[string]Expr, len(assignments))
+	for name, expr := range assignments {
+		field, expr, err := resolveAssignment(&b.table.State.CurrentMetadata.Schema, c.assignments, name, expr)
+		if err != nil {
+			b.err = errors.Join(b.err, err)
+			continue
+		}
+		c.assignments[field.Name] = expr
+	}
+	return c
+}
+
+// Execute merges the source into the table in a single MERGE commit and returns the metrics.
+//
+// Only the files of the table that may contain rows matching source keys, according to
+// their partition values and statistics, are read, or all the files if there are clauses
+// for target rows not matched by the source. Only files with updated or deleted rows are
+// rewritten. If the change data feed of the table is enabled, the inserted and deleted
+// rows and the updated rows, before and after the update, are written to change data
+// files of the commit. Nothing is committed if the merge does not change the table. The
+// source values of the columns of the table, the conditions and the assignments are
+// checked against the schema before any file is read or written.
+//
+// The commit fails with ErrCommitConflict if a concurrent commit removed any of the
+// rewritten files. Rows appended concurrently are not merged. Tables whose writer version
+// is not supported are refused before any file is written.
+func (b *MergeBuilder) Execute() (*MergeMetrics, error) {
+	t := b.table
+	if t.State.CurrentMetadata == nil {
+		return nil, errors.New("table has no metadata")
+	}
+	if !t.Config.RequireFiles {
+		return nil, errors.New("merge requires a table state loaded with files")
+	}
+	// the commit would be refused after the rewritten files are written
+	if err := t.State.checkWriterVersion(); err != nil {
+		return nil, err
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	schema := &t.State.CurrentMetadata.Schema
+	if len(b.keys) == 0 {
+		return nil, errors.New("merge requires key columns")
+	}
+	keyFields := make([]*types.StructField, len(b.keys))
+	for i, key := range b.keys {
+		field := lookupField(schema, key)
+		if field == nil || !types.IsPrimitiveType(field.Type) {
+			return nil, fmt.Errorf("key column %s is not a primitive column of the table", key)
+		}
+		keyFields[i] = field
+	}
+	// invalid clauses must fail before any file is written
+	records, err := b.recordSchema()
+	if err != nil {
+		return nil, err
+	}
+	if err := b.checkClauses(records); err != nil {
+		return nil, err
+	}
+
+	// index the source rows by key
+	index := make(map[string][]int, len(b.source))
+	sourceKeys := make([][]any, len(b.source))
+	for i, row := range b.source {
+		key, values, err := mergeKey(keyFields, row)
+		if err != nil {
+			return nil, fmt.Errorf("source row %d: %w", i, err)
+		}
+		if values != nil {
+			index[key] = append(index[key], i)
+			sourceKeys[i] = values
+		}
+	}
+
+	// the whole table is read when rows not matched by the source may change
+	read := Lit(true)
+	if len(b.notMatchedBySource) == 0 {
+		read = mergeCandidates(t.State.CurrentMetadata, keyFields, sourceKeys)
+	}
+	files, err := t.State.FilesMatching(read)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &MergeMetrics{Version: t.State.Version, NumSourceRows: int64(len(b.source))}
+	tx := t.NewTransaction(WithOperation("MERGE", b.operationParameters(keyFields)))
+	// concurrent merges inserting the same keys conflict
+	tx.AddReadPredicate(read)
+	changes, err := t.newChangeWriter()
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]bool, len(b.source))
+	now := time.Now().UnixMilli()
+	for _, add := range files {
+		adds, changed, err := b.mergeFile(add, keyFields, index, matched, changes, metrics)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue
+		}
+		for _, a := range adds {
+			tx.AddAction(a)
+			metrics.NumTargetFilesAdded++
+			metrics.NumTargetBytesAdded += a.Size
+		}
+		tx.AddAction(actions.NewRemove(add.Path, now, true, true, add.PartitionValues, add.Size, add.Tags))
+		metrics.NumTargetFilesRemoved++
+		metrics.NumTargetBytesRemoved += add.Size
+	}
+
+	adds, err := b.insertNotMatched(matched, changes, metrics)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range adds {
+		tx.AddAction(a)
+		metrics.NumTargetFilesAdded++
+		metrics.NumTargetBytesAdded += a.Size
+	}
+
+	log.Debug().
+		Int("candidates", len(files)).
+		Int64("filesRemoved", metrics.NumTargetFilesRemoved).
+		Int64("inserted", metrics.NumTargetRowsInserted).
+		Int64("updated", metrics.NumTargetRowsUpdated).
+		Int64("deleted", metrics.NumTargetRowsDeleted).
+		Msg("merge")
+	if metrics.NumTargetFilesAdded == 0 && metrics.NumTargetFilesRemoved == 0 {
+		return metrics, nil
+	}
+	cdcs, err := closeChangeWriter(changes)
+	if err != nil {
+		return nil, err
+	}
+	for _, cdc := range cdcs {
+		tx.AddAction(cdc)
+	}
+
+	tx.SetOperationMetrics(metrics.operationMetrics())
+	version, err := tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	metrics.Version = version
+	return metrics, nil
+}
+
+// recordSchema returns the schema of the records of source and target rows evaluated by
+// the clauses. Source columns have the types of the columns of the table with the same
+// names, which their values must fit, and other source columns the primitive types of
+// their values; source columns that are always null are of the null type.
+func (b *MergeBuilder) recordSchema() (*types.StructType, error) {
+	schema := &b.table.State.CurrentMetadata.Schema
+	var fields []*types.StructField
+	typed := make(map[string]bool)
+	var untyped []string
+	for i, row := range b.source {
+		for name, value := range row {
+			field := lookupField(schema, name)
+			if field == nil {
+				if dt, err := literalType(value); err == nil && dt != types.DataTypeNull && !typed[name] {
+					typed[name] = true
+					fields = append(fields, types.NewStructField(name, dt, true, nil))
+				} else if value == nil {
+					untyped = append(untyped, name)
+				}
+				continue
+			}
+			if s, ok := value.(string); ok && (field.Type == types.DataTypeDate || field.Type == types.DataTypeTimestamp) {
+				if t, ok := parseTime(s); ok {
+					value = t
+				}
+			}
+			if _, err := normalizeValue(field, value); err != nil {
+				return nil, fmt.Errorf("source row %d: %w", i, err)
+			}
+			if !typed[field.Name] {
+				typed[field.Name] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+	for _, name := range untyped {
+		if !typed[name] {
+			typed[name] = true
+			fields = append(fields, types.NewStructField(name, types.DataTypeNull, true, nil))
+		}
+	}
+	return types.NewStruct(
+		types.NewStructField(MergeSource, types.NewStruct(fields...), true, nil),
+		types.NewStructField(MergeTarget, schema, true, nil),
+	), nil
+}
+
+// checkClauses checks that the conditions of the clauses are boolean and that the values
+// of their assignments can be stored in their columns, for records of the schema.
+func (b *MergeBuilder) checkClauses(records *types.StructType) error {
+	schema := &b.table.State.CurrentMetadata.Schema
+	for _, clauses := range [][]*mergeClause{b.matched, b.notMatched, b.notMatchedBySource} {
+		for _, c := range clauses {
+			if c.cond != nil {
+				dt, err := exprType(records, c.cond)
+				if err != nil {
+					return err
+				}
+				if dt != types.DataTypeBoolean && dt != types.DataTypeNull {
+					return fmt.Errorf("condition %s is of type %s, not boolean", c.cond, dt)
+				}
+			}
+			for column, expr := range c.assignments {
+				if err := checkAssignment(records, lookupField(schema, column), expr); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// mergeFile applies the matched and not matched by source clauses to the rows of the file
+// and writes the rows that are kept to new data files, and the changed rows to the change
+// data writer, unless it is nil. The source rows matched are flagged in matched. It returns
+// the add actions of the written files and false if no row changed, in which case no file
+// is written.
+func (b *MergeBuilder) mergeFile(add *actions.Add, keyFields []*types.StructField, index map[string][]int, matched []bool, changes *Writer, metrics *MergeMetrics) ([]*actions.Add, bool, error) {
+	scanner := newScanner(b.table.Storage, b.table.State.CurrentMetadata, []*actions.Add{add})
+	defer scanner.Close()
+	writer, err := b.table.NewWriter()
+	if err != nil {
+		return nil, false, err
+	}
+	var updated, deleted, copied int64
+	for scanner.Next() {
+		row := scanner.Row()
+		key, values, err := mergeKey(keyFields, row)
+		if err != nil {
+			return nil, false, err
+		}
+		var sources []int
+		if values != nil {
+			sources = index[key]
+		}
+		for _, i := range sources {
+			matched[i] = true
+		}
+
+		var clauses []*mergeClause
+		record := map[string]any{MergeTarget: row}
+		switch {
+		case len(sources) > 1 && len(b.matched) > 0:
+			return nil, false, fmt.Errorf("%w: key %s", ErrMergeDuplicateSource, key)
+		case len(sources) > 0:
+			clauses = b.matched
+			record[MergeSource] = b.source[sources[0]]
+		default:
+			clauses = b.notMatchedBySource
+		}
+		clause, err := firstMatchingClause(clauses, record)
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch {
+		case clause == nil:
+			copied++
+		case clause.action == mergeDelete:
+			if err := writeChange(changes, ChangeDelete, row); err != nil {
+				return nil, false, err
+			}
+			deleted++
+			continue
+		default:
+			if err := writeChange(changes, ChangeUpdatePreimage, row); err != nil {
+				return nil, false, err
+			}
+			if row, err = clause.apply(&b.table.State.CurrentMetadata.Schema, row, record); err != nil {
+				return nil, false, err
+			}
+			if err := writeChange(changes, ChangeUpdatePostimage, row); err != nil {
+				return nil, false, err
+			}
+			updated++
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, err
+	}
+	// the writer only writes files when closed
+	if updated == 0 && deleted == 0 {
+		return nil, false, nil
+	}
+	adds, err := writer.Close()
+	if err != nil {
+		return nil, false, err
+	}
+	metrics.NumTargetRowsUpdated += updated
+	metrics.NumTargetRowsDeleted += deleted
+	metrics.NumTargetRowsCopied += copied
+	return adds, true, nil
+}
+
+// insertNotMatched applies the not matched clauses to the source rows that did not match
+// any target row and writes the inserted rows to new data files and to the change data
+// writer, unless it is nil.
+func (b *MergeBuilder) insertNotMatched(matched []bool, changes *Writer, metrics *MergeMetrics) ([]*actions.Add, error) {
+	if len(b.notMatched) == 0 {
+		return nil, nil
+	}
+	writer, err := b.table.NewWriter()
+	if err != nil {
+		return nil, err
+	}
+	for i, source := range b.source {
+		if matched[i] {
+			continue
+		}
+		record := map[string]any{MergeSource: source}
+		clause, err := firstMatchingClause(b.notMatched, record)
+		if err != nil {
+			return nil, err
+		}
+		if clause == nil {
+			continue
+		}
+		row, err := clause.apply(&b.table.State.CurrentMetadata.Schema, map[string]any{}, record)
+		if err != nil {
+			return nil, err
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, fmt.Errorf("source row %d: %w", i, err)
+		}
+		if err := writeChange(changes, ChangeInsert, row); err != nil {
+			return nil, fmt.Errorf("source row %d: %w", i, err)
+		}
+		metrics.NumTargetRowsInserted++
+	}
+	return writer.Close()
+}
+
+// apply returns the row with the assignments of the clause evaluated against the record
+// of the source and target rows, or with the columns of the source row if the clause has
+// no assignments.
+func (c *mergeClause) apply(schema *types.StructType, row map[string]any, record map[string]any) (map[string]any, error) {
+	values := make(map[string]any, len(row))
+	for column, v := range row {
+		values[column] = v
+	}
+	if c.assignments == nil {
+		source, _ := record[MergeSource].(map[string]any)
+		for name, v := range source {
+			if field := lookupField(schema, name); field != nil {
+				values[field.Name] = v
+			}
+		}
+		return values, nil
+	}
+	for column, expr := range c.assignments {
+		v, err := expr.Eval(record)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column, err)
+		}
+		values[column] = v
+	}
+	return values, nil
+}
+
+// firstMatchingClause returns the first clause whose condition matches the record, or nil.
+func firstMatchingClause(clauses []*mergeClause, record map[string]any) (*mergeClause, error) {
+	for _, clause := range clauses {
+		if clause.cond == nil {
+			return clause, nil
+		}
+		ok, err := Matches(clause.cond, record)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return clause, nil
+		}
+	}
+	return nil, nil
+}
+
+// mergeKey returns the values of the key columns of the row, normalized to the types of the
+// columns, and a string identifying them. Dates and timestamps may be given as strings.
+// The values are nil if any of them is null.
+func mergeKey(keyFields []*types.StructField, row map[string]any) (string, []any, error) {
+	values := make([]any, len(keyFields))
+	parts := make([]string, len(keyFields))
+	for i, field := range keyFields {
+		value := lookupValue(row, field.Name)
+		if s, ok := value.(string); ok && (field.Type == types.DataTypeDate || field.Type == types.DataTypeTimestamp) {
+			if t, ok := parseTime(s); ok {
+				value = t
+			}
+		}
+		v, err := normalizeValue(field, value)
+		if err != nil {
+			return "", nil, err
+		}
+		if v == nil {
+			return "", nil, nil
+		}
+		s, err := formatPartitionValue(field.Type, v)
+		if err != nil {
+			return "", nil, err
+		}
+		values[i], parts[i] = v, strconv.Quote(s)
+	}
+	return strings.Join(parts, ","), values, nil
+}
+
+// mergeCandidates returns a predicate matching the target rows that may match source keys:
+// the key values of partition columns, and the range of the key values of other columns.
+func mergeCandidates(metadata *TableMetadata, keyFields []*types.StructField, sourceKeys [][]any) Expr {
+	preds := make([]Expr, 0, len(keyFields))
+	for i, field := range keyFields {
+		var values []any
+		var lo, hi any
+		for _, key := range sourceKeys {
+			if key == nil {
+				continue
+			}
+			v := key[i]
+			values = append(values, v)
+			if c, ok := compareValues(v, lo); lo == nil || ok && c < 0 {
+				lo = v
+			}
+			if c, ok := compareValues(v, hi); hi == nil || ok && c > 0 {
+				hi = v
+			}
+		}
+		switch {
+		case len(values) == 0:
+			return Lit(false)
+		case isPartitionColumn(metadata.PartitionColumns, field.Name):
+			preds = append(preds, In(Col(field.Name), values...))
+		default:
+			preds = append(preds, Ge(Col(field.Name), Lit(lo)), Le(Col(field.Name), Lit(hi)))
+		}
+	}
+	return And(preds...)
+}
+
+// operationParameters returns the parameters of the MERGE commitInfo, like Spark.
+func (b *MergeBuilder) operationParameters(keyFields []*types.StructField) map[string]interface{} {
+	conditions := make([]Expr, len(keyFields))
+	for i, field := range keyFields {
+		conditions[i] = Eq(Col(MergeTarget, field.Name), Col(MergeSource, field.Name))
+	}
+	clauses := func(clauses []*mergeClause) string {
+		data, _ := json.Marshal(append([]*mergeClause{}, clauses...))
+		return string(data)
+	}
+	return map[string]interface{}{
+		"predicate":                    predicateParameter(And(conditions...)),
+		"matchedPredicates":            clauses(b.matched),
+		"notMatchedPredicates":         clauses(b.notMatched),
+		"notMatchedBySourcePredicates": clauses(b.notMatchedBySource),
+	}
+}
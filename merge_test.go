@@ -0,0 +1,361 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/types"
+)
+
+func TestTable_Merge(t *testing.T) {
+	// the table has ids 0-29 in three files on 2021-01-01, 30-39 on 2021-01-02 and 40-59
+	// in two files on 2021-01-03, all named "a"
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		source      []map[string]any
+		keys        []string
+		merge       func(b *MergeBuilder) *MergeBuilder
+		wantMetrics MergeMetrics
+		// want returns the expected rows after the merge, given the rows before keyed by id
+		want func(rows map[int64]map[string]any)
+	}{
+		"upsert": {
+			source: []map[string]any{
+				{"id": 5, "name": "b", "date": day},
+				{"id": 100, "name": "c", "date": day.AddDate(0, 0, 2)},
+			},
+			keys: []string{"id"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.WhenMatchedUpdate(nil, nil).WhenNotMatchedInsert(nil, nil)
+			},
+			wantMetrics: MergeMetrics{
+				NumSourceRows: 2, NumTargetRowsInserted: 1, NumTargetRowsUpdated: 1, NumTargetRowsCopied: 9,
+				NumTargetFilesAdded: 2, NumTargetFilesRemoved: 1,
+			},
+			want: func(rows map[int64]map[string]any) {
+				rows[5]["name"] = "b"
+				rows[100] = map[string]any{"id": int64(100), "name": "c", "date": day.AddDate(0, 0, 2)}
+			},
+		},
+		"matched clauses in order": {
+			source: []map[string]any{
+				{"id": 5, "deleted": true},
+				{"id": 6, "deleted": false, "name": "b"},
+				{"id": 36, "name": "c"},
+			},
+			keys: []string{"id"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.
+					WhenMatchedDelete(Eq(Col(MergeSource, "deleted"), Lit(true))).
+					WhenMatchedUpdate(nil, map[string]Expr{
+						"name": Col(MergeSource, "name"),
+						"id":   Add(Col(MergeTarget, "id"), Lit(1000)),
+					})
+			},
+			wantMetrics: MergeMetrics{
+				NumSourceRows: 3, NumTargetRowsUpdated: 2, NumTargetRowsDeleted: 1, NumTargetRowsCopied: 17,
+				NumTargetFilesAdded: 2, NumTargetFilesRemoved: 2,
+			},
+			want: func(rows map[int64]map[string]any) {
+				delete(rows, 5)
+				rows[1006], rows[1036] = rows[6], rows[36]
+				delete(rows, 6)
+				delete(rows, 36)
+				rows[1006]["id"], rows[1006]["name"] = int64(1006), "b"
+				rows[1036]["id"], rows[1036]["name"] = int64(1036), "c"
+			},
+		},
+		"not matched by source": {
+			source: []map[string]any{{"id": 30}, {"id": 31}},
+			keys:   []string{"id"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.WhenNotMatchedBySourceDelete(Ne(Col(MergeTarget, "date"), Lit("2021-01-01")))
+			},
+			wantMetrics: MergeMetrics{
+				NumSourceRows: 2, NumTargetRowsDeleted: 28, NumTargetRowsCopied: 2,
+				NumTargetFilesAdded: 1, NumTargetFilesRemoved: 3,
+			},
+			want: func(rows map[int64]map[string]any) {
+				for id := int64(32); id < 60; id++ {
+					delete(rows, id)
+				}
+			},
+		},
+		"conditional insert": {
+			source: []map[string]any{
+				{"id": 200, "name": "x"},
+				{"id": 201, "name": "y"},
+				{"id": 1, "name": "x"},
+			},
+			keys: []string{"id"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.WhenNotMatchedInsert(Eq(Col(MergeSource, "name"), Lit("x")), map[string]Expr{
+					"id":   Col(MergeSource, "id"),
+					"name": Lit("inserted"),
+					"date": Lit("2021-01-05"),
+				})
+			},
+			wantMetrics: MergeMetrics{NumSourceRows: 3, NumTargetRowsInserted: 1, NumTargetFilesAdded: 1},
+			want: func(rows map[int64]map[string]any) {
+				rows[200] = map[string]any{"id": int64(200), "name": "inserted", "date": day.AddDate(0, 0, 4)}
+			},
+		},
+		"composite key": {
+			source: []map[string]any{
+				{"id": 5, "date": "2021-01-01", "name": "b"},
+				{"id": 6, "date": "2021-01-02", "name": "c"},
+			},
+			keys: []string{"id", "date"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.WhenMatchedUpdate(nil, map[string]Expr{"name": Col(MergeSource, "name")}).
+					WhenNotMatchedInsert(nil, map[string]Expr{"id": Add(Col(MergeSource, "id"), Lit(1000)), "date": Lit(day)})
+			},
+			wantMetrics: MergeMetrics{
+				NumSourceRows: 2, NumTargetRowsInserted: 1, NumTargetRowsUpdated: 1, NumTargetRowsCopied: 9,
+				NumTargetFilesAdded: 2, NumTargetFilesRemoved: 1,
+			},
+			want: func(rows map[int64]map[string]any) {
+				rows[5]["name"] = "b"
+				rows[1006] = map[string]any{"id": int64(1006), "name": nil, "date": day}
+			},
+		},
+		"no change": {
+			source: []map[string]any{{"id": 5}, {"id": nil}},
+			keys:   []string{"id"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.WhenNotMatchedInsert(Eq(Col(MergeSource, "id"), Lit(5)), nil)
+			},
+			wantMetrics: MergeMetrics{NumSourceRows: 2},
+			want:        func(rows map[int64]map[string]any) {},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			version := tbl.State.Version
+			want := rowsByID(scanAll(t, tbl))
+			test.want(want)
+
+			metrics, err := test.merge(tbl.Merge(test.source, test.keys...)).Execute()
+			require.NoError(t, err)
+			got := *metrics
+			got.Version, got.NumTargetBytesAdded, got.NumTargetBytesRemoved = 0, 0, 0
+			require.Equal(t, test.wantMetrics, got)
+			require.Equal(t, want, rowsByID(scanAll(t, tbl)))
+
+			if test.wantMetrics.NumTargetFilesAdded == 0 && test.wantMetrics.NumTargetFilesRemoved == 0 {
+				require.Equal(t, version, metrics.Version)
+				require.Equal(t, version, tbl.State.Version)
+				return
+			}
+			require.Equal(t, version+1, metrics.Version)
+			history, err := tbl.History(1)
+			require.NoError(t, err)
+			require.Equal(t, "MERGE", history[0].CommitInfo.Operation)
+			require.Equal(t, metrics.operationMetrics(), history[0].CommitInfo.OperationMetrics)
+		})
+	}
+}
+
+func TestTable_MergeConcurrent(t *testing.T) {
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	upsert := func(tbl *Table, id int) error {
+		source := []map[string]any{{"id": id, "name": "b", "date": day}}
+		_, err := tbl.Merge(source, "id").WhenMatchedUpdate(nil, nil).WhenNotMatchedInsert(nil, nil).Execute()
+		return err
+	}
+	tests := map[string]struct {
+		concurrent func(tbl *Table) error
+		wantErr    error
+	}{
+		"same key inserted": {
+			concurrent: func(tbl *Table) error { return upsert(tbl, 100) },
+			wantErr:    ErrCommitConflict,
+		},
+		"other key inserted": {
+			concurrent: func(tbl *Table) error { return upsert(tbl, 200) },
+		},
+		"blind append": {
+			concurrent: func(tbl *Table) error {
+				w, err := tbl.NewWriter()
+				require.NoError(t, err)
+				require.NoError(t, w.Write(map[string]any{"id": 100, "name": "c", "date": day}))
+				adds, err := w.Close()
+				require.NoError(t, err)
+				tx := tbl.NewTransaction()
+				tx.AddActions(toActions(adds)...)
+				_, err = tx.Commit()
+				return err
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			other, err := LoadTable(tbl.Storage, nil)
+			require.NoError(t, err)
+
+			require.NoError(t, test.concurrent(other))
+			err = upsert(tbl, 100)
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestTable_MergeOperationParameters(t *testing.T) {
+	tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+	_, err := tbl.Merge([]map[string]any{{"id": 5, "name": "b"}}, "id").
+		WhenMatchedDelete(IsNull(Col(MergeSource, "name"))).
+		WhenMatchedUpdate(nil, map[string]Expr{"name": Col(MergeSource, "name")}).
+		Execute()
+	require.NoError(t, err)
+
+	history, err := tbl.History(1)
+	require.NoError(t, err)
+	params := history[0].CommitInfo.OperationParameters
+	require.Equal(t, `["(target.id = source.id)"]`, params["predicate"])
+	var matched []map[string]string
+	require.NoError(t, json.Unmarshal([]byte(params["matchedPredicates"].(string)), &matched))
+	require.Equal(t, []map[string]string{
+		{"actionType": "delete", "predicate": "source.name IS NULL"},
+		{"actionType": "update"},
+	}, matched)
+	require.Equal(t, "[]", params["notMatchedPredicates"])
+}
+
+func TestTable_MergeInvalid(t *testing.T) {
+	tests := map[string]struct {
+		source        []map[string]any
+		keys          []string
+		merge         func(b *MergeBuilder) *MergeBuilder
+		writerVersion int
+		wantErr       error
+	}{
+		"duplicate source rows": {
+			source:  []map[string]any{{"id": 5, "name": "b"}, {"id": 5, "name": "c"}},
+			keys:    []string{"id"},
+			merge:   func(b *MergeBuilder) *MergeBuilder { return b.WhenMatchedUpdate(nil, nil) },
+			wantErr: ErrMergeDuplicateSource,
+		},
+		"unknown assignment column": {
+			source: []map[string]any{{"id": 5}},
+			keys:   []string{"id"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.WhenMatchedUpdate(nil, map[string]Expr{"missing": Lit(1)})
+			},
+			wantErr: ErrInvalidAssignment,
+		},
+		"no keys": {
+			source: []map[string]any{{"id": 5}},
+			merge:  func(b *MergeBuilder) *MergeBuilder { return b.WhenMatchedDelete(nil) },
+		},
+		"unknown key": {
+			source: []map[string]any{{"id": 5}},
+			keys:   []string{"missing"},
+			merge:  func(b *MergeBuilder) *MergeBuilder { return b.WhenMatchedDelete(nil) },
+		},
+		"invalid key value": {
+			source: []map[string]any{{"id": "five"}},
+			keys:   []string{"id"},
+			merge:  func(b *MergeBuilder) *MergeBuilder { return b.WhenMatchedDelete(nil) },
+		},
+		"mistyped assignment": {
+			source: []map[string]any{{"id": 5}},
+			keys:   []string{"id"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.WhenMatchedUpdate(nil, map[string]Expr{"id": Lit("five")})
+			},
+			wantErr: ErrInvalidAssignment,
+		},
+		"mistyped source column": {
+			source: []map[string]any{{"id": 5, "label": "five"}, {"id": 25, "label": nil}},
+			keys:   []string{"id"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.WhenMatchedUpdate(nil, map[string]Expr{"id": Col(MergeSource, "label")})
+			},
+			wantErr: ErrInvalidAssignment,
+		},
+		"mistyped inserted expression": {
+			source: []map[string]any{{"id": 500}},
+			keys:   []string{"id"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.WhenNotMatchedInsert(nil, map[string]Expr{"id": Col(MergeSource, "id"), "date": Col(MergeSource, "id")})
+			},
+			wantErr: ErrInvalidAssignment,
+		},
+		"condition not boolean": {
+			source: []map[string]any{{"id": 5}},
+			keys:   []string{"id"},
+			merge:  func(b *MergeBuilder) *MergeBuilder { return b.WhenMatchedDelete(Col(MergeSource, "id")) },
+		},
+		"invalid inserted value": {
+			source: []map[string]any{{"id": 500, "name": 1}},
+			keys:   []string{"id"},
+			merge:  func(b *MergeBuilder) *MergeBuilder { return b.WhenNotMatchedInsert(nil, nil) },
+		},
+		"unsupported writer version": {
+			source: []map[string]any{{"id": 5, "name": "b"}, {"id": 500, "name": "c"}},
+			keys:   []string{"id"},
+			merge: func(b *MergeBuilder) *MergeBuilder {
+				return b.WhenMatchedUpdate(nil, nil).WhenNotMatchedInsert(nil, nil)
+			},
+			writerVersion: 3,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			if test.writerVersion > 0 {
+				tbl.State.MinWriterVersion = test.writerVersion
+			}
+			version := tbl.State.Version
+			objects, err := tbl.Storage.List("")
+			require.NoError(t, err)
+			_, err = test.merge(tbl.Merge(test.source, test.keys...)).Execute()
+			require.Error(t, err)
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+			}
+			require.Equal(t, version, tbl.State.Version)
+			// no data file is written before the merge fails
+			after, err := tbl.Storage.List("")
+			require.NoError(t, err)
+			require.Len(t, after, len(objects))
+		})
+	}
+}
+
+func TestMergeCandidates(t *testing.T) {
+	tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+	metadata := tbl.State.CurrentMetadata
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	id, err := metadata.Schema.GetFieldByName("id")
+	require.NoError(t, err)
+	date, err := metadata.Schema.GetFieldByName("date")
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		keys      []*types.StructField
+		values    [][]any
+		wantFiles int
+	}{
+		"range of keys":  {keys: []*types.StructField{id}, values: [][]any{{int64(5)}, {int64(12)}}, wantFiles: 2},
+		"null keys":      {keys: []*types.StructField{id}, values: [][]any{nil}, wantFiles: 0},
+		"partition keys": {keys: []*types.StructField{date}, values: [][]any{{day}, {day.AddDate(0, 0, 2)}}, wantFiles: 5},
+		"composite":      {keys: []*types.StructField{id, date}, values: [][]any{{int64(35), day.AddDate(0, 0, 1)}}, wantFiles: 1},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := tbl.State.FilesMatching(mergeCandidates(metadata, test.keys, test.values))
+			require.NoError(t, err)
+			require.Len(t, files, test.wantFiles)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+)
+
+// DefaultOptimizeTargetSize is the size in bytes that Optimize compacts files up to.
+const DefaultOptimizeTargetSize = 256 << 20
+
+// OptimizeMetrics reports the work done by Optimize. The files added are the compacted
+// files, and the files removed those they replace.
+type OptimizeMetrics struct {
+	// Version is the version committed by Optimize, or the current version if there was
+	// nothing to compact.
+	Version int64
+	FileMetrics
+	// PartitionsOptimized is the number of partitions with compacted files.
+	PartitionsOptimized int64
+	// NumBins is the number of groups of files that were compacted together.
+	NumBins int64
+	// TotalConsideredFiles is the number of files matching the partition filter.
+	TotalConsideredFiles int64
+	// TotalFilesSkipped is the number of considered files that were left as they are.
+	TotalFilesSkipped int64
+}
+
+// operationMetrics returns the metrics of the OPTIMIZE commitInfo, with the counts of
+// partitions, bins and files considered.
+func (m *OptimizeMetrics) operationMetrics() map[string]interface{} {
+	metrics := m.FileMetrics.operationMetrics()
+	metrics["partitionsOptimized"] = strconv.FormatInt(m.PartitionsOptimized, 10)
+	metrics["numBatches"] = strconv.FormatInt(m.NumBins, 10)
+	metrics["totalConsideredFiles"] = strconv.FormatInt(m.TotalConsideredFiles, 10)
+	metrics["totalFilesSkipped"] = strconv.FormatInt(m.TotalFilesSkipped, 10)
+	return metrics
+}
+
+type OptimizeOption func(*optimizeOptions)
+
+type optimizeOptions struct {
+	targetSize       int64
+	filter           Expr
+	zOrder           []string
+	maxZOrderBinSize int64
+}
+
+// WithTargetFileSize sets the size in bytes that files are compacted up to. Files at least
+// this large are not compacted.
+func WithTargetFileSize(size int64) OptimizeOption {
+	return func(o *optimizeOptions) {
+		o.targetSize = size
+	}
+}
+
+// WithPartitionFilter only compacts the files of the partitions matching the predicate,
+// which may only use partition columns.
+func WithPartitionFilter(pred Expr) OptimizeOption {
+	return func(o *optimizeOptions) {
+		o.filter = pred
+	}
+}
+
+// WithZOrder clusters the rows of each partition by the columns instead of only compacting
+// small files: all the files of a partition are rewritten with their rows sorted along a
+// Z-order curve of the columns, which keeps rows with close values of every column in the
+// same files, so that the min/max statistics of the files skip files for filters on any
+// of the columns.
+//
+// All the rows of a partition are sorted in memory, unless WithMaxZOrderBinSize splits
+// it. Partitions whose files were all written by the same z-order of the columns are not
+// rewritten.
+func WithZOrder(columns ...string) OptimizeOption {
+	return func(o *optimizeOptions) {
+		o.zOrder = columns
+	}
+}
+
+// WithMaxZOrderBinSize caps the total size in bytes of the files z-ordered together, whose
+// rows are all held in memory. Larger partitions are split into bins that are sorted
+// separately, which clusters their rows less. By default, or if size is not positive, each
+// partition is a single bin.
+func WithMaxZOrderBinSize(size int64) OptimizeOption {
+	return func(o *optimizeOptions) {
+		o.maxZOrderBinSize = size
+	}
+}
+
+// Optimize compacts the small files of each partition of the table. Files smaller than
+// the target size are grouped into bins of up to the target size, in order of size, and the
+// files of each bin are rewritten as a single file. Bins of a single file are left as they are.
+//
+// The compacted files replace the original files in a single OPTIMIZE commit, where both the
+// add and the remove actions have dataChange set to false, as the data of the table does
+// not change. The commit fails with ErrCommitConflict if a concurrent commit removed any of
+// the replaced files. Tables whose writer version is not supported are refused before any
+// file is written.
+func (t *Table) Optimize(opts ...OptimizeOption) (*OptimizeMetrics, error) {
+	options := optimizeOptions{targetSize: DefaultOptimizeTargetSize}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if t.State.CurrentMetadata == nil {
+		return nil, errors.New("table has no metadata")
+	}
+	if !t.Config.RequireFiles {
+		return nil, errors.New("optimize requires a table state loaded with files")
+	}
+	// the commit would be refused after the compacted files are written
+	if err := t.State.checkWriterVersion(); err != nil {
+		return nil, err
+	}
+
+	files := t.State.activeFiles()
+	var err error
+	if options.filter != nil {
+		if files, err = t.State.FilesInPartitions(options.filter); err != nil {
+			return nil, err
+		}
+	}
+	var bins [][]*actions.Add
+	if len(options.zOrder) > 0 {
+		if options.zOrder, err = validateZOrderColumns(t.State.CurrentMetadata, options.zOrder); err != nil {
+			return nil, err
+		}
+		bins = zOrderBins(t.State.CurrentMetadata.PartitionColumns, files, options.zOrder, options.maxZOrderBinSize)
+	} else {
+		bins = optimizeBins(t.State.CurrentMetadata.PartitionColumns, files, options.targetSize)
+	}
+
+	metrics := &OptimizeMetrics{Version: t.State.Version, TotalConsideredFiles: int64(len(files))}
+	tx := t.NewTransaction(WithOperation("OPTIMIZE", map[string]interface{}{
+		"predicate": predicateParameter(options.filter),
+		"zOrderBy":  zOrderBy(options.zOrder),
+	}))
+	partitions := make(map[string]struct{})
+	now := time.Now().UnixMilli()
+	for _, bin := range bins {
+		var adds []*actions.Add
+		if len(options.zOrder) > 0 {
+			adds, err = t.rewriteFilesZOrder(bin, options.zOrder, options.targetSize)
+		} else {
+			adds, err = t.rewriteFiles(bin)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, add := range adds {
+			tx.AddAction(add)
+			metrics.fileAdded(add)
+		}
+		for _, add := range bin {
+			tx.AddAction(actions.NewRemove(add.Path, now, false, true, add.PartitionValues, add.Size, add.Tags))
+			metrics.fileRemoved(add)
+		}
+		partitions[partitionPath(t.State.CurrentMetadata.PartitionColumns, bin[0].PartitionValues)] = struct{}{}
+	}
+	metrics.NumBins = int64(len(bins))
+	metrics.PartitionsOptimized = int64(len(partitions))
+	metrics.TotalFilesSkipped = metrics.TotalConsideredFiles - metrics.NumFilesRemoved
+
+	log.Debug().
+		Int64("bins", metrics.NumBins).
+		Int64("filesRemoved", metrics.NumFilesRemoved).
+		Int64("filesAdded", metrics.NumFilesAdded).
+		Msg("optimize")
+	if len(bins) == 0 {
+		return metrics, nil
+	}
+
+	tx.SetOperationMetrics(metrics.operationMetrics())
+	version, err := tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	metrics.Version = version
+	return metrics, nil
+}
+
+// optimizeBins groups the files smaller than the target size by partition and packs them,
+// smallest first, into bins of up to the target size. Bins of a single file are dropped.
+func optimizeBins(partitionColumns []string, files []*actions.Add, targetSize int64) [][]*actions.Add {
+	partitions := make(map[string][]*actions.Add)
+	var order []string
+	for _, add := range files {
+		if add.Size >= targetSize {
+			continue
+		}
+		dir := partitionPath(partitionColumns, add.PartitionValues)
+		if _, ok := partitions[dir]; !ok {
+			order = append(order, dir)
+		}
+		partitions[dir] = append(partitions[dir], add)
+	}
+	sort.Strings(order)
+
+	var bins [][]*actions.Add
+	for _, dir := range order {
+		candidates := partitions[dir]
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Size < candidates[j].Size })
+
+		var bin []*actions.Add
+		var size int64
+		for _, add := range candidates {
+			if len(bin) > 0 && size+add.Size > targetSize {
+				if len(bin) > 1 {
+					bins = append(bins, bin)
+				}
+				bin, size = nil, 0
+			}
+			bin = append(bin, add)
+			size += add.Size
+		}
+		if len(bin) > 1 {
+			bins = append(bins, bin)
+		}
+	}
+	return bins
+}
+
+// rewriteFiles writes the rows of the files to new data files, flagged as not changing
+// data, and returns their add actions.
+func (t *Table) rewriteFiles(files []*actions.Add) ([]*actions.Add, error) {
+	scanner := newScanner(t.Storage, t.State.CurrentMetadata, files)
+	defer scanner.Close()
+	writer, err := t.NewWriter(WithDataChange(false))
+	if err != nil {
+		return nil, err
+	}
+	for scanner.Next() {
+		if err := writer.Write(scanner.Row()); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return writer.Close()
+}
+
+// predicateParameter formats the predicate as the predicate parameter of an operation in
+// the commitInfo, a JSON array of predicates like Spark.
+func predicateParameter(pred Expr) string {
+	predicates := []string{}
+	if pred != nil {
+		predicates = append(predicates, pred.String())
+	}
+	data, _ := json.Marshal(predicates)
+	return string(data)
+}
@@ -0,0 +1,168 @@
+package deltalake
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+)
+
+// sortedIDs returns the sorted ids of the rows.
+func sortedIDs(rows []map[string]any) []int64 {
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row["id"].(int64)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func TestTable_Optimize(t *testing.T) {
+	tests := map[string]struct {
+		opts        []OptimizeOption
+		wantMetrics OptimizeMetrics
+		wantFiles   int
+		wantCommit  bool
+	}{
+		"all partitions": {
+			wantMetrics: OptimizeMetrics{
+				FileMetrics:          FileMetrics{NumFilesAdded: 2, NumFilesRemoved: 5},
+				PartitionsOptimized:  2,
+				NumBins:              2,
+				TotalConsideredFiles: 6,
+				TotalFilesSkipped:    1,
+			},
+			wantFiles:  3,
+			wantCommit: true,
+		},
+		"partition filter": {
+			opts: []OptimizeOption{WithPartitionFilter(Eq(Col("date"), Lit("2021-01-01")))},
+			wantMetrics: OptimizeMetrics{
+				FileMetrics:          FileMetrics{NumFilesAdded: 1, NumFilesRemoved: 3},
+				PartitionsOptimized:  1,
+				NumBins:              1,
+				TotalConsideredFiles: 3,
+			},
+			wantFiles:  4,
+			wantCommit: true,
+		},
+		"files larger than target": {
+			opts:        []OptimizeOption{WithTargetFileSize(1)},
+			wantMetrics: OptimizeMetrics{TotalConsideredFiles: 6, TotalFilesSkipped: 6},
+			wantFiles:   6,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			version := tbl.State.Version
+			before := sortedIDs(scanAll(t, tbl))
+
+			metrics, err := tbl.Optimize(test.opts...)
+			require.NoError(t, err)
+			got := *metrics
+			got.Version, got.NumBytesAdded, got.NumBytesRemoved = 0, 0, 0
+			require.Equal(t, test.wantMetrics, got)
+			require.Len(t, tbl.State.activeFiles(), test.wantFiles)
+			require.Equal(t, before, sortedIDs(scanAll(t, tbl)))
+
+			if !test.wantCommit {
+				require.Equal(t, version, metrics.Version)
+				require.Equal(t, version, tbl.State.Version)
+				return
+			}
+			require.Equal(t, version+1, metrics.Version)
+			require.Positive(t, metrics.NumBytesAdded)
+			require.Positive(t, metrics.NumBytesRemoved)
+
+			// the commit does not change data
+			acts, err := tbl.peakNextCommit(version)
+			require.NoError(t, err)
+			for _, action := range acts {
+				switch a := action.(type) {
+				case *actions.Add:
+					require.False(t, a.DataChange)
+					require.NotNil(t, a.Stats)
+				case *actions.Remove:
+					require.False(t, a.DataChange)
+				}
+			}
+			history, err := tbl.History(1)
+			require.NoError(t, err)
+			require.Equal(t, "OPTIMIZE", history[0].CommitInfo.Operation)
+			require.Equal(t, metrics.operationMetrics(), history[0].CommitInfo.OperationMetrics)
+
+			// reloading gives the same files
+			loaded, err := LoadTable(tbl.Storage, nil)
+			require.NoError(t, err)
+			require.Len(t, loaded.State.activeFiles(), test.wantFiles)
+		})
+	}
+}
+
+func TestTable_OptimizeUnsupportedProtocol(t *testing.T) {
+	for name, opts := range map[string][]OptimizeOption{
+		"compaction": nil,
+		"z-order":    {WithZOrder("id", "name")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			tbl.State.MinWriterVersion = 3
+			objects, err := tbl.Storage.List("")
+			require.NoError(t, err)
+
+			_, err = tbl.Optimize(opts...)
+			require.Error(t, err)
+			after, err := tbl.Storage.List("")
+			require.NoError(t, err)
+			require.Len(t, after, len(objects))
+		})
+	}
+}
+
+func TestTable_OptimizeNonPartitionFilter(t *testing.T) {
+	tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+	_, err := tbl.Optimize(WithPartitionFilter(Eq(Col("id"), Lit(1))))
+	require.ErrorIs(t, err, ErrNotPartitionColumn)
+}
+
+func TestOptimizeBins(t *testing.T) {
+	add := func(path string, size int64, date string) *actions.Add {
+		return actions.NewAdd(path, size, map[string]string{"date": date}, true, 0, nil, nil)
+	}
+	tests := map[string]struct {
+		files []*actions.Add
+		want  [][]string
+	}{
+		"packs smallest first": {
+			files: []*actions.Add{add("a", 60, "1"), add("b", 10, "1"), add("c", 30, "1"), add("d", 50, "1")},
+			want:  [][]string{{"b", "c", "d"}},
+		},
+		"by partition": {
+			files: []*actions.Add{add("a", 10, "1"), add("b", 10, "2"), add("c", 10, "1"), add("d", 10, "2")},
+			want:  [][]string{{"a", "c"}, {"b", "d"}},
+		},
+		"large files skipped": {
+			files: []*actions.Add{add("a", 100, "1"), add("b", 150, "1"), add("c", 10, "1")},
+		},
+		"several bins": {
+			files: []*actions.Add{add("a", 40, "1"), add("b", 40, "1"), add("c", 40, "1"), add("d", 40, "1"), add("e", 40, "1")},
+			want:  [][]string{{"a", "b"}, {"c", "d"}},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got [][]string
+			for _, bin := range optimizeBins([]string{"date"}, test.files, 100) {
+				var paths []string
+				for _, add := range bin {
+					paths = append(paths, add.Path)
+				}
+				got = append(got, paths)
+			}
+			require.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package deltalake
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/types"
+)
+
+// ErrNotPartitionColumn is returned when a partition filter references a column that is not
+// a partition column.
+var ErrNotPartitionColumn = errors.New("not a partition column")
+
+// hiveDefaultPartition is the directory name used for null partition values.
+const hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// formatPartitionValue serializes a normalized value as a partition value string.
+// Null values are serialized as an empty string.
+// https://github.com/delta-io/delta/blob/master/PROTOCOL.md#partition-value-serialization
+func formatPartitionValue(dt types.DataType, value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case time.Time:
+		if dt == types.DataTypeDate {
+			return v.Format("2006-01-02"), nil
+		}
+		return v.UTC().Format("2006-01-02 15:04:05.999999"), nil
+	case *big.Rat:
+		return formatDecimal(dt, v)
+	}
+	return "", fmt.Errorf("unsupported partition value type %T", value)
+}
+
+// partitionPath returns the directory of the partition relative to the table root, like
+//
+//	"date=2021-01-01/country=US"
+//
+// Column names and values are escaped the same way Hive does.
+func partitionPath(columns []string, values map[string]string) string {
+	var sb strings.Builder
+	for i, column := range columns {
+		if i > 0 {
+			sb.WriteByte('/')
+		}
+		sb.WriteString(escapePartitionName(column))
+		sb.WriteByte('=')
+		if value := values[column]; value == "" {
+			sb.WriteString(hiveDefaultPartition)
+		} else {
+			sb.WriteString(escapePartitionName(value))
+		}
+	}
+	return sb.String()
+}
+
+// escapePartitionName escapes the characters that are not allowed in partition
+// directory names as %XX.
+func escapePartitionName(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if needsPartitionEscape(c) {
+			fmt.Fprintf(&sb, "%%%02X", c)
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func needsPartitionEscape(c byte) bool {
+	if c < 0x20 || c == 0x7f {
+		return true
+	}
+	switch c {
+	case '"', '#', '%', '\'', '*', '/', ':', '=', '?', '\\', '{', '[', ']', '^':
+		return true
+	}
+	return false
+}
+
+// encodePath encodes the relative path of a data file as the URI stored in add and remove actions.
+func encodePath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+// partitionTimestampLayouts are the layouts accepted when parsing timestamp partition values.
+var partitionTimestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02",
+}
+
+// parsePartitionValue parses a serialized partition value into the Go type of the
+// delta type. An empty string is a null value.
+func parsePartitionValue(dt types.DataType, value string) (any, error) {
+	if value == "" {
+		return nil, nil
+	}
+	switch dt {
+	case types.DataTypeString:
+		return value, nil
+	case types.DataTypeBinary:
+		return []byte(value), nil
+	case types.DataTypeBoolean, types.DataTypeBool:
+		return strconv.ParseBool(value)
+	case types.DataTypeLong:
+		return strconv.ParseInt(value, 10, 64)
+	case types.DataTypeInteger:
+		i, err := strconv.ParseInt(value, 10, 32)
+		return int32(i), err
+	case types.DataTypeShort:
+		i, err := strconv.ParseInt(value, 10, 16)
+		return int16(i), err
+	case types.DataTypeByte:
+		i, err := strconv.ParseInt(value, 10, 8)
+		return int8(i), err
+	case types.DataTypeDouble:
+		return strconv.ParseFloat(value, 64)
+	case types.DataTypeFloat:
+		f, err := strconv.ParseFloat(value, 32)
+		return float32(f), err
+	case types.DataTypeDate:
+		return time.Parse("2006-01-02", value)
+	case types.DataTypeTimestamp:
+		for _, layout := range partitionTimestampLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC(), nil
+			}
+		}
+		return nil, fmt.Errorf("invalid timestamp %q", value)
+	}
+	if _, _, ok := dt.Decimal(); ok {
+		return parseDecimal(dt, value)
+	}
+	return nil, fmt.Errorf("unsupported partition column type %s", dt)
+}
+
+// PartitionValues returns the partition values of the data file of the add action, parsed
+// into the Go types of the partition columns (see normalizeRecord). Null values, which are
+// absent or empty in the add action, are nil.
+func (m *TableMetadata) PartitionValues(add *actions.Add) (map[string]any, error) {
+	values := make(map[string]any, len(m.PartitionColumns))
+	for _, column := range m.PartitionColumns {
+		field, err := m.Schema.GetFieldByName(column)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parsePartitionValue(field.Type, add.PartitionValues[column])
+		if err != nil {
+			return nil, fmt.Errorf("%s: partition column %s: %w", add.Path, column, err)
+		}
+		values[field.Name] = value
+	}
+	return values, nil
+}
+
+// FilesInPartitions returns the data files of the table state whose partition values
+// match the predicate, which may only reference partition columns, for example
+//
+//	files, err := state.FilesInPartitions(Ge(Col("date"), Lit("2024-01-01")))
+//
+// Partition values are compared with the types of their columns, so dates, numbers and
+// decimals are ordered by value rather than as strings. Null partition values only match
+// IS NULL. No data is read.
+func (s *TableState) FilesInPartitions(pred Expr) ([]*actions.Add, error) {
+	if s.CurrentMetadata == nil {
+		return nil, errors.New("table state has no metadata")
+	}
+	metadata := s.CurrentMetadata
+	for _, path := range exprColumns(pred) {
+		field := lookupField(&metadata.Schema, path[0])
+		if len(path) != 1 || field == nil || !isPartitionColumn(metadata.PartitionColumns, field.Name) {
+			return nil, fmt.Errorf("%w: %s", ErrNotPartitionColumn, strings.Join(path, "."))
+		}
+	}
+
+	files := make([]*actions.Add, 0, len(s.Files))
+	for _, add := range s.activeFiles() {
+		values, err := metadata.PartitionValues(add)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := Matches(pred, values)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", add.Path, err)
+		}
+		if ok {
+			files = append(files, add)
+		}
+	}
+	log.Debug().Str("predicate", pred.String()).Int("files", len(files)).Msg("partition pruning")
+	return files, nil
+}
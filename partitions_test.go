@@ -0,0 +1,143 @@
+package deltalake
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+func TestTableState_FilesInPartitions(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	schema := *types.NewStruct(
+		types.NewStructField("id", types.DataTypeLong, false, nil),
+		types.NewStructField("date", types.DataTypeDate, true, nil),
+		types.NewStructField("hour", types.DataTypeInteger, true, nil),
+		types.NewStructField("price", types.DecimalType(5, 2), true, nil),
+		types.NewStructField("ts", types.DataTypeTimestamp, true, nil),
+		types.NewStructField("active", types.DataTypeBoolean, true, nil),
+	)
+	tbl, err := CreateTable(store, &TableMetadata{Schema: schema, PartitionColumns: []string{"date", "hour", "price", "ts", "active"}})
+	require.NoError(t, err)
+
+	// the files are never read
+	files := map[string]map[string]string{
+		"a": {"date": "2023-12-31", "hour": "9", "price": "9.50", "ts": "2023-12-31 23:59:59.999999", "active": "true"},
+		"b": {"date": "2024-01-01", "hour": "10", "price": "10.00", "ts": "2024-01-01 00:00:00", "active": "false"},
+		"c": {"date": "2024-02-01", "hour": "", "price": "100.25"},
+	}
+	tx := tbl.NewTransaction()
+	for path, values := range files {
+		tx.AddAction(actions.NewAdd(path, 1, values, true, time.Now().UnixMilli(), nil, nil))
+	}
+	_, err = tx.Commit()
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		pred Expr
+		want []string
+	}{
+		"date":              {pred: Ge(Col("date"), Lit("2024-01-01")), want: []string{"b", "c"}},
+		"date as time":      {pred: Lt(Col("date"), Lit(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))), want: []string{"a"}},
+		"integer":           {pred: Lt(Col("hour"), Lit(10)), want: []string{"a"}},   // "10" < "9" as strings
+		"integer as string": {pred: Ge(Col("hour"), Lit("10")), want: []string{"b"}}, // null does not match
+		"decimal":           {pred: Gt(Col("price"), Lit("9.5")), want: []string{"b", "c"}},
+		"decimal equal":     {pred: Eq(Col("price"), Lit(10)), want: []string{"b"}},
+		"decimal as rat":    {pred: Le(Col("price"), Lit(big.NewRat(19, 2))), want: []string{"a"}},
+		"timestamp":         {pred: Ge(Col("ts"), Lit("2024-01-01 00:00:00")), want: []string{"b"}},
+		"boolean":           {pred: Eq(Col("active"), Lit(true)), want: []string{"a"}},
+		"null":              {pred: IsNull(Col("hour")), want: []string{"c"}},
+		"absent is null":    {pred: IsNull(Col("ts")), want: []string{"c"}},
+		"in":                {pred: In(Col("hour"), 9, 11), want: []string{"a"}},
+		"case-insensitive":  {pred: Eq(Col("DATE"), Lit("2024-02-01")), want: []string{"c"}},
+		"and":               {pred: And(Ge(Col("date"), Lit("2024-01-01")), IsNotNull(Col("hour"))), want: []string{"b"}},
+		"not":               {pred: Not(Eq(Col("active"), Lit(false))), want: []string{"a"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := tbl.State.FilesInPartitions(test.pred)
+			require.NoError(t, err)
+			var got []string
+			for _, add := range files {
+				got = append(got, add.Path)
+			}
+			require.ElementsMatch(t, test.want, got)
+		})
+	}
+
+	_, err = tbl.State.FilesInPartitions(Eq(Col("id"), Lit(1)))
+	require.ErrorIs(t, err, ErrNotPartitionColumn)
+	_, err = tbl.State.FilesInPartitions(Eq(Col("date"), Lit("not a date")))
+	require.Error(t, err)
+}
+
+func TestTableMetadata_PartitionValues(t *testing.T) {
+	metadata := &TableMetadata{
+		Schema: *types.NewStruct(
+			types.NewStructField("id", types.DataTypeLong, false, nil),
+			types.NewStructField("date", types.DataTypeDate, true, nil),
+			types.NewStructField("price", types.DecimalType(5, 2), true, nil),
+		),
+		PartitionColumns: []string{"date", "price"},
+	}
+
+	values, err := metadata.PartitionValues(&actions.Add{PartitionValues: map[string]string{"date": "2024-01-01"}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"date": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "price": nil}, values)
+
+	_, err = metadata.PartitionValues(&actions.Add{PartitionValues: map[string]string{"price": "abc"}})
+	require.Error(t, err)
+}
+
+func TestWriter_DecimalPartition(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	schema := *types.NewStruct(
+		types.NewStructField("id", types.DataTypeLong, false, nil),
+		types.NewStructField("price", types.DecimalType(5, 2), true, nil),
+	)
+	tbl, err := CreateTable(store, &TableMetadata{Schema: schema, PartitionColumns: []string{"price"}})
+	require.NoError(t, err)
+
+	w, err := tbl.NewWriter()
+	require.NoError(t, err)
+	require.NoError(t, w.Write(
+		map[string]any{"id": 1, "price": big.NewRat(5, 4)},
+		map[string]any{"id": 2, "price": "3.5"},
+	))
+	require.Error(t, w.Write(map[string]any{"id": 3, "price": "1000"}))
+	adds, err := w.Close()
+	require.NoError(t, err)
+	tx := tbl.NewTransaction()
+	tx.AddActions(toActions(adds)...)
+	_, err = tx.Commit()
+	require.NoError(t, err)
+
+	var prices []string
+	for _, add := range adds {
+		prices = append(prices, add.PartitionValues["price"])
+	}
+	require.ElementsMatch(t, []string{"1.25", "3.50"}, prices)
+
+	type record struct {
+		ID    int64    `delta:"id"`
+		Price *big.Rat `delta:"price"`
+	}
+	scanner, err := tbl.NewScanner()
+	require.NoError(t, err)
+	defer scanner.Close()
+	var got []record
+	for scanner.Next() {
+		var r record
+		require.NoError(t, scanner.Scan(&r))
+		got = append(got, r)
+	}
+	require.NoError(t, scanner.Err())
+	require.ElementsMatch(t, []record{{ID: 1, Price: big.NewRat(5, 4)}, {ID: 2, Price: big.NewRat(7, 2)}}, got)
+}
@@ -0,0 +1,788 @@
+package deltalake
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"deltalake/types"
+)
+
+// Expr is an expression over the columns of a record, such as a predicate used to filter
+// the rows and files of a table.
+//
+//	pred := And(Ge(Col("date"), Lit("2024-01-01")), In(Col("country"), "US", "CA"))
+//
+// Expressions follow SQL semantics: nil is null, comparisons with null are null, and
+// And, Or and Not use three-valued logic.
+type Expr interface {
+	// Eval evaluates the expression against a record keyed by column name.
+	Eval(record map[string]any) (any, error)
+	String() string
+}
+
+type columnExpr struct {
+	path []string
+}
+
+// Col returns an expression for the value of a column. Columns of nested structs are
+// referenced by their path, for example Col("a", "b") for the field b of the struct column a.
+func Col(path ...string) Expr {
+	return &columnExpr{path: path}
+}
+
+func (e *columnExpr) Eval(record map[string]any) (any, error) {
+	var v any = record
+	for _, name := range e.path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		v = lookupValue(m, name)
+	}
+	return v, nil
+}
+
+func (e *columnExpr) String() string {
+	return strings.Join(e.path, ".")
+}
+
+// lookupValue returns the value of the column, preferring an exact match of the name
+// over a case-insensitive match.
+func lookupValue(record map[string]any, name string) any {
+	if v, ok := record[name]; ok {
+		return v
+	}
+	for k, v := range record {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+type literalExpr struct {
+	value any
+}
+
+// Lit returns an expression for a constant value. Dates and timestamps can be given as
+// time.Time or as strings like "2006-01-02" and "2006-01-02 15:04:05".
+func Lit(value any) Expr {
+	return &literalExpr{value: value}
+}
+
+func (e *literalExpr) Eval(map[string]any) (any, error) {
+	return e.value, nil
+}
+
+func (e *literalExpr) String() string {
+	return formatLiteral(e.value)
+}
+
+func formatLiteral(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "\\'") + "'"
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'"
+	case []byte:
+		return fmt.Sprintf("X'%X'", v)
+	case *big.Rat:
+		if v.IsInt() {
+			return v.RatString()
+		}
+		return strings.TrimRight(v.FloatString(types.MaxDecimalPrecision), "0")
+	}
+	return fmt.Sprint(v)
+}
+
+type compareOp string
+
+const (
+	opEq compareOp = "="
+	opNe compareOp = "!="
+	opLt compareOp = "<"
+	opLe compareOp = "<="
+	opGt compareOp = ">"
+	opGe compareOp = ">="
+)
+
+// flip returns the operator with the operands swapped, such that a op b == b op.flip() a.
+func (op compareOp) flip() compareOp {
+	switch op {
+	case opLt:
+		return opGt
+	case opLe:
+		return opGe
+	case opGt:
+		return opLt
+	case opGe:
+		return opLe
+	}
+	return op
+}
+
+// negate returns the operator of the negated comparison.
+func (op compareOp) negate() compareOp {
+	switch op {
+	case opEq:
+		return opNe
+	case opNe:
+		return opEq
+	case opLt:
+		return opGe
+	case opLe:
+		return opGt
+	case opGt:
+		return opLe
+	}
+	return opLt
+}
+
+func (op compareOp) test(c int) bool {
+	switch op {
+	case opEq:
+		return c == 0
+	case opNe:
+		return c != 0
+	case opLt:
+		return c < 0
+	case opLe:
+		return c <= 0
+	case opGt:
+		return c > 0
+	}
+	return c >= 0
+}
+
+type comparisonExpr struct {
+	op          compareOp
+	left, right Expr
+}
+
+// Eq returns a predicate that is true if left is equal to right.
+func Eq(left, right Expr) Expr { return &comparisonExpr{op: opEq, left: left, right: right} }
+
+// Ne returns a predicate that is true if left is not equal to right.
+func Ne(left, right Expr) Expr { return &comparisonExpr{op: opNe, left: left, right: right} }
+
+// Lt returns a predicate that is true if left is less than right.
+func Lt(left, right Expr) Expr { return &comparisonExpr{op: opLt, left: left, right: right} }
+
+// Le returns a predicate that is true if left is less than or equal to right.
+func Le(left, right Expr) Expr { return &comparisonExpr{op: opLe, left: left, right: right} }
+
+// Gt returns a predicate that is true if left is greater than right.
+func Gt(left, right Expr) Expr { return &comparisonExpr{op: opGt, left: left, right: right} }
+
+// Ge returns a predicate that is true if left is greater than or equal to right.
+func Ge(left, right Expr) Expr { return &comparisonExpr{op: opGe, left: left, right: right} }
+
+func (e *comparisonExpr) Eval(record map[string]any) (any, error) {
+	l, err := e.left.Eval(record)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.Eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	c, ok := compareAny(l, r)
+	if !ok {
+		return nil, fmt.Errorf("cannot compare %T and %T in %s", l, r, e)
+	}
+	return e.op.test(c), nil
+}
+
+func (e *comparisonExpr) String() string {
+	return fmt.Sprintf("%s %s %s", e.left, e.op, e.right)
+}
+
+type inExpr struct {
+	expr   Expr
+	values []any
+}
+
+// In returns a predicate that is true if the value of the expression is one of the values.
+func In(expr Expr, values ...any) Expr {
+	return &inExpr{expr: expr, values: values}
+}
+
+func (e *inExpr) Eval(record map[string]any) (any, error) {
+	v, err := e.expr.Eval(record)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	hasNull := false
+	for _, value := range e.values {
+		if value == nil {
+			hasNull = true
+			continue
+		}
+		c, ok := compareAny(v, value)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %T and %T in %s", v, value, e)
+		}
+		if c == 0 {
+			return true, nil
+		}
+	}
+	if hasNull {
+		return nil, nil
+	}
+	return false, nil
+}
+
+func (e *inExpr) String() string {
+	values := make([]string, len(e.values))
+	for i, v := range e.values {
+		values[i] = formatLiteral(v)
+	}
+	return fmt.Sprintf("%s IN (%s)", e.expr, strings.Join(values, ", "))
+}
+
+type isNullExpr struct {
+	expr   Expr
+	negate bool
+}
+
+// IsNull returns a predicate that is true if the value of the expression is null.
+func IsNull(expr Expr) Expr { return &isNullExpr{expr: expr} }
+
+// IsNotNull returns a predicate that is true if the value of the expression is not null.
+func IsNotNull(expr Expr) Expr { return &isNullExpr{expr: expr, negate: true} }
+
+func (e *isNullExpr) Eval(record map[string]any) (any, error) {
+	v, err := e.expr.Eval(record)
+	if err != nil {
+		return nil, err
+	}
+	return (v == nil) != e.negate, nil
+}
+
+func (e *isNullExpr) String() string {
+	if e.negate {
+		return fmt.Sprintf("%s IS NOT NULL", e.expr)
+	}
+	return fmt.Sprintf("%s IS NULL", e.expr)
+}
+
+type andExpr struct {
+	exprs []Expr
+}
+
+// And returns a predicate that is true if all the predicates are true.
+func And(exprs ...Expr) Expr { return &andExpr{exprs: exprs} }
+
+func (e *andExpr) Eval(record map[string]any) (any, error) {
+	result := any(true)
+	for _, expr := range e.exprs {
+		v, err := evalBool(expr, record)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			result = nil
+		} else if !v.(bool) {
+			return false, nil
+		}
+	}
+	return result, nil
+}
+
+func (e *andExpr) String() string {
+	return joinExprs(e.exprs, " AND ")
+}
+
+type orExpr struct {
+	exprs []Expr
+}
+
+// Or returns a predicate that is true if any of the predicates is true.
+func Or(exprs ...Expr) Expr { return &orExpr{exprs: exprs} }
+
+func (e *orExpr) Eval(record map[string]any) (any, error) {
+	result := any(false)
+	for _, expr := range e.exprs {
+		v, err := evalBool(expr, record)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			result = nil
+		} else if v.(bool) {
+			return true, nil
+		}
+	}
+	return result, nil
+}
+
+func (e *orExpr) String() string {
+	return joinExprs(e.exprs, " OR ")
+}
+
+type notExpr struct {
+	expr Expr
+}
+
+// Not returns a predicate that is true if the predicate is false.
+func Not(expr Expr) Expr { return &notExpr{expr: expr} }
+
+func (e *notExpr) Eval(record map[string]any) (any, error) {
+	v, err := evalBool(e.expr, record)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return !v.(bool), nil
+}
+
+func (e *notExpr) String() string {
+	return fmt.Sprintf("NOT (%s)", e.expr)
+}
+
+type arithmeticOp string
+
+const (
+	opAdd arithmeticOp = "+"
+	opSub arithmeticOp = "-"
+	opMul arithmeticOp = "*"
+	opDiv arithmeticOp = "/"
+)
+
+type arithmeticExpr struct {
+	op          arithmeticOp
+	left, right Expr
+}
+
+// Add returns an expression for the sum of two numbers.
+func Add(left, right Expr) Expr { return &arithmeticExpr{op: opAdd, left: left, right: right} }
+
+// Sub returns an expression for the difference of two numbers.
+func Sub(left, right Expr) Expr { return &arithmeticExpr{op: opSub, left: left, right: right} }
+
+// Mul returns an expression for the product of two numbers.
+func Mul(left, right Expr) Expr { return &arithmeticExpr{op: opMul, left: left, right: right} }
+
+// Div returns an expression for the quotient of two numbers. As in Spark, integers are
+// divided as doubles, and dividing by zero is null.
+func Div(left, right Expr) Expr { return &arithmeticExpr{op: opDiv, left: left, right: right} }
+
+// Eval evaluates the expression. Integers are computed as int64 and fail on overflow,
+// decimals as *big.Rat and other numbers as float64.
+func (e *arithmeticExpr) Eval(record map[string]any) (any, error) {
+	l, err := e.left.Eval(record)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.Eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	lv, rv := reflect.ValueOf(l), reflect.ValueOf(r)
+	_, lDecimal := l.(*big.Rat)
+	_, rDecimal := r.(*big.Rat)
+	if !(lDecimal || isNumber(lv.Kind())) || !(rDecimal || isNumber(rv.Kind())) {
+		return nil, fmt.Errorf("cannot apply %s to %T and %T in %s", e.op, l, r, e)
+	}
+
+	switch {
+	case lDecimal || rDecimal:
+		x, y := toDecimal(l), toDecimal(r)
+		if x == nil || y == nil {
+			return nil, fmt.Errorf("cannot apply %s to %v and %v in %s", e.op, l, r, e)
+		}
+		return e.op.applyDecimal(x, y), nil
+	case isInteger(lv.Kind()) && isInteger(rv.Kind()) && e.op != opDiv:
+		if !lv.CanInt() || !rv.CanInt() {
+			return nil, fmt.Errorf("unsigned integers are not supported in %s", e)
+		}
+		v, ok := e.op.applyInt(lv.Int(), rv.Int())
+		if !ok {
+			return nil, fmt.Errorf("integer overflow in %s", e)
+		}
+		return v, nil
+	}
+	return e.op.applyFloat(toFloat64(lv), toFloat64(rv)), nil
+}
+
+func (e *arithmeticExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.left, e.op, e.right)
+}
+
+// applyInt returns the result of the operation, or false on overflow.
+func (op arithmeticOp) applyInt(a, b int64) (int64, bool) {
+	switch op {
+	case opAdd:
+		c := a + b
+		return c, (c > a) == (b > 0)
+	case opSub:
+		c := a - b
+		return c, (c < a) == (b > 0)
+	}
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	return c, c/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)
+}
+
+// applyFloat returns the result of the operation, or nil when dividing by zero.
+func (op arithmeticOp) applyFloat(a, b float64) any {
+	switch op {
+	case opAdd:
+		return a + b
+	case opSub:
+		return a - b
+	case opMul:
+		return a * b
+	}
+	if b == 0 {
+		return nil
+	}
+	return a / b
+}
+
+// applyDecimal returns the result of the operation, or nil when dividing by zero.
+func (op arithmeticOp) applyDecimal(a, b *big.Rat) any {
+	switch op {
+	case opAdd:
+		return new(big.Rat).Add(a, b)
+	case opSub:
+		return new(big.Rat).Sub(a, b)
+	case opMul:
+		return new(big.Rat).Mul(a, b)
+	}
+	if b.Sign() == 0 {
+		return nil
+	}
+	return new(big.Rat).Quo(a, b)
+}
+
+// toDecimal converts a decimal or a number to a decimal, or returns nil for NaN and
+// infinities.
+func toDecimal(v any) *big.Rat {
+	if r, ok := v.(*big.Rat); ok {
+		return r
+	}
+	return toRat(reflect.ValueOf(v))
+}
+
+func joinExprs(exprs []Expr, sep string) string {
+	s := make([]string, len(exprs))
+	for i, expr := range exprs {
+		s[i] = expr.String()
+	}
+	return "(" + strings.Join(s, sep) + ")"
+}
+
+// exprColumns returns the paths of the columns referenced by the expression.
+func exprColumns(expr Expr) [][]string {
+	switch e := expr.(type) {
+	case *columnExpr:
+		return [][]string{e.path}
+	case *comparisonExpr:
+		return append(exprColumns(e.left), exprColumns(e.right)...)
+	case *inExpr:
+		return exprColumns(e.expr)
+	case *isNullExpr:
+		return exprColumns(e.expr)
+	case *notExpr:
+		return exprColumns(e.expr)
+	case *andExpr:
+		return joinColumns(e.exprs)
+	case *orExpr:
+		return joinColumns(e.exprs)
+	case *arithmeticExpr:
+		return append(exprColumns(e.left), exprColumns(e.right)...)
+	}
+	return nil
+}
+
+func joinColumns(exprs []Expr) [][]string {
+	var columns [][]string
+	for _, expr := range exprs {
+		columns = append(columns, exprColumns(expr)...)
+	}
+	return columns
+}
+
+// exprType returns the type of the values of the expression for records of the schema,
+// or an error if the columns of the expression are not in the schema or the operands of
+// arithmetic are not numbers. Literals of integers, other than int8, int16 and int32, are
+// long, and untyped nulls are null.
+func exprType(schema *types.StructType, expr Expr) (types.DataType, error) {
+	switch e := expr.(type) {
+	case *columnExpr:
+		field, err := lookupFieldPath(schema, e.path)
+		if err != nil {
+			return "", err
+		}
+		return field.Type, nil
+	case *literalExpr:
+		return literalType(e.value)
+	case *arithmeticExpr:
+		left, err := exprType(schema, e.left)
+		if err != nil {
+			return "", err
+		}
+		right, err := exprType(schema, e.right)
+		if err != nil {
+			return "", err
+		}
+		if !isNumericType(left) || !isNumericType(right) {
+			return "", fmt.Errorf("cannot apply %s to %s and %s in %s", e.op, left, right, e)
+		}
+		_, _, lDecimal := left.Decimal()
+		_, _, rDecimal := right.Decimal()
+		switch {
+		case left == types.DataTypeNull || right == types.DataTypeNull:
+			return types.DataTypeNull, nil
+		case lDecimal || rDecimal:
+			return types.DecimalType(types.MaxDecimalPrecision, types.MaxDecimalPrecision/2), nil
+		case e.op == opDiv || isFloatingType(left) || isFloatingType(right):
+			return types.DataTypeDouble, nil
+		}
+		return types.DataTypeLong, nil
+	}
+	for _, path := range exprColumns(expr) {
+		if _, err := lookupFieldPath(schema, path); err != nil {
+			return "", err
+		}
+	}
+	return types.DataTypeBoolean, nil
+}
+
+// literalType returns the type of a literal value.
+func literalType(v any) (types.DataType, error) {
+	switch v.(type) {
+	case nil:
+		return types.DataTypeNull, nil
+	case string:
+		return types.DataTypeString, nil
+	case bool:
+		return types.DataTypeBoolean, nil
+	case []byte:
+		return types.DataTypeBinary, nil
+	case time.Time:
+		return types.DataTypeTimestamp, nil
+	case *big.Rat:
+		return types.DecimalType(types.MaxDecimalPrecision, types.MaxDecimalPrecision/2), nil
+	case int8:
+		return types.DataTypeByte, nil
+	case int16:
+		return types.DataTypeShort, nil
+	case int32:
+		return types.DataTypeInteger, nil
+	case float32:
+		return types.DataTypeFloat, nil
+	case float64:
+		return types.DataTypeDouble, nil
+	}
+	if isInteger(reflect.ValueOf(v).Kind()) {
+		return types.DataTypeLong, nil
+	}
+	return "", fmt.Errorf("unsupported literal %v of type %T", v, v)
+}
+
+// isIntegralType returns true for the integer types.
+func isIntegralType(dt types.DataType) bool {
+	switch dt {
+	case types.DataTypeByte, types.DataTypeShort, types.DataTypeInteger, types.DataTypeLong:
+		return true
+	}
+	return false
+}
+
+// isFloatingType returns true for the floating point types.
+func isFloatingType(dt types.DataType) bool {
+	return dt == types.DataTypeFloat || dt == types.DataTypeDouble
+}
+
+// isNumericType returns true for the number types, and for null which can be used as any.
+func isNumericType(dt types.DataType) bool {
+	_, _, decimal := dt.Decimal()
+	return decimal || isIntegralType(dt) || isFloatingType(dt) || dt == types.DataTypeNull
+}
+
+// evalBool evaluates a predicate, returning a bool or nil for null.
+func evalBool(expr Expr, record map[string]any) (any, error) {
+	v, err := expr.Eval(record)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a predicate: got %T", expr, v)
+	}
+	return b, nil
+}
+
+// Matches returns true if the predicate is true for the record. Null is not a match.
+func Matches(pred Expr, record map[string]any) (bool, error) {
+	v, err := evalBool(pred, record)
+	if err != nil {
+		return false, err
+	}
+	return v == true, nil
+}
+
+// compareAny compares two non-null values. Integers, floats and decimals of any size are
+// compared as numbers, and strings are parsed when compared to values of other types, so
+// that for example a date can be compared to "2024-01-01".
+// False is returned if the values cannot be compared.
+func compareAny(a, b any) (int, bool) {
+	if c, ok := compareValues(a, b); ok {
+		return c, true
+	}
+	if s, ok := b.(string); ok {
+		if v, ok := parseLiteral(s, a); ok {
+			return compareAny(a, v)
+		}
+		return 0, false
+	}
+	if s, ok := a.(string); ok {
+		if v, ok := parseLiteral(s, b); ok {
+			return compareAny(v, b)
+		}
+		return 0, false
+	}
+
+	x, xDecimal := a.(*big.Rat)
+	y, yDecimal := b.(*big.Rat)
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case xDecimal && isNumber(bv.Kind()):
+		if y = toRat(bv); y != nil {
+			return x.Cmp(y), true
+		}
+	case yDecimal && isNumber(av.Kind()):
+		if x = toRat(av); x != nil {
+			return x.Cmp(y), true
+		}
+	case isNumber(av.Kind()) && isNumber(bv.Kind()):
+		if isInteger(av.Kind()) && isInteger(bv.Kind()) {
+			return compareIntegers(av, bv), true
+		}
+		return compareValues(toFloat64(av), toFloat64(bv))
+	}
+	return 0, false
+}
+
+// parseLiteral parses a string as a value of the same type as like.
+func parseLiteral(s string, like any) (any, bool) {
+	switch like.(type) {
+	case time.Time:
+		return parseTime(s)
+	case []byte:
+		return []byte(s), true
+	case bool:
+		b, err := strconv.ParseBool(s)
+		return b, err == nil
+	case *big.Rat:
+		return new(big.Rat).SetString(s)
+	}
+	kind := reflect.ValueOf(like).Kind()
+	switch {
+	case isInteger(kind):
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+		fallthrough // for example 1.5 compared to an integer column
+	case isNumber(kind):
+		if r, ok := new(big.Rat).SetString(s); ok {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// parseTime parses a date or timestamp literal.
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range partitionTimestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func isInteger(kind reflect.Kind) bool {
+	return isNumber(kind) && kind != reflect.Float32 && kind != reflect.Float64
+}
+
+// compareIntegers compares two integer values of any size and signedness.
+func compareIntegers(a, b reflect.Value) int {
+	aSigned, bSigned := a.CanInt(), b.CanInt()
+	switch {
+	case aSigned && bSigned:
+		return cmpInt(a.Int(), b.Int())
+	case !aSigned && !bSigned:
+		return cmpUint(a.Uint(), b.Uint())
+	case aSigned:
+		if a.Int() < 0 {
+			return -1
+		}
+		return cmpUint(uint64(a.Int()), b.Uint())
+	}
+	if b.Int() < 0 {
+		return 1
+	}
+	return cmpUint(a.Uint(), uint64(b.Int()))
+}
+
+func cmpInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// toRat converts a number to a decimal, or returns nil for NaN and infinities.
+func toRat(v reflect.Value) *big.Rat {
+	switch {
+	case v.CanInt():
+		return new(big.Rat).SetInt64(v.Int())
+	case v.CanUint():
+		return new(big.Rat).SetUint64(v.Uint())
+	}
+	f := v.Float()
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return new(big.Rat).SetFloat64(f)
+}
+
+func toFloat64(v reflect.Value) float64 {
+	switch {
+	case v.CanInt():
+		return float64(v.Int())
+	case v.CanUint():
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
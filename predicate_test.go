@@ -0,0 +1,133 @@
+package deltalake
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/types"
+)
+
+func TestExpr_Eval(t *testing.T) {
+	day := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	record := map[string]any{
+		"id":    int64(3),
+		"name":  "c",
+		"date":  day,
+		"score": 1.5,
+		"none":  nil,
+		"point": map[string]any{"x": int32(1)},
+		"price": big.NewRat(5, 4),
+	}
+	tests := map[string]struct {
+		expr Expr
+		want any
+	}{
+		"eq":                {expr: Eq(Col("id"), Lit(3)), want: true},
+		"ne":                {expr: Ne(Col("name"), Lit("c")), want: false},
+		"lt int and float":  {expr: Lt(Col("id"), Lit(3.5)), want: true},
+		"ge literal first":  {expr: Ge(Lit(2), Col("id")), want: false},
+		"date as string":    {expr: Gt(Col("date"), Lit("2021-01-01")), want: true},
+		"number as string":  {expr: Eq(Col("id"), Lit("3")), want: true},
+		"decimal":           {expr: Gt(Col("price"), Lit(1.2)), want: true},
+		"decimal as string": {expr: Eq(Col("price"), Lit("1.250")), want: true},
+		"nested column":     {expr: Eq(Col("point", "x"), Lit(1)), want: true},
+		"case-insensitive":  {expr: Eq(Col("ID"), Lit(int8(3))), want: true},
+		"compare with null": {expr: Eq(Col("none"), Lit(1)), want: nil},
+		"in":                {expr: In(Col("name"), "a", "c"), want: true},
+		"not in":            {expr: In(Col("name"), "a", "b"), want: false},
+		"in with null":      {expr: In(Col("name"), "a", nil), want: nil},
+		"is null":           {expr: IsNull(Col("none")), want: true},
+		"is not null":       {expr: IsNotNull(Col("missing")), want: false},
+		"and":               {expr: And(Eq(Col("id"), Lit(3)), Lt(Col("score"), Lit(2))), want: true},
+		"and with null":     {expr: And(Eq(Col("id"), Lit(3)), Eq(Col("none"), Lit(1))), want: nil},
+		"and false null":    {expr: And(Eq(Col("id"), Lit(4)), Eq(Col("none"), Lit(1))), want: false},
+		"or true null":      {expr: Or(Eq(Col("none"), Lit(1)), Eq(Col("id"), Lit(3))), want: true},
+		"or with null":      {expr: Or(Eq(Col("none"), Lit(1)), Eq(Col("id"), Lit(4))), want: nil},
+		"not":               {expr: Not(Eq(Col("id"), Lit(3))), want: false},
+		"not null":          {expr: Not(Eq(Col("none"), Lit(3))), want: nil},
+		"add":               {expr: Add(Col("id"), Lit(1)), want: int64(4)},
+		"sub int32":         {expr: Sub(Col("point", "x"), Lit(int32(3))), want: int64(-2)},
+		"mul float":         {expr: Mul(Col("id"), Col("score")), want: 4.5},
+		"div integers":      {expr: Div(Col("id"), Lit(2)), want: 1.5},
+		"div by zero":       {expr: Div(Col("id"), Lit(0)), want: nil},
+		"add decimal":       {expr: Add(Col("price"), Lit(1)), want: big.NewRat(9, 4)},
+		"div decimal":       {expr: Div(Col("price"), Lit(0.5)), want: big.NewRat(5, 2)},
+		"add null":          {expr: Add(Col("none"), Lit(1)), want: nil},
+		"arithmetic in cmp": {expr: Eq(Add(Col("id"), Lit(1)), Lit(4)), want: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := test.expr.Eval(record)
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+
+	_, err := Eq(Col("name"), Lit(1)).Eval(record)
+	require.Error(t, err)
+	_, err = And(Col("id")).Eval(record)
+	require.Error(t, err)
+	_, err = Add(Col("name"), Lit(1)).Eval(record)
+	require.Error(t, err)
+	_, err = Add(Lit(int64(math.MaxInt64)), Lit(1)).Eval(record)
+	require.Error(t, err)
+	_, err = Mul(Lit(int64(math.MinInt64)), Lit(-1)).Eval(record)
+	require.Error(t, err)
+}
+
+func TestExpr_String(t *testing.T) {
+	expr := And(
+		Ge(Col("date"), Lit("2021-01-01")),
+		Or(In(Col("name"), "a", nil), Not(IsNull(Col("point", "x")))),
+	)
+	require.Equal(t, "(date >= '2021-01-01' AND (name IN ('a', NULL) OR NOT (point.x IS NULL)))", expr.String())
+	require.Equal(t, "price < 1.25", Lt(Col("price"), Lit(big.NewRat(5, 4))).String())
+	require.Equal(t, "((count + 1) * 2) > 3", Gt(Mul(Add(Col("count"), Lit(1)), Lit(2)), Lit(3)).String())
+}
+
+func TestExprType(t *testing.T) {
+	schema := types.NewStruct(
+		types.NewStructField("id", types.DataTypeLong, false, nil),
+		types.NewStructField("count", types.DataTypeInteger, true, nil),
+		types.NewStructField("score", types.DataTypeFloat, true, nil),
+		types.NewStructField("price", types.DecimalType(10, 2), true, nil),
+		types.NewStructField("name", types.DataTypeString, true, nil),
+	)
+	decimal := types.DecimalType(types.MaxDecimalPrecision, types.MaxDecimalPrecision/2)
+	tests := map[string]struct {
+		expr    Expr
+		want    types.DataType
+		wantErr bool
+	}{
+		"column":          {expr: Col("count"), want: types.DataTypeInteger},
+		"int literal":     {expr: Lit(1), want: types.DataTypeLong},
+		"int32 literal":   {expr: Lit(int32(1)), want: types.DataTypeInteger},
+		"string literal":  {expr: Lit("a"), want: types.DataTypeString},
+		"null literal":    {expr: Lit(nil), want: types.DataTypeNull},
+		"integers":        {expr: Add(Col("count"), Lit(1)), want: types.DataTypeLong},
+		"float":           {expr: Mul(Col("id"), Col("score")), want: types.DataTypeDouble},
+		"division":        {expr: Div(Col("id"), Lit(2)), want: types.DataTypeDouble},
+		"decimal":         {expr: Sub(Col("price"), Lit(1)), want: decimal},
+		"null operand":    {expr: Add(Col("id"), Lit(nil)), want: types.DataTypeNull},
+		"predicate":       {expr: And(Gt(Col("id"), Lit(1)), IsNull(Col("name"))), want: types.DataTypeBoolean},
+		"string operand":  {expr: Add(Col("name"), Lit(1)), wantErr: true},
+		"missing column":  {expr: Add(Col("missing"), Lit(1)), wantErr: true},
+		"missing in pred": {expr: Eq(Col("missing"), Lit(1)), wantErr: true},
+		"unsupported":     {expr: Lit(struct{}{}), wantErr: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := exprType(schema, test.expr)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package deltalake
+
+import (
+	"github.com/parquet-go/parquet-go"
+
+	"deltalake/actions"
+)
+
+// checkpointActionColumns are the columns that are never null in the rows of each action,
+// used to find out from row group statistics which actions a row group contains.
+var checkpointActionColumns = map[string][]string{
+	actions.AddAction:         {actions.AddAction, "path"},
+	actions.RemoveAction:      {actions.RemoveAction, "path"},
+	actions.MetadataAction:    {actions.MetadataAction, "id"},
+	actions.ProtocolAction:    {actions.ProtocolAction, "minReaderVersion"},
+	actions.TransactionAction: {actions.TransactionAction, "appId"},
+}
+
+// checkpointProjection selects the actions and columns read from a checkpoint.
+type checkpointProjection struct {
+	files      bool // read add actions
+	tombstones bool // read remove actions
+	stats      bool // read the statistics of add actions
+}
+
+// fullProjection reads every action with all its columns.
+var fullProjection = checkpointProjection{files: true, tombstones: true, stats: true}
+
+// projectionFromConfig returns the projection reading what the table config requires.
+// Tombstones are only kept when files are, as for commits.
+func projectionFromConfig(config *TableConfig) checkpointProjection {
+	if config == nil {
+		config = &DefaultTableConfig
+	}
+	return checkpointProjection{
+		files:      config.RequireFiles,
+		tombstones: config.RequireFiles && config.RequireTombstones,
+		stats:      !config.SkipStats,
+	}
+}
+
+// actions returns the names of the actions read.
+func (p checkpointProjection) actions() []string {
+	names := []string{actions.MetadataAction, actions.ProtocolAction, actions.TransactionAction}
+	if p.files {
+		names = append(names, actions.AddAction)
+	}
+	if p.tombstones {
+		names = append(names, actions.RemoveAction)
+	}
+	return names
+}
+
+// schema returns the schema of the checkpoint with only the columns of the projection.
+// Reading rows with this schema does not load the pages of the other columns.
+func (p checkpointProjection) schema(full *parquet.Schema) *parquet.Schema {
+	if p == fullProjection {
+		return full
+	}
+	group := make(parquet.Group)
+	for _, name := range p.actions() {
+		field := fieldNode(full, name)
+		if field == nil {
+			continue
+		}
+		if name == actions.AddAction && !p.stats {
+			add := make(parquet.Group)
+			for _, f := range field.Fields() {
+				if f.Name() != "stats" && f.Name() != "stats_parsed" {
+					add[f.Name()] = f
+				}
+			}
+			field = withRepetitionOf(add, field)
+		}
+		group[name] = field
+	}
+	return parquet.NewSchema(full.Name(), group)
+}
+
+// skipRowGroup returns true if the statistics of the row group show that it has no rows
+// with the actions of the projection.
+func (p checkpointProjection) skipRowGroup(file *parquet.File, i int) bool {
+	rowGroup := file.Metadata().RowGroups[i]
+	for _, name := range p.actions() {
+		leaf, ok := file.Schema().Lookup(checkpointActionColumns[name]...)
+		if !ok {
+			continue
+		}
+		chunk := rowGroup.Columns[leaf.ColumnIndex].MetaData
+		if chunk.Statistics.NullCount < rowGroup.NumRows {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldNode returns the field of the group node with the name, or nil if it has none.
+func fieldNode(node parquet.Node, name string) parquet.Node {
+	for _, field := range node.Fields() {
+		if field.Name() == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// withRepetitionOf returns the node with the same repetition as the other node.
+func withRepetitionOf(node, other parquet.Node) parquet.Node {
+	switch {
+	case other.Optional():
+		return parquet.Optional(node)
+	case other.Repeated():
+		return parquet.Repeated(node)
+	}
+	return parquet.Required(node)
+}
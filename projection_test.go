@@ -0,0 +1,138 @@
+package deltalake
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"deltalake/storage"
+)
+
+func TestTable_LoadCheckpointProjection(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl := writePartitionedTestTable(t, store, 1, []int{1, 1, 1}, removeTestCommit)
+	_, err = tbl.CreateCheckpoint()
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		config         *TableConfig
+		wantFiles      int
+		wantTombstones int
+		wantStats      bool
+	}{
+		"default": {
+			config:         nil,
+			wantFiles:      2,
+			wantTombstones: 1,
+			wantStats:      true,
+		},
+		"metadata only": {
+			config: &TableConfig{},
+		},
+		"without tombstones": {
+			config:    &TableConfig{RequireFiles: true},
+			wantFiles: 2,
+			wantStats: true,
+		},
+		"without stats": {
+			config:         &TableConfig{RequireFiles: true, RequireTombstones: true, SkipStats: true},
+			wantFiles:      2,
+			wantTombstones: 1,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			loaded, err := LoadTable(store, test.config)
+			require.NoError(t, err)
+			require.Equal(t, tbl.State.Version, loaded.State.Version)
+			require.Equal(t, tbl.State.CurrentMetadata, loaded.State.CurrentMetadata)
+			require.Equal(t, tbl.State.MinReaderVersion, loaded.State.MinReaderVersion)
+			require.Equal(t, tbl.State.MinWriterVersion, loaded.State.MinWriterVersion)
+			require.Equal(t, tbl.State.AppTransactionVersion, loaded.State.AppTransactionVersion)
+			require.Len(t, loaded.State.Files, test.wantFiles)
+			require.Len(t, loaded.State.Tombstones, test.wantTombstones)
+			for _, add := range loaded.State.Files {
+				require.NotEmpty(t, add.PartitionValues["date"])
+				require.Equal(t, test.wantStats, add.Stats != nil)
+			}
+		})
+	}
+}
+
+func TestCheckpointProjection_SkipRowGroup(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl := writePartitionedTestTable(t, store, 1, []int{1, 1, 1}, removeTestCommit)
+	_, err = tbl.CreateCheckpoint()
+	require.NoError(t, err)
+
+	obj, err := store.Get(checkpointPath(tbl.State.Version))
+	require.NoError(t, err)
+	data, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	require.NoError(t, obj.Close())
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	// table actions, adds and removes
+	require.Len(t, file.RowGroups(), 3)
+
+	tests := map[string]struct {
+		projection  checkpointProjection
+		wantSkipped []bool
+		wantColumns [][]string
+		wantMissing [][]string
+	}{
+		"full": {
+			projection:  fullProjection,
+			wantSkipped: []bool{false, false, false},
+			wantColumns: [][]string{{"add", "stats"}, {"remove", "path"}},
+		},
+		"metadata only": {
+			projection:  checkpointProjection{},
+			wantSkipped: []bool{false, true, true},
+			wantColumns: [][]string{{"metaData", "id"}, {"protocol", "minReaderVersion"}, {"txn", "appId"}},
+			wantMissing: [][]string{{"add", "path"}, {"remove", "path"}},
+		},
+		"files without stats": {
+			projection:  checkpointProjection{files: true},
+			wantSkipped: []bool{false, false, true},
+			wantColumns: [][]string{{"add", "path"}, {"add", "partitionValues", "key_value", "key"}},
+			wantMissing: [][]string{{"add", "stats"}, {"remove", "path"}},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			for i, want := range test.wantSkipped {
+				require.Equal(t, want, test.projection.skipRowGroup(file, i), "row group %d", i)
+			}
+			schema := test.projection.schema(file.Schema())
+			for _, column := range test.wantColumns {
+				_, ok := schema.Lookup(column...)
+				require.True(t, ok, column)
+			}
+			for _, column := range test.wantMissing {
+				_, ok := schema.Lookup(column...)
+				require.False(t, ok, column)
+			}
+		})
+	}
+}
+
+func TestLoadTable_MetadataOnlyCheckpoint(t *testing.T) {
+	store, err := storage.NewLocalStorage("testdata/simple_table_with_checkpoint")
+	require.NoError(t, err)
+	full, err := LoadTable(store, nil)
+	require.NoError(t, err)
+
+	tbl, err := LoadTable(store, &TableConfig{})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), tbl.State.Version)
+	require.Equal(t, full.State.CurrentMetadata, tbl.State.CurrentMetadata)
+	require.Equal(t, full.State.MinReaderVersion, tbl.State.MinReaderVersion)
+	require.Empty(t, tbl.State.Files)
+	require.Empty(t, tbl.State.Tombstones)
+}
@@ -0,0 +1,459 @@
+package deltalake
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"deltalake/types"
+)
+
+// Records are represented as map[string]any keyed by column name. Values are normalized to a
+// single Go type per delta type so that the writer, the reader and the statistics agree:
+//
+//	long: int64, integer: int32, short: int16, byte: int8, double: float64, float: float32,
+//	string: string, binary: []byte, boolean: bool, date and timestamp: time.Time (UTC),
+//	struct: map[string]any, array: []any, map: map[any]any, null: nil
+
+// recordTag is the struct tag used to map struct fields to column names.
+//
+//	type Event struct {
+//		ID   int64  `delta:"id"`
+//		Note string `delta:"-"` // ignored
+//	}
+const recordTag = "delta"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// normalizeRecord converts a record (a map[string]any or a struct) into a map of column
+// name to value normalized according to the schema. It returns an error if the record
+// has columns that are not in the schema, is missing non-nullable columns, or has
+// values that cannot be represented by the column type.
+func normalizeRecord(schema *types.StructType, record any) (map[string]any, error) {
+	values, err := recordValues(record)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeStruct(schema, values)
+}
+
+// recordValues returns the values of the record keyed by name.
+func recordValues(record any) (map[string]any, error) {
+	if m, ok := record.(map[string]any); ok {
+		return m, nil
+	}
+
+	v := reflect.ValueOf(record)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, fmt.Errorf("record is a nil pointer")
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		values := make(map[string]any)
+		for name, index := range structFields(v.Type()) {
+			values[name] = v.FieldByIndex(index).Interface()
+		}
+		return values, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported record type %s: map keys must be strings", v.Type())
+		}
+		values := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = iter.Value().Interface()
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unsupported record type %T: must be a struct or map[string]any", record)
+}
+
+// structFields returns the exported fields of a struct type keyed by column name.
+// The column name is taken from the `delta` tag, or is the field name otherwise.
+// Fields tagged with `delta:"-"` are ignored.
+func structFields(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup(recordTag); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields[name] = field.Index
+	}
+	return fields
+}
+
+// lookupField returns the field of the schema matching the name, preferring an exact
+// match over a case-insensitive match.
+func lookupField(schema *types.StructType, name string) *types.StructField {
+	var match *types.StructField
+	for _, field := range schema.Fields {
+		if field.Name == name {
+			return field
+		}
+		if match == nil && strings.EqualFold(field.Name, name) {
+			match = field
+		}
+	}
+	return match
+}
+
+// normalizeStruct normalizes the values of a struct according to the schema.
+func normalizeStruct(schema *types.StructType, values map[string]any) (map[string]any, error) {
+	normalized := make(map[string]any, len(schema.Fields))
+	for name, value := range values {
+		field := lookupField(schema, name)
+		if field == nil {
+			return nil, fmt.Errorf("column %s not found in schema", name)
+		}
+		if _, ok := normalized[field.Name]; ok {
+			return nil, fmt.Errorf("duplicate column %s", field.Name)
+		}
+		v, err := normalizeValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		normalized[field.Name] = v
+	}
+
+	for _, field := range schema.Fields {
+		if normalized[field.Name] != nil {
+			continue
+		}
+		if !field.Nullable {
+			return nil, fmt.Errorf("column %s is not nullable", field.Name)
+		}
+		normalized[field.Name] = nil
+	}
+	return normalized, nil
+}
+
+// normalizeValue normalizes a value according to the type of the field.
+func normalizeValue(field *types.StructField, value any) (any, error) {
+	v, err := convertValue(field, value)
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", field.Name, err)
+	}
+	return v, nil
+}
+
+// convertValue converts a value to the Go type used for the type of the field.
+func convertValue(field *types.StructField, value any) (any, error) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	switch field.Type {
+	case "struct":
+		values, err := recordValues(rv.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return normalizeStruct(field.StructType(), values)
+	case "array":
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, fmt.Errorf("cannot use %s as array", rv.Type())
+		}
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		elementType := field.ArrayType()
+		element := types.NewStructField("element", elementType.ElementType, elementType.ContainsNull, nil)
+		array := make([]any, rv.Len())
+		for i := range array {
+			v, err := convertValue(element, rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			if v == nil && !elementType.ContainsNull {
+				return nil, fmt.Errorf("array contains null")
+			}
+			array[i] = v
+		}
+		return array, nil
+	case "map":
+		if rv.Kind() != reflect.Map {
+			return nil, fmt.Errorf("cannot use %s as map", rv.Type())
+		}
+		if rv.IsNil() {
+			return nil, nil
+		}
+		mapType := field.MapType()
+		key := types.NewStructField("key", mapType.KeyType, false, nil)
+		val := types.NewStructField("value", mapType.ValueType, mapType.ValueContainsNull, nil)
+		m := make(map[any]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k, err := convertValue(key, iter.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+			if k == nil {
+				return nil, fmt.Errorf("map contains null key")
+			}
+			v, err := convertValue(val, iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			if v == nil && !mapType.ValueContainsNull {
+				return nil, fmt.Errorf("map contains null value")
+			}
+			m[k] = v
+		}
+		return m, nil
+	}
+	return convertPrimitive(field.Type, rv)
+}
+
+// convertPrimitive converts a value to the Go type used for a primitive type.
+func convertPrimitive(dt types.DataType, rv reflect.Value) (any, error) {
+	switch dt {
+	case types.DataTypeLong:
+		return convertInt(rv, math.MinInt64, math.MaxInt64, func(i int64) any { return i })
+	case types.DataTypeInteger:
+		return convertInt(rv, math.MinInt32, math.MaxInt32, func(i int64) any { return int32(i) })
+	case types.DataTypeShort:
+		return convertInt(rv, math.MinInt16, math.MaxInt16, func(i int64) any { return int16(i) })
+	case types.DataTypeByte:
+		return convertInt(rv, math.MinInt8, math.MaxInt8, func(i int64) any { return int8(i) })
+	case types.DataTypeDouble:
+		switch rv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return rv.Float(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(rv.Int()), nil
+		}
+	case types.DataTypeFloat:
+		switch rv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return float32(rv.Float()), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float32(rv.Int()), nil
+		}
+	case types.DataTypeString:
+		if rv.Kind() == reflect.String {
+			return rv.String(), nil
+		}
+	case types.DataTypeBinary:
+		if rv.Kind() == reflect.String {
+			return []byte(rv.String()), nil
+		}
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+	case types.DataTypeBoolean, types.DataTypeBool:
+		if rv.Kind() == reflect.Bool {
+			return rv.Bool(), nil
+		}
+	case types.DataTypeDate:
+		if rv.Type() == timeType {
+			t := rv.Interface().(time.Time)
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	case types.DataTypeTimestamp:
+		if rv.Type() == timeType {
+			return rv.Interface().(time.Time).UTC().Truncate(time.Microsecond), nil
+		}
+	case types.DataTypeNull:
+		return nil, fmt.Errorf("cannot use %s as null", rv.Type())
+	default:
+		if _, _, ok := dt.Decimal(); ok {
+			return convertDecimal(dt, rv)
+		}
+		return nil, fmt.Errorf("unsupported type %s", dt)
+	}
+	return nil, fmt.Errorf("cannot use %s as %s", rv.Type(), dt)
+}
+
+// convertInt converts an integer value checking that it fits in [min, max].
+func convertInt(rv reflect.Value, min, max int64, convert func(int64) any) (any, error) {
+	var i int64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d out of range", u)
+		}
+		i = int64(u)
+	default:
+		return nil, fmt.Errorf("cannot use %s as integer", rv.Type())
+	}
+	if i < min || i > max {
+		return nil, fmt.Errorf("value %d out of range [%d, %d]", i, min, max)
+	}
+	return convert(i), nil
+}
+
+// assignRecord stores the values of a record in dest, which must be a pointer to a struct
+// or to a map[string]any. Struct fields are matched to columns the same way as when writing;
+// columns without a matching field are ignored.
+func assignRecord(dest any, record map[string]any) error {
+	if m, ok := dest.(*map[string]any); ok {
+		if *m == nil {
+			*m = make(map[string]any, len(record))
+		}
+		for k, v := range record {
+			(*m)[k] = v
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("destination must be a non-nil pointer, got %T", dest)
+	}
+	if rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("destination must be a pointer to a struct or map[string]any, got %T", dest)
+	}
+	return assignStruct(rv.Elem(), record)
+}
+
+func assignStruct(dst reflect.Value, record map[string]any) error {
+	for name, index := range structFields(dst.Type()) {
+		value, ok := record[name]
+		if !ok {
+			for column, v := range record {
+				if strings.EqualFold(column, name) {
+					value, ok = v, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := assignValue(dst.FieldByIndex(index), value); err != nil {
+			return fmt.Errorf("column %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// assignValue stores a normalized value in dst, converting it to the type of dst.
+func assignValue(dst reflect.Value, value any) error {
+	if value == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(dst.Type()):
+		dst.Set(v)
+		return nil
+	case v.Kind() == reflect.Pointer && v.Elem().Type().AssignableTo(dst.Type()):
+		dst.Set(v.Elem())
+		return nil
+	case dst.Kind() == reflect.Pointer:
+		ptr := reflect.New(dst.Type().Elem())
+		if err := assignValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		dst.Set(ptr)
+		return nil
+	}
+
+	switch src := value.(type) {
+	case map[string]any:
+		switch dst.Kind() {
+		case reflect.Struct:
+			return assignStruct(dst, src)
+		case reflect.Map:
+			if dst.Type().Key().Kind() != reflect.String {
+				break
+			}
+			m := reflect.MakeMapWithSize(dst.Type(), len(src))
+			for k, item := range src {
+				elem := reflect.New(dst.Type().Elem()).Elem()
+				if err := assignValue(elem, item); err != nil {
+					return err
+				}
+				m.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+			}
+			dst.Set(m)
+			return nil
+		}
+	case []any:
+		if dst.Kind() != reflect.Slice {
+			break
+		}
+		s := reflect.MakeSlice(dst.Type(), len(src), len(src))
+		for i, item := range src {
+			if err := assignValue(s.Index(i), item); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+		return nil
+	case map[any]any:
+		if dst.Kind() != reflect.Map {
+			break
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), len(src))
+		for k, item := range src {
+			key := reflect.New(dst.Type().Key()).Elem()
+			if err := assignValue(key, k); err != nil {
+				return err
+			}
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := assignValue(elem, item); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		dst.Set(m)
+		return nil
+	default:
+		if isNumber(v.Kind()) && isNumber(dst.Kind()) {
+			converted := v.Convert(dst.Type())
+			if !converted.Convert(v.Type()).Equal(v) {
+				return fmt.Errorf("cannot represent %v as %s", value, dst.Type())
+			}
+			dst.Set(converted)
+			return nil
+		}
+		if v.CanConvert(dst.Type()) && v.Kind() != reflect.Slice && dst.Kind() != reflect.String {
+			dst.Set(v.Convert(dst.Type()))
+			return nil
+		}
+		if v.Kind() == reflect.String && dst.Kind() == reflect.String {
+			dst.SetString(v.String())
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot assign %T to %s", value, dst.Type())
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
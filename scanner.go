@@ -0,0 +1,271 @@
+package deltalake
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+const (
+	// scanBatchSize is the number of rows read from a data file at once.
+	scanBatchSize = 128
+
+	// maxReaderVersion is the highest reader protocol version this library can read, other
+	// than table features without reader features.
+	maxReaderVersion = 1
+	// tableFeaturesReaderVersion is the reader protocol version of tables with table features.
+	tableFeaturesReaderVersion = 3
+)
+
+// Scanner iterates the rows of the data files of a table snapshot.
+//
+//	scanner, err := table.NewScanner()
+//	if err != nil { ... }
+//	defer scanner.Close()
+//	for scanner.Next() {
+//		row := scanner.Row()
+//	}
+//	if err := scanner.Err(); err != nil { ... }
+//
+// Rows are records keyed by column name with values of the Go types of the columns (see
+// normalizeRecord); partition columns are filled in from the partition values of the files.
+type Scanner struct {
+	storage    storage.ObjectStorage
+	metadata   *TableMetadata
+	dataFields []*types.StructField
+	files      []*actions.Add
+	fileValues []map[string]any // values set on the rows of each file, if not nil
+
+	next   int         // index of the next file to open
+	file   *fileReader // the file being read
+	row    map[string]any
+	err    error
+	closed bool
+}
+
+// fileReader reads the rows of a single data file.
+type fileReader struct {
+	add             *actions.Add
+	partitionValues map[string]any
+	values          map[string]any
+	obj             storage.ReaderAt
+	reader          rowReadCloser
+	decoder         *rowDecoder
+	rows            []parquet.Row
+	n, i            int
+	err             error // returned once the rows read with it are consumed
+}
+
+// rowReadCloser reads the rows of a data file, like a *parquet.Reader.
+type rowReadCloser interface {
+	parquet.RowReader
+	io.Closer
+}
+
+// NewScanner returns a Scanner over the rows of the current version of the table.
+func (t *Table) NewScanner() (*Scanner, error) {
+	if t.State.CurrentMetadata == nil {
+		return nil, errors.New("table has no metadata")
+	}
+	return NewScanner(t.Storage, t.State)
+}
+
+// NewScanner returns a Scanner over the rows of the data files of the table state.
+// Files that have been removed are skipped. Tables whose protocol requires reader features
+// this library does not support, like deletion vectors, are refused.
+func NewScanner(storage storage.ObjectStorage, state *TableState) (*Scanner, error) {
+	if state.CurrentMetadata == nil {
+		return nil, errors.New("table state has no metadata")
+	}
+	if err := state.checkReaderVersion(); err != nil {
+		return nil, err
+	}
+	return newScanner(storage, state.CurrentMetadata, state.activeFiles()), nil
+}
+
+// checkReaderVersion returns an error if the protocol of the table requires a reader
+// version or reader features this library does not support.
+func (s *TableState) checkReaderVersion() error {
+	switch {
+	case s.MinReaderVersion <= maxReaderVersion:
+		return nil
+	case s.MinReaderVersion == tableFeaturesReaderVersion && len(s.ReaderFeatures) == 0:
+		return nil
+	case s.MinReaderVersion == tableFeaturesReaderVersion:
+		return fmt.Errorf("unsupported reader features %s", strings.Join(s.ReaderFeatures, ", "))
+	}
+	return fmt.Errorf("unsupported reader version %d, maximum supported is %d", s.MinReaderVersion, maxReaderVersion)
+}
+
+// newScanner returns a Scanner over the rows of the data files of a table with the metadata.
+func newScanner(storage storage.ObjectStorage, metadata *TableMetadata, files []*actions.Add) *Scanner {
+	var dataFields []*types.StructField
+	for _, field := range metadata.Schema.Fields {
+		if !isPartitionColumn(metadata.PartitionColumns, field.Name) {
+			dataFields = append(dataFields, field)
+		}
+	}
+
+	return &Scanner{
+		storage:    storage,
+		metadata:   metadata,
+		dataFields: dataFields,
+		files:      files,
+	}
+}
+
+// Next advances the scanner to the next row. It returns false when there are no
+// more rows or an error occurred.
+func (s *Scanner) Next() bool {
+	if s.err != nil || s.closed {
+		return false
+	}
+	for {
+		if s.file == nil {
+			if s.next >= len(s.files) {
+				s.row = nil
+				return false
+			}
+			s.file, s.err = s.open(s.next)
+			s.next++
+			if s.err != nil {
+				return false
+			}
+		}
+
+		row, err := s.read()
+		if err == io.EOF {
+			s.err = s.file.close()
+			s.file = nil
+			if s.err != nil {
+				return false
+			}
+			continue
+		}
+		if err != nil {
+			s.err = fmt.Errorf("%s: %w", s.file.add.Path, err)
+			return false
+		}
+		s.row = row
+		return true
+	}
+}
+
+// Row returns the current row.
+func (s *Scanner) Row() map[string]any {
+	return s.row
+}
+
+// Scan stores the current row in dest, which must be a pointer to a struct or to a
+// map[string]any. Struct fields are mapped to columns by name or by their `delta` tag.
+func (s *Scanner) Scan(dest any) error {
+	if s.row == nil {
+		return errors.New("no current row")
+	}
+	return assignRecord(dest, s.row)
+}
+
+// Err returns the first error that occurred while scanning.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Close releases the resources of the scanner.
+func (s *Scanner) Close() error {
+	s.closed = true
+	s.row = nil
+	if s.file != nil {
+		err := s.file.close()
+		s.file = nil
+		return err
+	}
+	return nil
+}
+
+// close closes the reader and the object of the file.
+func (f *fileReader) close() error {
+	return errors.Join(f.reader.Close(), f.obj.Close())
+}
+
+// open opens the i-th data file.
+func (s *Scanner) open(i int) (*fileReader, error) {
+	add := s.files[i]
+	path, err := add.PathDecoded()
+	if err != nil {
+		return nil, err
+	}
+	partitionValues, err := s.metadata.PartitionValues(add)
+	if err != nil {
+		return nil, err
+	}
+
+	// the file is read in place or with ranged reads, so that it is not held in memory
+	obj, err := storage.OpenReaderAt(s.storage, path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := parquet.OpenFile(obj, obj.Size(), parquet.SkipPageIndex(true), parquet.SkipBloomFilters(true))
+	if err != nil {
+		obj.Close()
+		return nil, fmt.Errorf("%s: %w", add.Path, err)
+	}
+	log.Debug().Str("path", path).Int64("rows", file.NumRows()).Msg("scanning data file")
+
+	var values map[string]any
+	if s.fileValues != nil {
+		values = s.fileValues[i]
+	}
+	return &fileReader{
+		add:             add,
+		partitionValues: partitionValues,
+		values:          values,
+		obj:             obj,
+		reader:          parquet.NewReader(file),
+		decoder:         newRowDecoder(file.Schema()),
+		rows:            make([]parquet.Row, scanBatchSize),
+	}, nil
+}
+
+// read returns the next row of the current file, or io.EOF at the end of the file.
+func (s *Scanner) read() (map[string]any, error) {
+	f := s.file
+	if f.i >= f.n {
+		if f.err != nil {
+			return nil, f.err
+		}
+		n, err := f.reader.ReadRows(f.rows)
+		if n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, err
+		}
+		// the rows are still returned, and the error after them, so that a truncated or
+		// corrupt file is not taken for its end
+		if err != io.EOF {
+			f.err = err
+		}
+		f.n, f.i = n, 0
+	}
+
+	record, err := f.decoder.decode(s.dataFields, f.rows[f.i])
+	if err != nil {
+		return nil, err
+	}
+	f.i++
+	for column, value := range f.partitionValues {
+		record[column] = value
+	}
+	for column, value := range f.values {
+		record[column] = value
+	}
+	return record, nil
+}
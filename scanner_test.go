@@ -0,0 +1,269 @@
+package deltalake
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+// scanAll returns all the rows of the table.
+func scanAll(t *testing.T, tbl *Table) []map[string]any {
+	t.Helper()
+	scanner, err := tbl.NewScanner()
+	require.NoError(t, err)
+	defer scanner.Close()
+	var rows []map[string]any
+	for scanner.Next() {
+		rows = append(rows, scanner.Row())
+	}
+	require.NoError(t, scanner.Err())
+	return rows
+}
+
+func TestScanner(t *testing.T) {
+	tests := map[string]struct {
+		path    string
+		version int64
+		column  string
+		want    []int64
+	}{
+		"simple_table": {
+			path:    "testdata/simple_table",
+			version: -1,
+			column:  "id",
+			want:    []int64{5, 7, 9},
+		},
+		"simple_table at version 0": {
+			path:    "testdata/simple_table",
+			version: 0,
+			column:  "id",
+			want:    []int64{0, 1, 2, 3, 4},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, err := storage.NewLocalStorage(test.path)
+			require.NoError(t, err)
+			var tbl *Table
+			if test.version < 0 {
+				tbl, err = LoadTable(store, nil)
+			} else {
+				tbl, err = LoadTableAtVersion(store, nil, test.version)
+			}
+			require.NoError(t, err)
+
+			var got []int64
+			for _, row := range scanAll(t, tbl) {
+				got = append(got, row[test.column].(int64))
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			require.Equal(t, test.want, got)
+		})
+	}
+}
+
+// failingRowReader returns an error along with the rows it reads.
+type failingRowReader struct {
+	rowReadCloser
+	err error
+}
+
+func (r *failingRowReader) ReadRows(rows []parquet.Row) (int, error) {
+	n, err := r.rowReadCloser.ReadRows(rows)
+	if n > 0 {
+		return n, r.err
+	}
+	return n, err
+}
+
+func TestScanner_ReadError(t *testing.T) {
+	tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+	scanner, err := tbl.NewScanner()
+	require.NoError(t, err)
+	defer scanner.Close()
+	corrupt := errors.New("corrupt page")
+	scanner.file, err = scanner.open(0)
+	require.NoError(t, err)
+	scanner.file.reader = &failingRowReader{rowReadCloser: scanner.file.reader, err: corrupt}
+	scanner.next = 1
+
+	// the rows read before the error are returned, and the scan stops at the error
+	rows := 0
+	for scanner.Next() {
+		rows++
+	}
+	require.Equal(t, 10, rows)
+	require.ErrorIs(t, scanner.Err(), corrupt)
+}
+
+func TestNewScanner_UnsupportedProtocol(t *testing.T) {
+	store, err := storage.NewLocalStorage("testdata/simple_table")
+	require.NoError(t, err)
+	tbl, err := LoadTable(store, nil)
+	require.NoError(t, err)
+
+	tbl.State.MinReaderVersion, tbl.State.ReaderFeatures = 3, []string{}
+	_, err = tbl.NewScanner()
+	require.NoError(t, err)
+	tbl.State.ReaderFeatures = []string{"deletionVectors"}
+	_, err = tbl.NewScanner()
+	require.Error(t, err)
+	tbl.State.MinReaderVersion, tbl.State.ReaderFeatures = 2, nil
+	_, err = tbl.NewScanner()
+	require.Error(t, err)
+}
+
+func TestScanner_Partitioned(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl, err := CreateTable(store, &TableMetadata{Schema: testSchema(), PartitionColumns: []string{"date"}})
+	require.NoError(t, err)
+
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	w, err := tbl.NewWriter()
+	require.NoError(t, err)
+	require.NoError(t, w.Write(
+		map[string]any{"id": 1, "name": "a", "date": day},
+		map[string]any{"id": 2, "date": day.AddDate(0, 0, 1)},
+		map[string]any{"id": 3, "name": "c"},
+	))
+	adds, err := w.Close()
+	require.NoError(t, err)
+	tx := tbl.NewTransaction()
+	for _, add := range adds {
+		tx.AddAction(add)
+	}
+	_, err = tx.Commit()
+	require.NoError(t, err)
+
+	rows := scanAll(t, tbl)
+	sort.Slice(rows, func(i, j int) bool { return rows[i]["id"].(int64) < rows[j]["id"].(int64) })
+	require.Equal(t, []map[string]any{
+		{"id": int64(1), "name": "a", "date": day},
+		{"id": int64(2), "name": nil, "date": day.AddDate(0, 0, 1)},
+		{"id": int64(3), "name": "c", "date": nil},
+	}, rows)
+
+	// removed files are not scanned
+	var removed *actions.Add
+	for _, add := range adds {
+		if add.PartitionValues["date"] == "2021-01-01" {
+			removed = add
+		}
+	}
+	tx = tbl.NewTransaction(WithOperation("DELETE", nil))
+	tx.AddAction(actions.NewRemove(removed.Path, time.Now().UnixMilli(), true, true, removed.PartitionValues, removed.Size, nil))
+	_, err = tx.Commit()
+	require.NoError(t, err)
+	require.Len(t, scanAll(t, tbl), 2)
+}
+
+func TestScanner_Scan(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	schema := *types.NewStruct(
+		types.NewStructField("id", types.DataTypeLong, false, nil),
+		types.NewStructField("score", types.DataTypeFloat, true, nil),
+		types.NewStructField("tags", types.NewArrayType(types.DataTypeString, true), true, nil),
+		types.NewStructField("attrs", types.NewMapType(types.DataTypeString, types.DataTypeInteger, true), true, nil),
+		types.NewStructField("point", types.NewStruct(
+			types.NewStructField("x", types.DataTypeDouble, false, nil),
+			types.NewStructField("y", types.DataTypeDouble, true, nil),
+		), true, nil),
+		types.NewStructField("ts", types.DataTypeTimestamp, true, nil),
+		types.NewStructField("day", types.DataTypeShort, false, nil),
+	)
+	tbl, err := CreateTable(store, &TableMetadata{Schema: schema, PartitionColumns: []string{"day"}})
+	require.NoError(t, err)
+
+	type point struct {
+		X float64
+		Y *float64
+	}
+	type record struct {
+		ID    int64            `delta:"id"`
+		Score *float32         `delta:"score"`
+		Tags  []string         `delta:"tags"`
+		Attrs map[string]int   `delta:"attrs"`
+		Point *point           `delta:"point"`
+		TS    time.Time        `delta:"ts"`
+		Day   int              `delta:"day"`
+		Extra map[string]int64 `delta:"-"`
+	}
+	y := 2.5
+	score := float32(0.5)
+	ts := time.Date(2021, 1, 1, 12, 0, 0, 1000, time.UTC)
+	want := []record{
+		{ID: 1, Score: &score, Tags: []string{"a", "b"}, Attrs: map[string]int{"x": 1}, Point: &point{X: 1, Y: &y}, TS: ts, Day: 7},
+		{ID: 2, Tags: []string{}, Attrs: map[string]int{}, Point: &point{X: 2}, Day: 7},
+		{ID: 3, Day: 8},
+	}
+
+	w, err := tbl.NewWriter()
+	require.NoError(t, err)
+	for _, r := range want {
+		require.NoError(t, w.Write(r))
+	}
+	adds, err := w.Close()
+	require.NoError(t, err)
+	tx := tbl.NewTransaction()
+	for _, add := range adds {
+		tx.AddAction(add)
+	}
+	_, err = tx.Commit()
+	require.NoError(t, err)
+
+	scanner, err := tbl.NewScanner()
+	require.NoError(t, err)
+	defer scanner.Close()
+	var got []record
+	for scanner.Next() {
+		var r record
+		require.NoError(t, scanner.Scan(&r))
+		got = append(got, r)
+	}
+	require.NoError(t, scanner.Err())
+	sort.Slice(got, func(i, j int) bool { return got[i].ID < got[j].ID })
+	require.Equal(t, want, got)
+}
+
+func TestParsePartitionValue(t *testing.T) {
+	tests := map[string]struct {
+		dt    types.DataType
+		value string
+		want  any
+	}{
+		"null":      {dt: types.DataTypeLong, value: "", want: nil},
+		"string":    {dt: types.DataTypeString, value: "a b", want: "a b"},
+		"integer":   {dt: types.DataTypeInteger, value: "-3", want: int32(-3)},
+		"byte":      {dt: types.DataTypeByte, value: "7", want: int8(7)},
+		"boolean":   {dt: types.DataTypeBoolean, value: "true", want: true},
+		"double":    {dt: types.DataTypeDouble, value: "1.25", want: 1.25},
+		"date":      {dt: types.DataTypeDate, value: "2021-01-02", want: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)},
+		"timestamp": {dt: types.DataTypeTimestamp, value: "2021-01-02 03:04:05.000006", want: time.Date(2021, 1, 2, 3, 4, 5, 6000, time.UTC)},
+		"decimal":   {dt: types.DecimalType(5, 2), value: "-12.30", want: big.NewRat(-123, 10)},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parsePartitionValue(test.dt, test.value)
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+
+	_, err := parsePartitionValue(types.DataTypeByte, "300")
+	require.Error(t, err)
+	_, err = parsePartitionValue(types.DecimalType(5, 2), "1.234")
+	require.Error(t, err)
+	_, err = parsePartitionValue(types.DecimalType(5, 2), "1234")
+	require.Error(t, err)
+}
@@ -0,0 +1,351 @@
+package deltalake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/types"
+)
+
+// FilesMatching returns the data files of the table state that may contain rows matching
+// the predicate. Files are pruned using their partition values and the min, max and null
+// count statistics of their columns; files without statistics are always returned.
+// The returned files may still contain rows that do not match, so rows should be filtered
+// again after reading.
+func (s *TableState) FilesMatching(pred Expr) ([]*actions.Add, error) {
+	if s.CurrentMetadata == nil {
+		return nil, errors.New("table state has no metadata")
+	}
+	files := make([]*actions.Add, 0, len(s.Files))
+	for _, add := range s.activeFiles() {
+		f := &fileSkipping{metadata: s.CurrentMetadata, add: add}
+		ok, err := f.mayMatch(pred)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", add.Path, err)
+		}
+		if ok {
+			files = append(files, add)
+		}
+	}
+	log.Debug().Str("predicate", pred.String()).Int("files", len(files)).Int("skipped", len(s.Files)-len(files)).Msg("data skipping")
+	return files, nil
+}
+
+// activeFiles returns the data files of the table state that have not been removed.
+func (s *TableState) activeFiles() []*actions.Add {
+	files := make([]*actions.Add, 0, len(s.Files))
+	for _, add := range s.Files {
+		if _, ok := s.Tombstones[add.Path]; ok {
+			continue
+		}
+		files = append(files, add)
+	}
+	return files
+}
+
+// columnBounds are the known bounds of the values of a column in a data file.
+type columnBounds struct {
+	min, max   any   // nil if unknown
+	nullCount  int64 // -1 if unknown
+	numRecords int64 // -1 if unknown
+}
+
+// allNull returns true if all the values of the column are known to be null.
+func (b columnBounds) allNull() bool {
+	return b.nullCount >= 0 && b.numRecords >= 0 && b.nullCount == b.numRecords
+}
+
+// fileSkipping evaluates predicates against the statistics of a data file.
+type fileSkipping struct {
+	metadata *TableMetadata
+	add      *actions.Add
+}
+
+// mayMatch returns false if no row of the file can match the predicate.
+func (f *fileSkipping) mayMatch(pred Expr) (bool, error) {
+	switch e := pred.(type) {
+	case *andExpr:
+		for _, expr := range e.exprs {
+			ok, err := f.mayMatch(expr)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case *orExpr:
+		for _, expr := range e.exprs {
+			ok, err := f.mayMatch(expr)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return len(e.exprs) == 0, nil
+	case *notExpr:
+		if negated, ok := negateExpr(e.expr); ok {
+			return f.mayMatch(negated)
+		}
+	case *literalExpr:
+		return e.value == true, nil
+	case *comparisonExpr:
+		return f.mayMatchComparison(e)
+	case *inExpr:
+		for _, v := range e.values {
+			ok, err := f.mayMatch(Eq(e.expr, Lit(v)))
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case *isNullExpr:
+		column, ok := e.expr.(*columnExpr)
+		if !ok {
+			break
+		}
+		b, err := f.bounds(column.path)
+		if err != nil {
+			return false, err
+		}
+		if e.negate {
+			return !b.allNull(), nil
+		}
+		return b.nullCount != 0, nil
+	}
+	return true, nil
+}
+
+// mayMatchComparison checks a comparison of a column with a literal against the bounds of
+// the column. Other comparisons cannot be used to skip files.
+func (f *fileSkipping) mayMatchComparison(e *comparisonExpr) (bool, error) {
+	op := e.op
+	column, ok := e.left.(*columnExpr)
+	lit, isLit := e.right.(*literalExpr)
+	if !ok || !isLit {
+		column, ok = e.right.(*columnExpr)
+		lit, isLit = e.left.(*literalExpr)
+		if !ok || !isLit {
+			return true, nil
+		}
+		op = op.flip()
+	}
+	if lit.value == nil {
+		return false, nil // comparisons with null are never true
+	}
+
+	b, err := f.bounds(column.path)
+	if err != nil {
+		return false, err
+	}
+	if b.allNull() {
+		return false, nil
+	}
+
+	// compare returns the comparison of the bound with the literal, or false if unknown
+	compare := func(bound any) (int, bool, error) {
+		if bound == nil {
+			return 0, false, nil
+		}
+		c, ok := compareAny(bound, lit.value)
+		if !ok {
+			return 0, false, fmt.Errorf("cannot compare column %s of type %T with %T", column, bound, lit.value)
+		}
+		return c, true, nil
+	}
+	minCmp, minOK, err := compare(b.min)
+	if err != nil {
+		return false, err
+	}
+	maxCmp, maxOK, err := compare(b.max)
+	if err != nil {
+		return false, err
+	}
+
+	switch op {
+	case opEq:
+		return (!minOK || minCmp <= 0) && (!maxOK || maxCmp >= 0), nil
+	case opNe:
+		return !minOK || !maxOK || minCmp != 0 || maxCmp != 0, nil
+	case opLt:
+		return !minOK || minCmp < 0, nil
+	case opLe:
+		return !minOK || minCmp <= 0, nil
+	case opGt:
+		return !maxOK || maxCmp > 0, nil
+	}
+	return !maxOK || maxCmp >= 0, nil
+}
+
+// bounds returns the bounds of the column at the path, from the partition values of the
+// file for partition columns and from the file statistics otherwise.
+func (f *fileSkipping) bounds(path []string) (columnBounds, error) {
+	field, err := lookupFieldPath(&f.metadata.Schema, path)
+	if err != nil {
+		return columnBounds{}, err
+	}
+	b := columnBounds{nullCount: -1, numRecords: -1}
+	if n, ok := f.add.NumRecords(); ok {
+		b.numRecords = n
+	}
+
+	if len(path) == 1 && isPartitionColumn(f.metadata.PartitionColumns, field.Name) {
+		v, err := parsePartitionValue(field.Type, f.add.PartitionValues[field.Name])
+		if err != nil {
+			return columnBounds{}, fmt.Errorf("partition column %s: %w", field.Name, err)
+		}
+		if b.numRecords < 0 {
+			b.numRecords = 1 // only the ratio of nulls matters for partition columns
+		}
+		if v == nil {
+			b.nullCount = b.numRecords
+		} else {
+			b.min, b.max, b.nullCount = v, v, 0
+		}
+		return b, nil
+	}
+
+	names := resolvedPath(&f.metadata.Schema, path)
+	cs, ok, err := f.metadata.ColumnStats(f.add, names...)
+	if err != nil || !ok {
+		return b, err
+	}
+	b.min, b.max, b.nullCount = cs.Min, cs.Max, cs.NullCount
+	if t, ok := b.max.(time.Time); ok && field.Type == types.DataTypeTimestamp {
+		// timestamps in stats may be truncated to milliseconds
+		b.max = t.Add(time.Millisecond - time.Nanosecond)
+	}
+	return b, nil
+}
+
+// resolvedPath returns the path with the names of the fields of the schema, which may
+// differ in case from the names of the path.
+func resolvedPath(schema *types.StructType, path []string) []string {
+	names := make([]string, 0, len(path))
+	for _, name := range path {
+		field := lookupField(schema, name)
+		if field == nil {
+			return path
+		}
+		names = append(names, field.Name)
+		schema = field.StructType()
+	}
+	return names
+}
+
+// negateExpr returns the predicate negated without Not, or false if the predicate cannot
+// be negated this way.
+func negateExpr(pred Expr) (Expr, bool) {
+	switch e := pred.(type) {
+	case *notExpr:
+		return e.expr, true
+	case *comparisonExpr:
+		return &comparisonExpr{op: e.op.negate(), left: e.left, right: e.right}, true
+	case *isNullExpr:
+		return &isNullExpr{expr: e.expr, negate: !e.negate}, true
+	case *literalExpr:
+		if b, ok := e.value.(bool); ok {
+			return Lit(!b), true
+		}
+	case *inExpr:
+		exprs := make([]Expr, len(e.values))
+		for i, v := range e.values {
+			exprs[i] = Ne(e.expr, Lit(v))
+		}
+		return And(exprs...), true
+	case *andExpr:
+		exprs := make([]Expr, len(e.exprs))
+		for i, expr := range e.exprs {
+			negated, ok := negateExpr(expr)
+			if !ok {
+				return nil, false
+			}
+			exprs[i] = negated
+		}
+		return Or(exprs...), true
+	case *orExpr:
+		exprs := make([]Expr, len(e.exprs))
+		for i, expr := range e.exprs {
+			negated, ok := negateExpr(expr)
+			if !ok {
+				return nil, false
+			}
+			exprs[i] = negated
+		}
+		return And(exprs...), true
+	}
+	return nil, false
+}
+
+// mustMatch returns true if all the rows of the file are known to match the predicate:
+// the columns of the predicate have no null values, so that the predicate is never null,
+// and no row can match its negation.
+//
+// The statistics of float and double columns are never trusted to prove a match, as
+// writers may leave NaN and infinities out of their min and max values.
+func (f *fileSkipping) mustMatch(pred Expr) (bool, error) {
+	negated, ok := negateExpr(pred)
+	if !ok || hasNullLiteral(pred) {
+		return false, nil
+	}
+	for _, path := range exprColumns(pred) {
+		field, err := lookupFieldPath(&f.metadata.Schema, path)
+		if err != nil {
+			return false, err
+		}
+		partition := len(path) == 1 && isPartitionColumn(f.metadata.PartitionColumns, field.Name)
+		if isFloatingType(field.Type) && !partition {
+			return false, nil
+		}
+		b, err := f.bounds(path)
+		if err != nil {
+			return false, err
+		}
+		if b.nullCount != 0 {
+			return false, nil
+		}
+	}
+	mayMatch, err := f.mayMatch(negated)
+	if err != nil {
+		return false, err
+	}
+	return !mayMatch, nil
+}
+
+// hasNullLiteral returns true if the predicate compares a value with null, which is
+// never true or false.
+func hasNullLiteral(pred Expr) bool {
+	switch e := pred.(type) {
+	case *literalExpr:
+		return e.value == nil
+	case *comparisonExpr:
+		return hasNullLiteral(e.left) || hasNullLiteral(e.right)
+	case *arithmeticExpr:
+		return hasNullLiteral(e.left) || hasNullLiteral(e.right)
+	case *inExpr:
+		for _, v := range e.values {
+			if v == nil {
+				return true
+			}
+		}
+		return hasNullLiteral(e.expr)
+	case *isNullExpr:
+		return hasNullLiteral(e.expr)
+	case *notExpr:
+		return hasNullLiteral(e.expr)
+	case *andExpr:
+		for _, expr := range e.exprs {
+			if hasNullLiteral(expr) {
+				return true
+			}
+		}
+	case *orExpr:
+		for _, expr := range e.exprs {
+			if hasNullLiteral(expr) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package deltalake
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+func TestTableState_FilesMatching(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl, err := CreateTable(store, &TableMetadata{Schema: testSchema(), PartitionColumns: []string{"date"}})
+	require.NoError(t, err)
+
+	// one file per date partition: ids 1-3 on 2021-01-01, 4-6 on 2021-01-02, 7 with a null date
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	w, err := tbl.NewWriter()
+	require.NoError(t, err)
+	require.NoError(t, w.Write(
+		map[string]any{"id": 1, "name": "a", "date": day},
+		map[string]any{"id": 2, "name": "b", "date": day},
+		map[string]any{"id": 3, "date": day},
+		map[string]any{"id": 4, "name": "d", "date": day.AddDate(0, 0, 1)},
+		map[string]any{"id": 6, "name": "f", "date": day.AddDate(0, 0, 1)},
+		map[string]any{"id": 7, "name": "g"},
+	))
+	adds, err := w.Close()
+	require.NoError(t, err)
+	tx := tbl.NewTransaction()
+	tx.AddActions(toActions(adds)...)
+	_, err = tx.Commit()
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		pred Expr
+		want []string // dates of the partitions of the matching files
+	}{
+		"eq":                 {pred: Eq(Col("id"), Lit(5)), want: []string{"2021-01-02"}},
+		"eq out of range":    {pred: Eq(Col("id"), Lit(10)), want: nil},
+		"literal first":      {pred: Gt(Lit(5), Col("id")), want: []string{"2021-01-01", "2021-01-02"}},
+		"lt":                 {pred: Lt(Col("id"), Lit(4)), want: []string{"2021-01-01"}},
+		"le":                 {pred: Le(Col("id"), Lit(4)), want: []string{"2021-01-01", "2021-01-02"}},
+		"gt":                 {pred: Gt(Col("id"), Lit(6)), want: []string{""}},
+		"ge":                 {pred: Ge(Col("id"), Lit(6)), want: []string{"2021-01-02", ""}},
+		"ne":                 {pred: Ne(Col("id"), Lit(7)), want: []string{"2021-01-01", "2021-01-02"}},
+		"string":             {pred: Gt(Col("name"), Lit("e")), want: []string{"2021-01-02", ""}},
+		"in":                 {pred: In(Col("id"), 0, 3, 10), want: []string{"2021-01-01"}},
+		"is null":            {pred: IsNull(Col("name")), want: []string{"2021-01-01"}},
+		"is not null":        {pred: IsNotNull(Col("name")), want: []string{"2021-01-01", "2021-01-02", ""}},
+		"partition":          {pred: Eq(Col("date"), Lit("2021-01-02")), want: []string{"2021-01-02"}},
+		"partition range":    {pred: Lt(Col("date"), Lit(day.AddDate(0, 0, 1))), want: []string{"2021-01-01"}},
+		"partition null":     {pred: IsNull(Col("date")), want: []string{""}},
+		"partition not null": {pred: IsNotNull(Col("date")), want: []string{"2021-01-01", "2021-01-02"}},
+		"and":                {pred: And(Ge(Col("id"), Lit(2)), Eq(Col("date"), Lit("2021-01-01"))), want: []string{"2021-01-01"}},
+		"or":                 {pred: Or(Eq(Col("id"), Lit(1)), Eq(Col("id"), Lit(7))), want: []string{"2021-01-01", ""}},
+		"not":                {pred: Not(Lt(Col("id"), Lit(4))), want: []string{"2021-01-02", ""}},
+		"not and":            {pred: Not(And(Ge(Col("id"), Lit(4)), Le(Col("id"), Lit(6)))), want: []string{"2021-01-01", ""}},
+		"not in":             {pred: Not(In(Col("id"), 7)), want: []string{"2021-01-01", "2021-01-02"}},
+		"null literal":       {pred: Eq(Col("id"), Lit(nil)), want: nil},
+		"two columns":        {pred: Eq(Col("id"), Col("name")), want: []string{"2021-01-01", "2021-01-02", ""}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := tbl.State.FilesMatching(test.pred)
+			require.NoError(t, err)
+			var got []string
+			for _, add := range files {
+				got = append(got, add.PartitionValues["date"])
+			}
+			require.ElementsMatch(t, test.want, got)
+		})
+	}
+
+	_, err = tbl.State.FilesMatching(Eq(Col("missing"), Lit(1)))
+	require.Error(t, err)
+	_, err = tbl.State.FilesMatching(Eq(Col("id"), Lit("a")))
+	require.Error(t, err)
+}
+
+func TestTableState_FilesMatchingWithoutStats(t *testing.T) {
+	store, err := storage.NewLocalStorage("testdata/simple_table")
+	require.NoError(t, err)
+	tbl, err := LoadTable(store, nil)
+	require.NoError(t, err)
+
+	files, err := tbl.State.FilesMatching(Eq(Col("id"), Lit(100)))
+	require.NoError(t, err)
+	require.Len(t, files, len(tbl.State.Files))
+}
+
+func TestTableState_FilesMatchingNonFinite(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	schema := *types.NewStruct(types.NewStructField("x", types.DataTypeDouble, true, nil))
+	tbl, err := CreateTable(store, &TableMetadata{Schema: schema})
+	require.NoError(t, err)
+
+	// one file of finite values, one with +Inf and one with NaN
+	for _, xs := range [][]float64{{1, 2}, {1, math.Inf(1)}, {-1, math.NaN()}} {
+		w, err := tbl.NewWriter()
+		require.NoError(t, err)
+		for _, x := range xs {
+			require.NoError(t, w.Write(map[string]any{"x": x}))
+		}
+		adds, err := w.Close()
+		require.NoError(t, err)
+		tx := tbl.NewTransaction()
+		tx.AddActions(toActions(adds)...)
+		_, err = tx.Commit()
+		require.NoError(t, err)
+	}
+	// the stats survive the round trip through the log
+	tbl, err = LoadTable(store, nil)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		pred Expr
+		want int
+	}{
+		"gt":       {pred: Gt(Col("x"), Lit(100.0)), want: 2},
+		"lt":       {pred: Lt(Col("x"), Lit(0.0)), want: 1},
+		"infinity": {pred: Eq(Col("x"), Lit(math.Inf(1))), want: 2},
+		"le":       {pred: Le(Col("x"), Lit(2.0)), want: 3},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := tbl.State.FilesMatching(test.pred)
+			require.NoError(t, err)
+			require.Len(t, files, test.want)
+		})
+	}
+}
+
+func TestFileSkipping_MustMatch(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl, err := CreateTable(store, &TableMetadata{Schema: testSchema(), PartitionColumns: []string{"date"}})
+	require.NoError(t, err)
+
+	// ids 1-3 with a null name on 2021-01-01
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	w, err := tbl.NewWriter()
+	require.NoError(t, err)
+	require.NoError(t, w.Write(
+		map[string]any{"id": 1, "name": "a", "date": day},
+		map[string]any{"id": 2, "name": "b", "date": day},
+		map[string]any{"id": 3, "date": day},
+	))
+	adds, err := w.Close()
+	require.NoError(t, err)
+	tx := tbl.NewTransaction()
+	tx.AddActions(toActions(adds)...)
+	_, err = tx.Commit()
+	require.NoError(t, err)
+	files := tbl.State.activeFiles()
+	require.Len(t, files, 1)
+
+	tests := map[string]struct {
+		pred Expr
+		want bool
+	}{
+		"all in range":      {pred: Le(Col("id"), Lit(3)), want: true},
+		"some in range":     {pred: Lt(Col("id"), Lit(3)), want: false},
+		"partition":         {pred: Eq(Col("date"), Lit("2021-01-01")), want: true},
+		"other partition":   {pred: Eq(Col("date"), Lit("2021-01-02")), want: false},
+		"and":               {pred: And(Ge(Col("id"), Lit(1)), Eq(Col("date"), Lit("2021-01-01"))), want: true},
+		"or":                {pred: Or(Le(Col("id"), Lit(3)), Gt(Col("id"), Lit(5))), want: true},
+		"or of ranges":      {pred: Or(Lt(Col("id"), Lit(2)), Ge(Col("id"), Lit(2))), want: false},
+		"in":                {pred: In(Col("id"), 1, 2), want: false},
+		"nulls":             {pred: Ge(Col("name"), Lit("a")), want: false},
+		"null literal":      {pred: Not(Eq(Col("id"), Lit(nil))), want: false},
+		"two columns":       {pred: Eq(Col("id"), Col("id")), want: false},
+		"true":              {pred: Lit(true), want: true},
+		"not":               {pred: Not(Gt(Col("id"), Lit(3))), want: true},
+		"is not null":       {pred: IsNotNull(Col("id")), want: true},
+		"is null with null": {pred: IsNull(Col("name")), want: false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := &fileSkipping{metadata: tbl.State.CurrentMetadata, add: files[0]}
+			got, err := f.mustMatch(test.pred)
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package deltalake
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"deltalake/actions"
+	"deltalake/types"
+)
+
+// statsTimestampLayout is the layout of timestamps in the JSON-encoded stats.
+const statsTimestampLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// ColumnStats are the statistics of a column in a data file.
+type ColumnStats struct {
+	// Min and Max are the minimum and maximum values of the column, with the Go type of
+	// the column (see normalizeRecord), or nil if they are unknown.
+	Min, Max any
+	// NullCount is the number of null values of the column, or -1 if it is unknown.
+	NullCount int64
+}
+
+// ColumnStats returns the statistics of the column at the path in the data file of the add
+// action, for example ColumnStats(add, "a", "b") for the field b of the struct column a.
+// False is returned if the add action has no statistics.
+func (m *TableMetadata) ColumnStats(add *actions.Add, path ...string) (ColumnStats, bool, error) {
+	if add.Stats == nil {
+		return ColumnStats{}, false, nil
+	}
+	field, err := lookupFieldPath(&m.Schema, path)
+	if err != nil {
+		return ColumnStats{}, false, err
+	}
+
+	cs := ColumnStats{NullCount: -1}
+	if v, ok := add.Stats.Min(path...); ok {
+		if cs.Min, err = statsValue(field.Type, v); err != nil {
+			return ColumnStats{}, false, fmt.Errorf("min value of %s: %w", strings.Join(path, "."), err)
+		}
+	}
+	if v, ok := add.Stats.Max(path...); ok {
+		if cs.Max, err = statsValue(field.Type, v); err != nil {
+			return ColumnStats{}, false, fmt.Errorf("max value of %s: %w", strings.Join(path, "."), err)
+		}
+	}
+	if n, ok := add.Stats.NullCountOf(path...); ok {
+		cs.NullCount = n
+	}
+	return cs, true, nil
+}
+
+// lookupFieldPath returns the field at the path of nested struct fields.
+func lookupFieldPath(schema *types.StructType, path []string) (*types.StructField, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty column path")
+	}
+	field := lookupField(schema, path[0])
+	if field == nil {
+		return nil, fmt.Errorf("column %s not found in schema", path[0])
+	}
+	if len(path) == 1 {
+		return field, nil
+	}
+	if field.StructType() == nil {
+		return nil, fmt.Errorf("column %s is not a struct", path[0])
+	}
+	return lookupFieldPath(field.StructType(), path[1:])
+}
+
+// statsValue converts a value of the JSON-encoded stats to the Go type of the delta type.
+func statsValue(dt types.DataType, v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch dt {
+	case types.DataTypeLong, types.DataTypeInteger, types.DataTypeShort, types.DataTypeByte:
+		var i int64
+		switch n := v.(type) {
+		case json.Number:
+			var err error
+			if i, err = n.Int64(); err != nil {
+				return nil, err
+			}
+		case float64:
+			i = int64(n)
+		default:
+			return nil, fmt.Errorf("cannot use %T as %s", v, dt)
+		}
+		return convertPrimitive(dt, reflect.ValueOf(i))
+	case types.DataTypeDouble, types.DataTypeFloat:
+		var f float64
+		switch n := v.(type) {
+		case json.Number:
+			var err error
+			if f, err = n.Float64(); err != nil {
+				return nil, err
+			}
+		case float64:
+			f = n
+		case string: // NaN and infinities are strings in JSON
+			switch n {
+			case "NaN":
+				f = math.NaN()
+			case "Infinity":
+				f = math.Inf(1)
+			case "-Infinity":
+				f = math.Inf(-1)
+			default:
+				return nil, fmt.Errorf("invalid number %q", n)
+			}
+		default:
+			return nil, fmt.Errorf("cannot use %T as %s", v, dt)
+		}
+		if dt == types.DataTypeFloat {
+			return float32(f), nil
+		}
+		return f, nil
+	case types.DataTypeDate:
+		if s, ok := v.(string); ok {
+			return time.Parse("2006-01-02", s)
+		}
+	case types.DataTypeTimestamp:
+		if s, ok := v.(string); ok {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, err
+			}
+			return t.UTC(), nil
+		}
+	case types.DataTypeString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case types.DataTypeBinary:
+		if s, ok := v.(string); ok {
+			return []byte(s), nil
+		}
+	case types.DataTypeBoolean, types.DataTypeBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	default:
+		if _, _, ok := dt.Decimal(); !ok {
+			return nil, fmt.Errorf("unsupported type %s", dt)
+		}
+		var s string
+		switch n := v.(type) {
+		case json.Number:
+			s = n.String()
+		case float64:
+			s = strconv.FormatFloat(n, 'f', -1, 64)
+		case string:
+			s = n
+		default:
+			return nil, fmt.Errorf("cannot use %T as %s", v, dt)
+		}
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("invalid decimal %q", s)
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("cannot use %T as %s", v, dt)
+}
+
+// jsonStatsValue converts a value with the Go type of the delta type to its representation
+// in the JSON-encoded stats. NaN and infinities, which JSON numbers cannot encode, are
+// written as strings like Spark.
+func jsonStatsValue(dt types.DataType, v any) any {
+	switch x := v.(type) {
+	case time.Time:
+		if dt == types.DataTypeDate {
+			return x.Format("2006-01-02")
+		}
+		return x.UTC().Format(statsTimestampLayout)
+	case float32:
+		return jsonFloat(float64(x), v)
+	case float64:
+		return jsonFloat(x, v)
+	case *big.Rat: // decimals are numbers with the digits of their scale, like Spark
+		_, scale, _ := dt.Decimal()
+		return json.Number(x.FloatString(scale))
+	}
+	return v
+}
+
+// jsonFloat returns the string of a NaN or infinite float, or v otherwise.
+func jsonFloat(f float64, v any) any {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return v
+}
+
+// compareValues compares two values with the same Go type of a primitive delta type.
+// False is returned if the values cannot be compared.
+func compareValues(a, b any) (int, bool) {
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			return cmp.Compare(x, y), true
+		}
+	case int32:
+		if y, ok := b.(int32); ok {
+			return cmp.Compare(x, y), true
+		}
+	case int16:
+		if y, ok := b.(int16); ok {
+			return cmp.Compare(x, y), true
+		}
+	case int8:
+		if y, ok := b.(int8); ok {
+			return cmp.Compare(x, y), true
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			return compareFloats(x, y), true
+		}
+	case float32:
+		if y, ok := b.(float32); ok {
+			return compareFloats(float64(x), float64(y)), true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case []byte:
+		if y, ok := b.([]byte); ok {
+			return bytes.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case !x:
+				return -1, true
+			}
+			return 1, true
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), true
+		}
+	case *big.Rat:
+		if y, ok := b.(*big.Rat); ok {
+			return x.Cmp(y), true
+		}
+	}
+	return 0, false
+}
+
+// compareFloats compares two floats like Spark: NaN is equal to NaN and greater than any
+// other value, including positive infinity.
+func compareFloats(x, y float64) int {
+	switch xNaN, yNaN := math.IsNaN(x), math.IsNaN(y); {
+	case xNaN && yNaN:
+		return 0
+	case xNaN:
+		return 1
+	case yNaN:
+		return -1
+	}
+	return cmp.Compare(x, y)
+}
+
+// statsCollector computes the stats of the records written to a data file.
+type statsCollector struct {
+	fields     []*types.StructField
+	numRecords int64
+	min, max   map[string]any
+	nullCount  map[string]any
+}
+
+func newStatsCollector(fields []*types.StructField) *statsCollector {
+	return &statsCollector{
+		fields:    fields,
+		min:       make(map[string]any),
+		max:       make(map[string]any),
+		nullCount: make(map[string]any),
+	}
+}
+
+// add updates the stats with a normalized record.
+func (c *statsCollector) add(record map[string]any) {
+	c.numRecords++
+	collectStats(c.fields, record, c.min, c.max, c.nullCount)
+}
+
+func collectStats(fields []*types.StructField, record map[string]any, min, max, nullCount map[string]any) {
+	for _, field := range fields {
+		v := record[field.Name]
+		if field.Type == "struct" {
+			nested, _ := v.(map[string]any)
+			collectStats(
+				field.StructType().Fields,
+				nested,
+				nestedStats(min, field.Name),
+				nestedStats(max, field.Name),
+				nestedStats(nullCount, field.Name),
+			)
+			continue
+		}
+
+		count, _ := nullCount[field.Name].(int64)
+		if v == nil {
+			count++
+		}
+		nullCount[field.Name] = count
+
+		// min and max are only kept for primitive types that can be compared
+		if v == nil || !types.IsPrimitiveType(field.Type) || field.Type == types.DataTypeBinary {
+			continue
+		}
+		if current, ok := min[field.Name]; !ok {
+			min[field.Name] = v
+		} else if c, ok := compareValues(v, current); ok && c < 0 {
+			min[field.Name] = v
+		}
+		if current, ok := max[field.Name]; !ok {
+			max[field.Name] = v
+		} else if c, ok := compareValues(v, current); ok && c > 0 {
+			max[field.Name] = v
+		}
+	}
+}
+
+func nestedStats(stats map[string]any, name string) map[string]any {
+	nested, ok := stats[name].(map[string]any)
+	if !ok {
+		nested = make(map[string]any)
+		stats[name] = nested
+	}
+	return nested
+}
+
+// stats returns the collected stats in their JSON representation.
+func (c *statsCollector) stats() *actions.Stats {
+	return &actions.Stats{
+		NumRecords: c.numRecords,
+		MinValues:  jsonStats(c.fields, c.min),
+		MaxValues:  jsonStats(c.fields, c.max),
+		NullCount:  c.nullCount,
+	}
+}
+
+func jsonStats(fields []*types.StructField, values map[string]any) map[string]any {
+	out := make(map[string]any, len(values))
+	for _, field := range fields {
+		v, ok := values[field.Name]
+		if !ok {
+			continue
+		}
+		if nested, ok := v.(map[string]any); ok {
+			if n := jsonStats(field.StructType().Fields, nested); len(n) > 0 {
+				out[field.Name] = n
+			}
+			continue
+		}
+		out[field.Name] = jsonStatsValue(field.Type, v)
+	}
+	return out
+}
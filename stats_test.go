@@ -0,0 +1,111 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+func TestWriter_Stats(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	schema := *types.NewStruct(
+		types.NewStructField("id", types.DataTypeLong, false, nil),
+		types.NewStructField("name", types.DataTypeString, true, nil),
+		types.NewStructField("score", types.DataTypeDouble, true, nil),
+		types.NewStructField("ts", types.DataTypeTimestamp, true, nil),
+		types.NewStructField("point", types.NewStruct(
+			types.NewStructField("x", types.DataTypeInteger, true, nil),
+		), true, nil),
+		types.NewStructField("date", types.DataTypeDate, true, nil),
+	)
+	metadata := &TableMetadata{Schema: schema, PartitionColumns: []string{"date"}}
+	tbl, err := CreateTable(store, metadata)
+	require.NoError(t, err)
+
+	ts := time.Date(2021, 1, 1, 0, 0, 0, 1000, time.UTC)
+	w, err := tbl.NewWriter()
+	require.NoError(t, err)
+	require.NoError(t, w.Write(
+		map[string]any{"id": 3, "name": "b", "score": math.NaN(), "ts": ts, "point": map[string]any{"x": 5}},
+		map[string]any{"id": 1, "name": "c", "score": 1.5},
+		map[string]any{"id": 2, "point": map[string]any{"x": -1}},
+	))
+	adds, err := w.Close()
+	require.NoError(t, err)
+	require.Len(t, adds, 1)
+
+	n, ok := adds[0].NumRecords()
+	require.True(t, ok)
+	require.Equal(t, int64(3), n)
+
+	// stats survive the round trip through the log
+	tx := tbl.NewTransaction()
+	tx.AddAction(adds[0])
+	_, err = tx.Commit()
+	require.NoError(t, err)
+	loaded, err := LoadTable(store, nil)
+	require.NoError(t, err)
+	add := loaded.State.Files[0]
+
+	tests := map[string]struct {
+		path []string
+		want ColumnStats
+	}{
+		"long":      {path: []string{"id"}, want: ColumnStats{Min: int64(1), Max: int64(3), NullCount: 0}},
+		"string":    {path: []string{"name"}, want: ColumnStats{Min: "b", Max: "c", NullCount: 1}},
+		"timestamp": {path: []string{"ts"}, want: ColumnStats{Min: ts, Max: ts, NullCount: 2}},
+		"nested":    {path: []string{"point", "x"}, want: ColumnStats{Min: int32(-1), Max: int32(5), NullCount: 1}},
+		"partition": {path: []string{"date"}, want: ColumnStats{NullCount: -1}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok, err := loaded.State.CurrentMetadata.ColumnStats(add, test.path...)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, test.want, got)
+		})
+	}
+
+	// NaN is greater than any other value, like in Spark
+	score, ok, err := loaded.State.CurrentMetadata.ColumnStats(add, "score")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 1.5, score.Min)
+	require.True(t, math.IsNaN(score.Max.(float64)))
+
+	_, _, err = loaded.State.CurrentMetadata.ColumnStats(add, "missing")
+	require.Error(t, err)
+}
+
+func TestStatsValue(t *testing.T) {
+	tests := map[string]struct {
+		dt    types.DataType
+		value any
+		want  any
+	}{
+		"long":      {dt: types.DataTypeLong, value: json.Number("9007199254740993"), want: int64(9007199254740993)},
+		"short":     {dt: types.DataTypeShort, value: json.Number("-2"), want: int16(-2)},
+		"float":     {dt: types.DataTypeFloat, value: json.Number("0.5"), want: float32(0.5)},
+		"date":      {dt: types.DataTypeDate, value: "2021-01-02", want: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)},
+		"timestamp": {dt: types.DataTypeTimestamp, value: "2021-01-02T03:04:05.123Z", want: time.Date(2021, 1, 2, 3, 4, 5, 123000000, time.UTC)},
+		"boolean":   {dt: types.DataTypeBoolean, value: true, want: true},
+		"infinity":  {dt: types.DataTypeDouble, value: "-Infinity", want: math.Inf(-1)},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := statsValue(test.dt, test.value)
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+
+	_, err := statsValue(types.DataTypeLong, "a")
+	require.Error(t, err)
+}
@@ -35,8 +35,21 @@ func (l *LocalStorage) fullpath(path string) string {
 	return filepath.Join(l.rootDir, path)
 }
 
+// relpath returns the path relative to the root directory using forward slashes,
+// matching the paths accepted by the other methods.
+func (l *LocalStorage) relpath(path string) string {
+	rel, err := filepath.Rel(l.rootDir, path)
+	if err != nil {
+		return filepath.ToSlash(strings.TrimPrefix(path, l.rootDir))
+	}
+	return filepath.ToSlash(rel)
+}
+
 func (l *LocalStorage) Put(path string, data io.Reader) error {
 	path = l.fullpath(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
 
 	// Atomically create the file.
 	f, err := os.Create(path + ".tmp")
@@ -64,6 +77,40 @@ func (l *LocalStorage) Put(path string, data io.Reader) error {
 	return nil
 }
 
+func (l *LocalStorage) PutIfAbsent(path string, data io.Reader) error {
+	path = l.fullpath(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write the data to a uniquely named temporary file so concurrent writers don't clobber each other.
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := io.Copy(f, data); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Linking fails if the destination already exists, unlike renaming which replaces it.
+	if err := os.Link(f.Name(), path); err != nil {
+		if os.IsExist(err) {
+			log.Debug().Str("path", path).Msg("file already exists")
+			return ErrExists
+		}
+		return err
+	}
+
+	log.Debug().Str("path", path).Msg("put file if absent")
+	return nil
+}
+
 // exists returns true if the path exists.
 // it expects the path to be absolute from l.fullpath()
 func (l *LocalStorage) exists(path string) bool {
@@ -102,7 +149,7 @@ func (l *LocalStorage) Head(path string) (ObjectInfo, error) {
 
 	log.Debug().Str("path", path).Msg("head file")
 	return ObjectInfo{
-		Path:         strings.TrimPrefix(path, l.rootDir),
+		Path:         l.relpath(path),
 		Size:         info.Size(),
 		LastModified: info.ModTime(),
 	}, nil
@@ -121,9 +168,15 @@ func (l *LocalStorage) List(prefix string) ([]ObjectInfo, error) {
 
 	// Find all files with the prefix.
 	err := filepath.Walk(l.fullpath(prefix), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) { // a missing prefix is an empty listing, not an error
+				return nil
+			}
+			return err
+		}
 		if !info.IsDir() {
 			infos = append(infos, ObjectInfo{
-				Path:         strings.TrimPrefix(path, l.rootDir),
+				Path:         l.relpath(path),
 				Size:         info.Size(),
 				LastModified: info.ModTime(),
 			})
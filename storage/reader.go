@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// RangeGetter is implemented by storages that can read a range of bytes of an object
+// without downloading all of it.
+type RangeGetter interface {
+	// GetRange returns a reader for length bytes of the object at the path, starting at
+	// the offset.
+	GetRange(path string, offset, length int64) (io.ReadCloser, error)
+}
+
+// ReaderAt is an io.ReaderAt over an object.
+type ReaderAt interface {
+	io.ReaderAt
+	io.Closer
+	// Size returns the size of the object in bytes.
+	Size() int64
+}
+
+// OpenReaderAt returns a ReaderAt over the object at the path, so that formats like parquet
+// can read parts of the object without holding all of it in memory. Objects are read in place
+// when the storage returns seekable files, with ranged reads when it implements RangeGetter,
+// and are otherwise downloaded into memory.
+func OpenReaderAt(storage ObjectStorage, path string) (ReaderAt, error) {
+	info, err := storage.Head(path)
+	if err != nil {
+		return nil, err
+	}
+	if getter, ok := storage.(RangeGetter); ok {
+		return &rangeReaderAt{getter: getter, path: path, size: info.Size}, nil
+	}
+
+	obj, err := storage.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := obj.(io.ReaderAt); ok {
+		return &objectReaderAt{ReaderAt: r, closer: obj, size: info.Size}, nil
+	}
+	defer obj.Close()
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &objectReaderAt{ReaderAt: bytes.NewReader(data), size: int64(len(data))}, nil
+}
+
+type objectReaderAt struct {
+	io.ReaderAt
+	closer io.Closer // nil if the object was downloaded
+	size   int64
+}
+
+func (r *objectReaderAt) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+func (r *objectReaderAt) Size() int64 {
+	return r.size
+}
+
+// rangeReaderAt reads an object with one ranged request per read.
+type rangeReaderAt struct {
+	getter RangeGetter
+	path   string
+	size   int64
+}
+
+func (r *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	length := int64(len(p))
+	if off+length > r.size {
+		length = r.size - off
+	}
+	body, err := r.getter.GetRange(r.path, off, length)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	n, err := io.ReadFull(body, p[:length])
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (r *rangeReaderAt) Close() error {
+	return nil
+}
+
+func (r *rangeReaderAt) Size() int64 {
+	return r.size
+}
@@ -2,14 +2,19 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	unixpath "path"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 )
 
 // s3EndpointResolver returns an endpoint resolver that always returns the given endpoint URL.
@@ -107,6 +112,22 @@ func (s *S3Storage) fullpath(path string) string {
 	return unixpath.Join(s.prefix, path) // only use unix-style paths with forward slashes
 }
 
+// relpath strips the storage prefix from an object key so that listed paths
+// can be passed back to the other methods.
+func (s *S3Storage) relpath(key string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(key, s.prefix), "/")
+}
+
+// translateError maps S3 not found errors to ErrNotFound.
+func translateError(err error) error {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	if errors.As(err, &noSuchKey) || errors.As(err, &notFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
 func (s *S3Storage) Put(path string, data io.Reader) error {
 	_, err := s.client.PutObject(context.Background(), &s3.PutObjectInput{
 		Bucket: aws.String(s.bucket),
@@ -120,25 +141,57 @@ func (s *S3Storage) Put(path string, data io.Reader) error {
 	return nil
 }
 
+// PutIfAbsent uses a conditional write (If-None-Match: *) so that S3 rejects the
+// request when an object already exists at the path.
+func (s *S3Storage) PutIfAbsent(path string, data io.Reader) error {
+	_, err := s.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.fullpath(path)),
+		Body:   data,
+	}, s3.WithAPIOptions(smithyhttp.AddHeaderValue("If-None-Match", "*")))
+	if err != nil {
+		var respErr interface{ HTTPStatusCode() int }
+		if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusPreconditionFailed {
+			return ErrExists
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (s *S3Storage) Get(path string) (io.ReadCloser, error) {
 	resp, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(s.fullpath(path)),
 	})
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	return resp.Body, nil
 }
 
+// GetRange reads a range of the object with a Range request.
+func (s *S3Storage) GetRange(path string, offset, length int64) (io.ReadCloser, error) {
+	resp, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.fullpath(path)),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return resp.Body, nil
+}
+
 func (s *S3Storage) Head(path string) (ObjectInfo, error) {
 	head, err := s.client.HeadObject(context.Background(), &s3.HeadObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(s.fullpath(path)),
 	})
 	if err != nil {
-		return ObjectInfo{}, err
+		return ObjectInfo{}, translateError(err)
 	}
 	return ObjectInfo{
 		Path:         path,
@@ -161,20 +214,23 @@ func (s *S3Storage) Delete(path string) error {
 
 func (s *S3Storage) List(prefix string) ([]ObjectInfo, error) {
 	ls := make([]ObjectInfo, 0)
-	resp, err := s.client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(s.bucket),
 		Prefix: aws.String(s.fullpath(prefix)),
 	})
-	if err != nil {
-		return nil, err
-	}
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
 
-	for _, obj := range resp.Contents {
-		ls = append(ls, ObjectInfo{
-			Path:         *obj.Key,
-			Size:         obj.Size,
-			LastModified: *obj.LastModified,
-		})
+		for _, obj := range resp.Contents {
+			ls = append(ls, ObjectInfo{
+				Path:         s.relpath(*obj.Key),
+				Size:         obj.Size,
+				LastModified: *obj.LastModified,
+			})
+		}
 	}
 	return ls, nil
 }
@@ -9,6 +9,7 @@ import (
 
 var (
 	ErrNotFound = fmt.Errorf("not found")
+	ErrExists   = fmt.Errorf("already exists")
 )
 
 type ObjectInfo struct {
@@ -25,6 +26,9 @@ func (o ObjectInfo) IsZero() bool {
 type ObjectStorage interface {
 	// Put writes the data to the given path.
 	Put(path string, data io.Reader) error
+	// PutIfAbsent atomically writes the data to the given path if nothing exists there yet.
+	// ErrExists is returned if the path already exists.
+	PutIfAbsent(path string, data io.Reader) error
 	// Get returns a reader for the given path.
 	Get(path string) (io.ReadCloser, error)
 	// Head returns the object info for the given path.
@@ -32,6 +36,7 @@ type ObjectStorage interface {
 	// Delete deletes the object at the given path.
 	Delete(path string) error
 	// List returns a list of objects with the given prefix.
+	// The returned paths are relative to the root of the storage.
 	List(prefix string) ([]ObjectInfo, error)
 	// RootURI returns the root URI of the storage.
 	RootURI() string
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -28,3 +30,99 @@ func TestWhichStorageProvider(t *testing.T) {
 		})
 	}
 }
+
+func TestLocalStorage_PutIfAbsent(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, store.PutIfAbsent("dir/file.json", strings.NewReader("first")))
+	err = store.PutIfAbsent("dir/file.json", strings.NewReader("second"))
+	require.ErrorIs(t, err, ErrExists)
+
+	obj, err := store.Get("dir/file.json")
+	require.NoError(t, err)
+	defer obj.Close()
+	data, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	require.Equal(t, "first", string(data))
+
+	infos, err := store.List("dir")
+	require.NoError(t, err)
+	require.Len(t, infos, 1, "temporary files should be cleaned up")
+	require.Equal(t, "dir/file.json", infos[0].Path)
+}
+
+// rangeStorage serves objects from memory and counts ranged reads.
+type rangeStorage struct {
+	*LocalStorage
+	ranges int
+}
+
+func (s *rangeStorage) Get(path string) (io.ReadCloser, error) {
+	obj, err := s.LocalStorage.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(obj), nil // hide io.ReaderAt
+}
+
+func (s *rangeStorage) GetRange(path string, offset, length int64) (io.ReadCloser, error) {
+	s.ranges++
+	obj, err := s.LocalStorage.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(obj)
+	obj.Close()
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader(string(data[offset : offset+length]))), nil
+}
+
+// getStorage only supports reading whole objects.
+type getStorage struct {
+	*LocalStorage
+}
+
+func (s *getStorage) Get(path string) (io.ReadCloser, error) {
+	obj, err := s.LocalStorage.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(obj), nil
+}
+
+func TestOpenReaderAt(t *testing.T) {
+	local, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, local.Put("file", strings.NewReader("0123456789")))
+	ranges := &rangeStorage{LocalStorage: local}
+
+	tests := map[string]ObjectStorage{
+		"local":  local,
+		"ranged": ranges,
+		"get":    &getStorage{LocalStorage: local},
+	}
+	for name, store := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := OpenReaderAt(store, "file")
+			require.NoError(t, err)
+			defer r.Close()
+			require.Equal(t, int64(10), r.Size())
+
+			p := make([]byte, 4)
+			n, err := r.ReadAt(p, 3)
+			require.NoError(t, err)
+			require.Equal(t, "3456", string(p[:n]))
+
+			n, err = r.ReadAt(p, 8)
+			require.ErrorIs(t, err, io.EOF)
+			require.Equal(t, "89", string(p[:n]))
+		})
+	}
+	require.Equal(t, 2, ranges.ranges)
+
+	_, err = OpenReaderAt(local, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
@@ -5,8 +5,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"path"
+	"sort"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
@@ -14,9 +17,24 @@ import (
 	"deltalake/storage"
 )
 
+var (
+	// ErrVersionNotFound is returned when a requested version of the table was never committed.
+	ErrVersionNotFound = errors.New("version not found")
+	// ErrVersionExpired is returned when a requested version of the table can no longer be
+	// reconstructed because the commits it depends on have been removed by log cleanup.
+	ErrVersionExpired = errors.New("version expired")
+)
+
 type TableConfig struct {
 	RequireTombstones bool
 	RequireFiles      bool
+	// SkipStats skips reading the statistics of data files from checkpoints, which makes
+	// loading faster when data skipping is not needed. Files added after the checkpoint
+	// keep their statistics.
+	SkipStats bool
+	// CheckpointConcurrency is the number of parts of a multi-part checkpoint that are
+	// loaded at once. Defaults to DefaultCheckpointConcurrency when zero.
+	CheckpointConcurrency int
 }
 
 var DefaultTableConfig = TableConfig{
@@ -26,10 +44,11 @@ var DefaultTableConfig = TableConfig{
 
 // Table is a struct that represents a delta table.
 type Table struct {
-	State             *TableState
-	Config            *TableConfig
-	Storage           storage.ObjectStorage
-	LastCheckpoint    *Checkpoint
+	State          *TableState
+	Config         *TableConfig
+	Storage        storage.ObjectStorage
+	LastCheckpoint *Checkpoint
+	// VersionTimestamps caches the commit timestamps (milliseconds since epoch) by version.
 	VersionTimestamps map[int64]int64
 }
 
@@ -38,9 +57,7 @@ func NewTable(storage storage.ObjectStorage, config *TableConfig) *Table {
 		config = &DefaultTableConfig
 	}
 	return &Table{
-		State: &TableState{
-			Version: -1,
-		},
+		State:             NewTableState(),
 		Storage:           storage,
 		Config:            config,
 		VersionTimestamps: make(map[int64]int64),
@@ -52,9 +69,7 @@ func NewTableWithState(storage storage.ObjectStorage, config *TableConfig, state
 		config = &DefaultTableConfig
 	}
 	if state == nil {
-		state = &TableState{
-			Version: -1,
-		}
+		state = NewTableState()
 	}
 	return &Table{
 		State:             state,
@@ -64,8 +79,9 @@ func NewTableWithState(storage storage.ObjectStorage, config *TableConfig, state
 	}
 }
 
+// TableURI returns the URI of the root of the table.
 func (t *Table) TableURI() string {
-	panic("implement me")
+	return t.Storage.RootURI()
 }
 
 func copyBytes(b []byte) []byte {
@@ -83,6 +99,58 @@ func LoadTable(storage storage.ObjectStorage, config *TableConfig) (*Table, erro
 	return table, nil
 }
 
+// LoadTableAtVersion loads the table as it was at the given version.
+// An error wrapping ErrVersionNotFound is returned if the version was never committed and
+// an error wrapping ErrVersionExpired if it can no longer be reconstructed from the log.
+func LoadTableAtVersion(storage storage.ObjectStorage, config *TableConfig, version int64) (*Table, error) {
+	table := NewTable(storage, config)
+	if err := table.LoadVersion(version); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// LoadVersion replaces the state of the table with the state at the given version.
+// The newest checkpoint at or below the version is used as a starting point and only
+// the commits after it, up to and including the version, are replayed.
+func (t *Table) LoadVersion(version int64) error {
+	if version < 0 {
+		return fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+
+	listing, err := t.listLog()
+	if err != nil {
+		return err
+	}
+	if version > listing.latestVersion() {
+		return fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+
+	checkpoint := listing.checkpointAtOrBefore(version)
+	from := int64(0)
+	if checkpoint != nil {
+		from = checkpoint.Version + 1
+	}
+	if !listing.hasCommits(from, version) {
+		return fmt.Errorf("%w: %d", ErrVersionExpired, version)
+	}
+
+	log.Debug().
+		Int64("version", version).
+		Int64("startVersion", from).
+		Msg("loading version")
+
+	t.LastCheckpoint = nil
+	t.State = NewTableState(WithVersion(-1))
+	if checkpoint != nil {
+		t.LastCheckpoint = checkpoint
+		if err := t.loadCheckpoint(checkpoint); err != nil {
+			return err
+		}
+	}
+	return t.updateIncremental(version)
+}
+
 func (t *Table) load() error {
 	t.LastCheckpoint = nil
 	t.State = NewTableState(WithVersion(-1))
@@ -130,6 +198,9 @@ func (t *Table) updateIncremental(maxVersion int64) error {
 
 	// https://github.com/delta-io/delta-rs/blob/main/rust/src/delta.rs#L766
 	for {
+		if maxVersion != -1 && t.State.Version >= maxVersion { // reached max version
+			break
+		}
 		acts, err := t.peakNextCommit(t.State.Version)
 		if err != nil {
 			return err
@@ -137,9 +208,6 @@ func (t *Table) updateIncremental(maxVersion int64) error {
 		if len(acts) == 0 { // no more commits to load
 			break
 		}
-		if maxVersion != -1 && t.State.Version >= maxVersion { // reached max version
-			break
-		}
 
 		newState, err := NewTableStateFromActions(acts, WithVersion(t.State.Version+1))
 		if err != nil {
@@ -158,8 +226,117 @@ func (t *Table) updateIncremental(maxVersion int64) error {
 	return nil
 }
 
+// LoadTableAtTimestamp loads the table as it was at the given time, that is at the latest
+// version committed at or before the timestamp.
+// An error wrapping ErrVersionNotFound is returned if no version was committed at or before
+// the timestamp and an error wrapping ErrVersionExpired if that version can no longer be
+// reconstructed from the log.
+func LoadTableAtTimestamp(storage storage.ObjectStorage, config *TableConfig, timestamp time.Time) (*Table, error) {
+	table := NewTable(storage, config)
+	if err := table.loadTimestamp(timestamp.UnixMilli()); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// loadTimestamp loads the latest version committed at or before the timestamp (milliseconds since epoch).
 func (t *Table) loadTimestamp(timestamp int64) error {
-	panic("implement me")
+	listing, err := t.listLog()
+	if err != nil {
+		return err
+	}
+	if len(listing.commits) == 0 {
+		return fmt.Errorf("%w: no commits at or before %d", ErrVersionNotFound, timestamp)
+	}
+
+	// Commit timestamps increase with the version, so the log can be binary searched for the
+	// first commit after the timestamp. The version before it is the one to load.
+	var searchErr error
+	i := sort.Search(len(listing.commits), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		ts, err := t.commitTimestamp(listing.commits[i])
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return ts > timestamp
+	})
+	if searchErr != nil {
+		return searchErr
+	}
+
+	if i == 0 {
+		if listing.commits[0].Version > 0 { // older versions existed but have been cleaned up
+			return fmt.Errorf("%w: no commits retained at or before %d", ErrVersionExpired, timestamp)
+		}
+		return fmt.Errorf("%w: no commits at or before %d", ErrVersionNotFound, timestamp)
+	}
+
+	version := listing.commits[i-1].Version
+	log.Debug().
+		Int64("timestamp", timestamp).
+		Int64("version", version).
+		Msg("resolved timestamp to version")
+	return t.LoadVersion(version)
+}
+
+// commitTimestamp returns the timestamp of the commit in milliseconds since epoch.
+// The timestamp of the commitInfo action is used when present, otherwise the modification
+// time of the commit file. Results are cached in VersionTimestamps.
+func (t *Table) commitTimestamp(commit logFile) (int64, error) {
+	if ts, ok := t.VersionTimestamps[commit.Version]; ok {
+		return ts, nil
+	}
+
+	ts, err := t.commitInfoTimestamp(commit.Version)
+	if err != nil {
+		return 0, err
+	}
+	if ts == 0 {
+		info := commit.Info
+		if info.IsZero() {
+			if info, err = t.Storage.Head(commit.Path); err != nil {
+				return 0, err
+			}
+		}
+		ts = info.LastModified.UnixMilli()
+	}
+
+	t.VersionTimestamps[commit.Version] = ts
+	return ts, nil
+}
+
+// commitInfoTimestamp returns the timestamp recorded in the commitInfo action of the commit,
+// or 0 if the commit does not contain one.
+func (t *Table) commitInfoTimestamp(version int64) (int64, error) {
+	commitInfo, err := t.readCommitInfo(version)
+	if err != nil || commitInfo == nil {
+		return 0, err
+	}
+	return commitInfo.Timestamp, nil
+}
+
+// readCommitInfo returns the commitInfo action of the commit, or nil if it has none.
+func (t *Table) readCommitInfo(version int64) (*actions.CommitInfo, error) {
+	oplog, err := t.Storage.Get(CommitURIFromVersion(version))
+	if err != nil {
+		return nil, err
+	}
+	defer oplog.Close()
+
+	scanner := bufio.NewScanner(oplog)
+	for scanner.Scan() {
+		action, err := actions.ParseActionJSON(copyBytes(scanner.Bytes()))
+		if err != nil {
+			return nil, err
+		}
+		if commitInfo, ok := action.(*actions.CommitInfo); ok {
+			return commitInfo, nil
+		}
+	}
+	return nil, scanner.Err()
 }
 
 // peakNextCommit returns a list of actions that will update the table state to the next version.
@@ -178,6 +355,7 @@ func (t *Table) peakNextCommit(currentVersion int64) ([]actions.Action, error) {
 		}
 		return nil, err
 	}
+	defer oplog.Close()
 
 	log.Debug().
 		Str("uri", uri).
@@ -191,6 +369,9 @@ func (t *Table) peakNextCommit(currentVersion int64) ([]actions.Action, error) {
 		}
 		acts = append(acts, action)
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 
 	return acts, nil
 }
@@ -1,7 +1,10 @@
 package deltalake
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -31,3 +34,132 @@ func TestLoadTable(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadTableAtVersion(t *testing.T) {
+	tests := map[string]struct {
+		path        string
+		version     int64
+		wantFiles   int
+		wantErr     error
+		removeFiles []string
+	}{
+		"simple first version":                {path: "testdata/simple_table", version: 0, wantFiles: 6},
+		"simple middle version":               {path: "testdata/simple_table", version: 2, wantFiles: 6},
+		"simple latest version":               {path: "testdata/simple_table", version: 4, wantFiles: 5},
+		"simple missing version":              {path: "testdata/simple_table", version: 5, wantErr: ErrVersionNotFound},
+		"simple negative version":             {path: "testdata/simple_table", version: -1, wantErr: ErrVersionNotFound},
+		"checkpoint before checkpoint":        {path: "testdata/simple_table_with_checkpoint", version: 5, wantFiles: 6},
+		"checkpoint at checkpoint":            {path: "testdata/simple_table_with_checkpoint", version: 10, wantFiles: 11},
+		"checkpoint missing version":          {path: "testdata/simple_table_with_checkpoint", version: 11, wantErr: ErrVersionNotFound},
+		"checkpoint expired version":          {path: "testdata/simple_table_with_checkpoint", version: 5, wantErr: ErrVersionExpired, removeFiles: []string{"00000000000000000000.json", "00000000000000000001.json"}},
+		"checkpoint version after expiration": {path: "testdata/simple_table_with_checkpoint", version: 10, wantFiles: 11, removeFiles: []string{"00000000000000000000.json", "00000000000000000001.json"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := test.path
+			if len(test.removeFiles) > 0 {
+				path = copyTable(t, test.path)
+				for _, f := range test.removeFiles {
+					require.NoError(t, os.Remove(filepath.Join(path, LogDirName, f)))
+				}
+			}
+			store, err := storage.NewLocalStorage(path)
+			require.NoErrorf(t, err, "failed to create local storage at %s", path)
+			tbl, err := LoadTableAtVersion(store, nil, test.version)
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.version, tbl.State.Version)
+			require.Len(t, tbl.State.Files, test.wantFiles)
+		})
+	}
+}
+
+// copyTable copies the table at src into a temporary directory and returns its path.
+func copyTable(t *testing.T, src string) string {
+	t.Helper()
+	dst := t.TempDir()
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0o644)
+	})
+	require.NoError(t, err)
+	return dst
+}
+
+func TestLoadTableAtTimestamp(t *testing.T) {
+	tests := map[string]struct {
+		path        string
+		timestamp   int64
+		wantVersion int64
+		wantErr     error
+		removeFiles []string
+	}{
+		"simple exact commit time":     {path: "testdata/simple_table", timestamp: 1587968596254, wantVersion: 1},
+		"simple between commits":       {path: "testdata/simple_table", timestamp: 1587968614000, wantVersion: 2},
+		"simple after latest":          {path: "testdata/simple_table", timestamp: 1700000000000, wantVersion: 4},
+		"simple before first":          {path: "testdata/simple_table", timestamp: 1587968586000, wantErr: ErrVersionNotFound},
+		"checkpoint between commits":   {path: "testdata/simple_table_with_checkpoint", timestamp: 1615751710000, wantVersion: 9},
+		"checkpoint after checkpoint":  {path: "testdata/simple_table_with_checkpoint", timestamp: 1615751716705, wantVersion: 10},
+		"checkpoint before expiration": {path: "testdata/simple_table_with_checkpoint", timestamp: 1615751700500, wantErr: ErrVersionExpired, removeFiles: []string{"00000000000000000000.json", "00000000000000000001.json"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := test.path
+			if len(test.removeFiles) > 0 {
+				path = copyTable(t, test.path)
+				for _, f := range test.removeFiles {
+					require.NoError(t, os.Remove(filepath.Join(path, LogDirName, f)))
+				}
+			}
+			store, err := storage.NewLocalStorage(path)
+			require.NoErrorf(t, err, "failed to create local storage at %s", path)
+			tbl, err := LoadTableAtTimestamp(store, nil, time.UnixMilli(test.timestamp))
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantVersion, tbl.State.Version)
+			require.NotEmpty(t, tbl.VersionTimestamps)
+		})
+	}
+}
+
+func TestLoadTableAtTimestamp_ModificationTimeFallback(t *testing.T) {
+	path := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(path, LogDirName), 0o755))
+	commits := []string{
+		`{"protocol":{"minReaderVersion":1,"minWriterVersion":2}}` + "\n" +
+			`{"metaData":{"id":"1","format":{"provider":"parquet","options":{}},"schemaString":"{\"type\":\"struct\",\"fields\":[]}","partitionColumns":[],"configuration":{}}}`,
+		`{"add":{"path":"a.parquet","partitionValues":{},"size":1,"modificationTime":0,"dataChange":true}}`,
+	}
+	for version, commit := range commits {
+		p := filepath.Join(path, CommitURIFromVersion(int64(version)))
+		require.NoError(t, os.WriteFile(p, []byte(commit), 0o644))
+		mtime := time.UnixMilli(int64(1000 * (version + 1)))
+		require.NoError(t, os.Chtimes(p, mtime, mtime))
+	}
+
+	store, err := storage.NewLocalStorage(path)
+	require.NoError(t, err)
+	tbl, err := LoadTableAtTimestamp(store, nil, time.UnixMilli(1500))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), tbl.State.Version)
+	require.Equal(t, int64(1000), tbl.VersionTimestamps[0])
+}
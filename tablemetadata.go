@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -63,6 +64,24 @@ func (m *TableMetadata) String() string {
 	)
 }
 
+// MetadataAction returns the metaData action that records the table metadata in the log.
+func (m *TableMetadata) MetadataAction() (*actions.Metadata, error) {
+	schema, err := json.Marshal(&m.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return actions.NewMetadata(
+		m.ID,
+		m.Name,
+		m.Description,
+		m.Format,
+		string(schema),
+		m.PartitionColumns,
+		m.CreatedTime,
+		m.Configuration,
+	), nil
+}
+
 func NewTableMetadataFromMap(m map[string]interface{}) (*TableMetadata, error) {
 	metadata := &TableMetadata{}
 	if id, ok := m["id"]; ok {
@@ -103,7 +122,12 @@ func NewTableMetadataFromMap(m map[string]interface{}) (*TableMetadata, error) {
 	return metadata, nil
 }
 
+// TombstoneRetentionMillis returns how long removed files must be kept for readers of older
+// versions, from delta.deletedFileRetentionDuration, or 0 if the table does not configure it.
 func (m *TableMetadata) TombstoneRetentionMillis() int64 {
+	if d, err := parseInterval(m.Configuration["delta.deletedFileRetentionDuration"]); err == nil {
+		return d.Milliseconds()
+	}
 	if m.Configuration != nil {
 		tombstoneRetentionMillis, err := strconv.Atoi(m.Configuration["tombstoneRetentionDurationMillis"])
 		if err != nil {
@@ -114,7 +138,12 @@ func (m *TableMetadata) TombstoneRetentionMillis() int64 {
 	return 0
 }
 
+// LogRetentionMillis returns how long commits are kept in the log, from
+// delta.logRetentionDuration, or 0 if the table does not configure it.
 func (m *TableMetadata) LogRetentionMillis() int64 {
+	if d, err := parseInterval(m.Configuration["delta.logRetentionDuration"]); err == nil {
+		return d.Milliseconds()
+	}
 	if m.Configuration != nil {
 		logRetentionMillis, err := strconv.Atoi(m.Configuration["logRetentionDurationMillis"])
 		if err != nil {
@@ -125,13 +154,70 @@ func (m *TableMetadata) LogRetentionMillis() int64 {
 	return 0
 }
 
+// EnableLogExpiredCleanup returns true if commits older than the log retention are deleted
+// after writing a checkpoint (delta.enableExpiredLogCleanup). Tables must opt in, so the
+// history of tables that do not configure it is kept.
 func (m *TableMetadata) EnableLogExpiredCleanup() bool {
+	for _, key := range []string{"delta.enableExpiredLogCleanup", "enableLogExpiredCleanup"} {
+		if enabled, err := strconv.ParseBool(m.Configuration[key]); err == nil {
+			return enabled
+		}
+	}
+	return false
+}
+
+// AppendOnly returns true if the table only allows appending data (delta.appendOnly).
+func (m *TableMetadata) AppendOnly() bool {
 	if m.Configuration != nil {
-		enableLogExpiredCleanup, err := strconv.ParseBool(m.Configuration["enableLogExpiredCleanup"])
+		appendOnly, err := strconv.ParseBool(m.Configuration["delta.appendOnly"])
 		if err != nil {
 			return false
 		}
-		return enableLogExpiredCleanup
+		return appendOnly
 	}
 	return false
 }
+
+// EnableChangeDataFeed returns true if operations changing rows also write the changes to
+// change data files (delta.enableChangeDataFeed).
+func (m *TableMetadata) EnableChangeDataFeed() bool {
+	enabled, err := strconv.ParseBool(m.Configuration["delta.enableChangeDataFeed"])
+	return err == nil && enabled
+}
+
+// intervalUnits are the units of the intervals of table properties, like "interval 7 days".
+var intervalUnits = map[string]time.Duration{
+	"nanosecond":  time.Nanosecond,
+	"microsecond": time.Microsecond,
+	"millisecond": time.Millisecond,
+	"second":      time.Second,
+	"minute":      time.Minute,
+	"hour":        time.Hour,
+	"day":         24 * time.Hour,
+	"week":        7 * 24 * time.Hour,
+}
+
+// parseInterval parses an interval of a table property, like "interval 1 week" or
+// "interval 1 day 12 hours". The "interval" keyword is optional.
+func parseInterval(s string) (time.Duration, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) > 0 && fields[0] == "interval" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return 0, fmt.Errorf("invalid interval: %q", s)
+	}
+	var d time.Duration
+	for i := 0; i < len(fields); i += 2 {
+		n, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval: %q", s)
+		}
+		unit, ok := intervalUnits[strings.TrimSuffix(fields[i+1], "s")]
+		if !ok {
+			return 0, fmt.Errorf("invalid interval unit %q in %q", fields[i+1], s)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
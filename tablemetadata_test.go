@@ -0,0 +1,74 @@
+package deltalake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := map[string]struct {
+		interval string
+		want     time.Duration
+		wantErr  bool
+	}{
+		"week":           {interval: "interval 1 week", want: 7 * 24 * time.Hour},
+		"plural":         {interval: "interval 30 days", want: 30 * 24 * time.Hour},
+		"without prefix": {interval: "2 hours", want: 2 * time.Hour},
+		"compound":       {interval: "INTERVAL 1 day 12 hours", want: 36 * time.Hour},
+		"milliseconds":   {interval: "interval 500 milliseconds", want: 500 * time.Millisecond},
+		"empty":          {interval: "", wantErr: true},
+		"missing unit":   {interval: "interval 1", wantErr: true},
+		"unknown unit":   {interval: "interval 1 fortnight", wantErr: true},
+		"not a number":   {interval: "interval one day", wantErr: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseInterval(test.interval)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestTableMetadata_TombstoneRetentionMillis(t *testing.T) {
+	tests := map[string]struct {
+		configuration map[string]string
+		want          int64
+	}{
+		"unset":    {configuration: map[string]string{}, want: 0},
+		"property": {configuration: map[string]string{"delta.deletedFileRetentionDuration": "interval 2 days"}, want: 2 * 24 * 60 * 60 * 1000},
+		"legacy":   {configuration: map[string]string{"tombstoneRetentionDurationMillis": "1000"}, want: 1000},
+		"invalid":  {configuration: map[string]string{"delta.deletedFileRetentionDuration": "soon"}, want: 0},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &TableMetadata{Configuration: test.configuration}
+			require.Equal(t, test.want, m.TombstoneRetentionMillis())
+		})
+	}
+}
+
+func TestTableMetadata_LogCleanupConfiguration(t *testing.T) {
+	tests := map[string]struct {
+		configuration map[string]string
+		wantRetention int64
+		wantCleanup   bool
+	}{
+		"unset":    {configuration: nil, wantRetention: 0},
+		"property": {configuration: map[string]string{"delta.logRetentionDuration": "interval 1 day", "delta.enableExpiredLogCleanup": "true"}, wantRetention: 24 * 60 * 60 * 1000, wantCleanup: true},
+		"legacy":   {configuration: map[string]string{"logRetentionDurationMillis": "1000", "enableLogExpiredCleanup": "true"}, wantRetention: 1000, wantCleanup: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &TableMetadata{Configuration: test.configuration}
+			require.Equal(t, test.wantRetention, m.LogRetentionMillis())
+			require.Equal(t, test.wantCleanup, m.EnableLogExpiredCleanup())
+		})
+	}
+}
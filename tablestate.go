@@ -6,11 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 
+	"github.com/parquet-go/parquet-go"
 	"github.com/rs/zerolog/log"
-	"github.com/segmentio/parquet-go"
 
 	"deltalake/actions"
+	"deltalake/storage"
 	"deltalake/types"
 )
 
@@ -22,6 +24,8 @@ type TableState struct {
 	CommitInfos              []*actions.CommitInfo
 	MinReaderVersion         int
 	MinWriterVersion         int
+	ReaderFeatures           []string // only with reader version 3
+	WriterFeatures           []string // only with writer version 7
 	CurrentMetadata          *TableMetadata
 	TombstoneRetentionMillis int64
 	LogRetentionMillis       int64
@@ -89,12 +93,14 @@ func (s *TableState) DoAction(action actions.Action, requireFiles bool, requireT
 		if requireFiles {
 			s.Files = append(s.Files, a)
 		}
-	case *actions.CDC: // TODO: optionally support CDC
+	case *actions.CDC: // change data files are only read by Table.Changes
 	case *actions.CommitInfo:
 		s.CommitInfos = append(s.CommitInfos, a)
 	case *actions.Protocol:
 		s.MinReaderVersion = a.MinReaderVersion
 		s.MinWriterVersion = a.MinWriterVersion
+		s.ReaderFeatures = a.ReaderFeatures
+		s.WriterFeatures = a.WriterFeatures
 	case *actions.Metadata:
 		var schema types.StructType
 		if err := json.Unmarshal([]byte(a.SchemaString), &schema); err != nil {
@@ -167,6 +173,8 @@ func (s *TableState) Merge(other *TableState, requireFiles bool, requireTombston
 	if other.MinReaderVersion > 0 {
 		s.MinReaderVersion = other.MinReaderVersion
 		s.MinWriterVersion = other.MinWriterVersion
+		s.ReaderFeatures = other.ReaderFeatures
+		s.WriterFeatures = other.WriterFeatures
 	}
 
 	if other.CurrentMetadata != nil {
@@ -193,59 +201,140 @@ func (s *TableState) Merge(other *TableState, requireFiles bool, requireTombston
 	}
 }
 
+// checkpointBatchSize is the number of checkpoint rows decoded and applied at once.
+const checkpointBatchSize = 1024
+
+// NewTableStateFromCheckpoint loads the table state from the parts of the checkpoint.
+// Parts are read directly from the storage in batches of rows, so that memory use does not
+// grow with the size of the checkpoint beyond the state itself. The parts of a multi-part
+// checkpoint are loaded concurrently, up to the CheckpointConcurrency of the table config,
+// and merged in part order. Only the actions and columns required by the table config are read.
 func NewTableStateFromCheckpoint(table *Table, checkpoint *Checkpoint) (*TableState, error) {
 	checkpointPaths := ListCheckpointParts(checkpoint)
+	concurrency := DefaultCheckpointConcurrency
+	if table.Config != nil && table.Config.CheckpointConcurrency > 0 {
+		concurrency = table.Config.CheckpointConcurrency
+	}
+	projection := projectionFromConfig(table.Config)
 	log.Debug().
 		Strs("paths", checkpointPaths).
-		Int("num_parts", len(checkpointPaths)).Msg("loading checkpoint")
+		Int("num_parts", len(checkpointPaths)).
+		Int("concurrency", concurrency).Msg("loading checkpoint")
+
+	parts := make([]*TableState, len(checkpointPaths))
+	errs := make([]error, len(checkpointPaths))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, path := range checkpointPaths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, path string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			parts[i], errs[i] = loadCheckpointPart(table.Storage, path, checkpoint.Version, projection)
+		}(i, path)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
 	state := NewTableState(WithVersion(checkpoint.Version))
-	for _, path := range checkpointPaths {
-		obj, err := table.Storage.Get(path)
-		if err != nil {
-			return nil, err
-		}
-		data := &bytes.Buffer{}
-		if _, err := io.Copy(data, obj); err != nil {
-			return nil, err
-		}
-		obj.Close()
+	for _, part := range parts {
+		state.Merge(part, projection.files, projection.tombstones)
+	}
+	return state, nil
+}
 
-		if err := state.ParseCheckpointBytes(data.Bytes()); err != nil {
-			return nil, err
-		}
+// loadCheckpointPart returns the state of the actions of a single checkpoint part. The
+// protocol versions are zero unless the part contains the protocol action, so that merging
+// the part does not reset the protocol read from another part.
+func loadCheckpointPart(store storage.ObjectStorage, path string, version int64, projection checkpointProjection) (*TableState, error) {
+	obj, err := storage.OpenReaderAt(store, path)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	state := NewTableState(WithVersion(version), WithMinReaderVersion(0), WithMinWriterVersion(0))
+	if err := state.readCheckpoint(obj, obj.Size(), projection); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
 	return state, nil
 }
 
+// ParseCheckpointBytes applies the actions of a checkpoint file held in memory.
 func (s *TableState) ParseCheckpointBytes(data []byte) error {
-	reader := parquet.NewGenericReader[any](bytes.NewReader(data))
-	defer reader.Close()
+	return s.ReadCheckpoint(bytes.NewReader(data), int64(len(data)))
+}
 
-	schema := reader.Schema()
+// ReadCheckpoint applies the actions of a checkpoint file of the given size. Rows are read
+// and applied in batches, so only the footer and the pages being decoded are held in memory.
+func (s *TableState) ReadCheckpoint(r io.ReaderAt, size int64) error {
+	return s.readCheckpoint(r, size, fullProjection)
+}
 
-	rows := make([]parquet.Row, reader.NumRows()) // TODO: Read in batches instead of all at once? Do using for (while) loop and fixed batch size
-	n, err := reader.ReadRows(rows)
-	if err != nil && !errors.Is(err, io.EOF) {
+// readCheckpoint applies the actions of the projection from a checkpoint file. Row groups
+// without any of the actions are skipped and only the projected columns are decoded.
+func (s *TableState) readCheckpoint(r io.ReaderAt, size int64, projection checkpointProjection) error {
+	file, err := parquet.OpenFile(r, size, parquet.SkipPageIndex(true), parquet.SkipBloomFilters(true))
+	if err != nil {
 		return err
 	}
-	if int64(n) != reader.NumRows() {
-		return fmt.Errorf("expected %d rows, got %d", reader.NumRows(), n)
+	schema := projection.schema(file.Schema())
+	rows := make([]parquet.Row, checkpointBatchSize)
+	for i, rowGroup := range file.RowGroups() {
+		if projection.skipRowGroup(file, i) {
+			log.Debug().Int("rowGroup", i).Int64("rows", rowGroup.NumRows()).Msg("skipping checkpoint row group")
+			continue
+		}
+		if err := s.readCheckpointRowGroup(rowGroup, schema, rows); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	acts := make([]actions.Action, 0, len(rows))
-	for _, row := range rows {
-		action, err := actions.ParseParquetRecord(schema, row)
+// readCheckpointRowGroup applies the actions of the rows of the row group read with the schema,
+// decoding them in batches of the size of rows.
+func (s *TableState) readCheckpointRowGroup(rowGroup parquet.RowGroup, schema *parquet.Schema, rows []parquet.Row) error {
+	reader := parquet.NewRowGroupReader(rowGroup, schema)
+	defer reader.Close()
+
+	for {
+		n, err := reader.ReadRows(rows)
+		for _, row := range rows[:n] {
+			if isNullRow(row) { // a row of an action that is not projected
+				continue
+			}
+			action, err := actions.ParseParquetRecord(schema, row)
+			if err != nil {
+				return err
+			}
+			if action == nil { // a row without a known action, like commitInfo or domainMetadata
+				continue
+			}
+			if err := s.DoAction(action, true, true); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
-		acts = append(acts, action)
 	}
+}
 
-	for _, action := range acts {
-		if err := s.DoAction(action, true, true); err != nil {
-			return err
+// isNullRow returns true if all the values of the row are null.
+func isNullRow(row parquet.Row) bool {
+	for _, v := range row {
+		if !v.IsNull() {
+			return false
 		}
 	}
-
-	return nil
+	return true
 }
@@ -0,0 +1,382 @@
+package deltalake
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/storage"
+)
+
+const (
+	// DefaultMaxCommitRetries is the number of times a commit is retried on version collisions.
+	DefaultMaxCommitRetries = 15
+
+	// maxWriterVersion is the highest writer protocol version this library can write to.
+	maxWriterVersion = 2
+
+	// engineInfo is recorded in the commitInfo of every commit written by this library.
+	engineInfo = "deltalake-go"
+)
+
+var (
+	// ErrCommitConflict is returned when a concurrent commit conflicts with the transaction.
+	ErrCommitConflict = errors.New("commit conflict")
+	// ErrMaxCommitRetries is returned when a commit still collides with concurrent commits
+	// after the maximum number of retries.
+	ErrMaxCommitRetries = errors.New("max commit retries exceeded")
+)
+
+// Transaction accumulates actions and commits them atomically as the next version of a table.
+// Commits use optimistic concurrency: if another writer committed the version first, the
+// transaction checks the winning commit for conflicts and retries with the next version.
+type Transaction struct {
+	table               *Table
+	readVersion         int64
+	actions             []actions.Action
+	readPredicates      []Expr
+	operation           string
+	operationParameters map[string]interface{}
+	operationMetrics    map[string]interface{}
+	maxRetries          int
+}
+
+type TransactionOption func(*Transaction)
+
+// WithMaxCommitRetries sets the number of times a commit is retried on version collisions.
+func WithMaxCommitRetries(retries int) TransactionOption {
+	return func(tx *Transaction) {
+		tx.maxRetries = retries
+	}
+}
+
+// WithOperation sets the operation and its parameters recorded in the commitInfo.
+func WithOperation(operation string, parameters map[string]interface{}) TransactionOption {
+	return func(tx *Transaction) {
+		tx.operation = operation
+		tx.operationParameters = parameters
+	}
+}
+
+// NewTransaction starts a transaction reading from the current version of the table.
+func (t *Table) NewTransaction(options ...TransactionOption) *Transaction {
+	tx := &Transaction{
+		table:               t,
+		readVersion:         t.State.Version,
+		actions:             make([]actions.Action, 0),
+		operation:           "WRITE",
+		operationParameters: map[string]interface{}{"mode": "Append"},
+		maxRetries:          DefaultMaxCommitRetries,
+	}
+	for _, option := range options {
+		option(tx)
+	}
+	return tx
+}
+
+// ReadVersion returns the version of the table the transaction started from.
+func (tx *Transaction) ReadVersion() int64 {
+	return tx.readVersion
+}
+
+// AddAction adds an action to the transaction.
+func (tx *Transaction) AddAction(action actions.Action) {
+	tx.actions = append(tx.actions, action)
+}
+
+// AddActions adds actions to the transaction.
+func (tx *Transaction) AddActions(acts ...actions.Action) {
+	tx.actions = append(tx.actions, acts...)
+}
+
+// AddReadPredicate records that the transaction read the rows of the table matching the
+// predicate, so that the commit fails with ErrCommitConflict if a concurrent commit added
+// files that may contain such rows, for example when two merges insert the same key.
+// Files added by concurrent blind appends do not conflict, like in Spark's WriteSerializable
+// isolation. Transactions that read the table are not blind appends.
+func (tx *Transaction) AddReadPredicate(pred Expr) {
+	tx.readPredicates = append(tx.readPredicates, pred)
+}
+
+// SetOperationMetrics sets the operation metrics recorded in the commitInfo.
+func (tx *Transaction) SetOperationMetrics(metrics map[string]interface{}) {
+	tx.operationMetrics = metrics
+}
+
+// FileMetrics counts the data files added and removed by an operation rewriting files.
+type FileMetrics struct {
+	// NumFilesAdded is the number of files written.
+	NumFilesAdded int64
+	// NumFilesRemoved is the number of files removed.
+	NumFilesRemoved int64
+	// NumBytesAdded is the total size of the files written.
+	NumBytesAdded int64
+	// NumBytesRemoved is the total size of the files removed.
+	NumBytesRemoved int64
+}
+
+// fileAdded counts a file written.
+func (m *FileMetrics) fileAdded(add *actions.Add) {
+	m.NumFilesAdded++
+	m.NumBytesAdded += add.Size
+}
+
+// fileRemoved counts a file removed.
+func (m *FileMetrics) fileRemoved(add *actions.Add) {
+	m.NumFilesRemoved++
+	m.NumBytesRemoved += add.Size
+}
+
+// operationMetrics returns the file metrics with the names Spark records in the commitInfo
+// of DELETE, UPDATE and OPTIMIZE, and their values as strings.
+func (m *FileMetrics) operationMetrics() map[string]interface{} {
+	return map[string]interface{}{
+		"numAddedFiles":   strconv.FormatInt(m.NumFilesAdded, 10),
+		"numRemovedFiles": strconv.FormatInt(m.NumFilesRemoved, 10),
+		"numAddedBytes":   strconv.FormatInt(m.NumBytesAdded, 10),
+		"numRemovedBytes": strconv.FormatInt(m.NumBytesRemoved, 10),
+	}
+}
+
+// Commit writes the actions as the next version of the table and returns the committed version.
+// The table state is updated to include the commit.
+func (tx *Transaction) Commit() (int64, error) {
+	if err := tx.validate(); err != nil {
+		return -1, err
+	}
+
+	version := tx.readVersion + 1
+	for attempt := 0; ; attempt++ {
+		data, err := tx.serialize()
+		if err != nil {
+			return -1, err
+		}
+
+		uri := CommitURIFromVersion(version)
+		err = tx.table.Storage.PutIfAbsent(uri, bytes.NewReader(data))
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, storage.ErrExists) {
+			return -1, err
+		}
+
+		log.Debug().
+			Str("uri", uri).
+			Int("attempt", attempt).
+			Msg("version already committed by another writer")
+		if attempt >= tx.maxRetries {
+			return -1, fmt.Errorf("%w: %d attempts", ErrMaxCommitRetries, attempt+1)
+		}
+
+		winning, err := tx.table.peakNextCommit(version - 1)
+		if err != nil {
+			return -1, err
+		}
+		if err := tx.checkConflicts(version, winning); err != nil {
+			return -1, err
+		}
+		version++
+	}
+
+	log.Debug().
+		Int64("version", version).
+		Int64("readVersion", tx.readVersion).
+		Int("actions", len(tx.actions)).
+		Msg("committed transaction")
+
+	if tx.table.State.Version < version {
+		if err := tx.table.updateIncremental(version); err != nil {
+			return version, err
+		}
+	}
+	return version, nil
+}
+
+// validate checks that the transaction can be committed to the table.
+func (tx *Transaction) validate() error {
+	state := tx.table.State
+	hasMetadata := false
+	for _, action := range tx.actions {
+		switch a := action.(type) {
+		case *actions.Metadata:
+			hasMetadata = true
+		case *actions.Protocol:
+			if a.MinWriterVersion > maxWriterVersion {
+				return fmt.Errorf("unsupported writer version %d, maximum supported is %d", a.MinWriterVersion, maxWriterVersion)
+			}
+		case *actions.Remove:
+			if a.DataChange && state.CurrentMetadata != nil && state.CurrentMetadata.AppendOnly() {
+				return fmt.Errorf("cannot remove %s: table is append-only", a.Path)
+			}
+		case *actions.CommitInfo:
+			return errors.New("commitInfo is written by the transaction and must not be added")
+		}
+	}
+
+	if tx.readVersion == -1 && !hasMetadata {
+		return errors.New("the first commit of a table must contain a metaData action")
+	}
+	if tx.readVersion >= 0 {
+		return state.checkWriterVersion()
+	}
+	return nil
+}
+
+// checkWriterVersion returns an error if the protocol of the table requires a writer
+// version this library does not support.
+func (s *TableState) checkWriterVersion() error {
+	if s.MinWriterVersion > maxWriterVersion {
+		return fmt.Errorf("unsupported writer version %d, maximum supported is %d", s.MinWriterVersion, maxWriterVersion)
+	}
+	return nil
+}
+
+// checkConflicts returns an error wrapping ErrCommitConflict if the winning commit,
+// committed concurrently at the given version, conflicts with the transaction.
+func (tx *Transaction) checkConflicts(version int64, winning []actions.Action) error {
+	removed := make(map[string]struct{})
+	appIDs := make(map[string]struct{})
+	changesMetadata := false
+	for _, action := range tx.actions {
+		switch a := action.(type) {
+		case *actions.Remove:
+			removed[a.Path] = struct{}{}
+		case *actions.Transaction:
+			appIDs[a.AppID] = struct{}{}
+		case *actions.Metadata, *actions.Protocol:
+			changesMetadata = true
+		}
+	}
+
+	if changesMetadata {
+		return fmt.Errorf("%w: version %d was committed concurrently with a metadata change", ErrCommitConflict, version)
+	}
+	if err := tx.checkReadConflicts(version, winning); err != nil {
+		return err
+	}
+	for _, action := range winning {
+		switch a := action.(type) {
+		case *actions.Metadata:
+			return fmt.Errorf("%w: metadata changed in version %d", ErrCommitConflict, version)
+		case *actions.Protocol:
+			return fmt.Errorf("%w: protocol changed in version %d", ErrCommitConflict, version)
+		case *actions.Remove:
+			if _, ok := removed[a.Path]; ok {
+				return fmt.Errorf("%w: %s was concurrently removed in version %d", ErrCommitConflict, a.Path, version)
+			}
+		case *actions.Transaction:
+			if _, ok := appIDs[a.AppID]; ok {
+				return fmt.Errorf("%w: app %s concurrently committed in version %d", ErrCommitConflict, a.AppID, version)
+			}
+		}
+	}
+	return nil
+}
+
+// checkReadConflicts returns an error wrapping ErrCommitConflict if the winning commit,
+// unless it is a blind append, added data files that may contain rows matching the read
+// predicates of the transaction.
+func (tx *Transaction) checkReadConflicts(version int64, winning []actions.Action) error {
+	if len(tx.readPredicates) == 0 || tx.isBlindAppend() {
+		return nil
+	}
+	for _, action := range winning {
+		if commitInfo, ok := action.(*actions.CommitInfo); ok && commitInfo.IsBlindAppend != nil && *commitInfo.IsBlindAppend {
+			return nil
+		}
+	}
+	metadata := tx.table.State.CurrentMetadata
+	for _, action := range winning {
+		add, ok := action.(*actions.Add)
+		if !ok || !add.DataChange {
+			continue
+		}
+		f := &fileSkipping{metadata: metadata, add: add}
+		for _, pred := range tx.readPredicates {
+			ok, err := f.mayMatch(pred)
+			if err != nil {
+				return fmt.Errorf("%s: %w", add.Path, err)
+			}
+			if ok {
+				return fmt.Errorf("%w: %s, added in version %d, may contain rows matching %s read by the transaction", ErrCommitConflict, add.Path, version, pred)
+			}
+		}
+	}
+	return nil
+}
+
+// isBlindAppend returns true if the transaction only adds data files, without reading
+// the table.
+func (tx *Transaction) isBlindAppend() bool {
+	if len(tx.readPredicates) > 0 {
+		return false
+	}
+	for _, action := range tx.actions {
+		add, ok := action.(*actions.Add)
+		if !ok || !add.DataChange {
+			return false
+		}
+	}
+	return true
+}
+
+// commitInfo returns the commitInfo action written at the start of the commit.
+func (tx *Transaction) commitInfo() *actions.CommitInfo {
+	isBlindAppend := tx.isBlindAppend()
+	commitInfo := &actions.CommitInfo{
+		Timestamp:           time.Now().UnixMilli(),
+		Operation:           tx.operation,
+		OperationParameters: tx.operationParameters,
+		IsBlindAppend:       &isBlindAppend,
+		EngineInfo:          engineInfo,
+	}
+	if tx.readVersion >= 0 {
+		readVersion := tx.readVersion
+		commitInfo.ReadVersion = &readVersion
+	}
+	if len(tx.operationMetrics) > 0 {
+		commitInfo.OperationMetrics = tx.operationMetrics
+	}
+	return commitInfo
+}
+
+// serialize returns the content of the commit file: one JSON action per line,
+// starting with the commitInfo, followed by the protocol and metadata changes.
+func (tx *Transaction) serialize() ([]byte, error) {
+	ordered := make([]actions.Action, 0, len(tx.actions)+1)
+	ordered = append(ordered, tx.commitInfo())
+	for _, action := range tx.actions {
+		if _, ok := action.(*actions.Protocol); ok {
+			ordered = append(ordered, action)
+		}
+	}
+	for _, action := range tx.actions {
+		if _, ok := action.(*actions.Metadata); ok {
+			ordered = append(ordered, action)
+		}
+	}
+	for _, action := range tx.actions {
+		switch action.(type) {
+		case *actions.Protocol, *actions.Metadata:
+		default:
+			ordered = append(ordered, action)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	for _, action := range ordered {
+		data, err := actions.SerializeActionJSON(action)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
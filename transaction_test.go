@@ -0,0 +1,111 @@
+package deltalake
+
+import (
+	"bufio"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+)
+
+func TestTransaction_Commit(t *testing.T) {
+	tbl := writeTestTable(t, nil, nil)
+
+	tx := tbl.NewTransaction()
+	require.Equal(t, int64(0), tx.ReadVersion())
+	tx.AddAction(actions.NewAdd("a.parquet", 1, nil, true, 1, nil, nil))
+	tx.AddAction(actions.NewTransaction("app", 1, 1))
+	version, err := tx.Commit()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), version)
+	require.Equal(t, int64(1), tbl.State.Version)
+	require.Len(t, tbl.State.Files, 1)
+	require.Equal(t, int64(1), tbl.State.AppTransactionVersion["app"])
+
+	obj, err := tbl.Storage.Get(CommitURIFromVersion(1))
+	require.NoError(t, err)
+	defer obj.Close()
+	scanner := bufio.NewScanner(obj)
+	require.True(t, scanner.Scan())
+	action, err := actions.ParseActionJSON(scanner.Bytes())
+	require.NoError(t, err)
+	commitInfo, ok := action.(*actions.CommitInfo)
+	require.True(t, ok, "first action should be commitInfo, got %T", action)
+	require.Equal(t, "WRITE", commitInfo.Operation)
+	require.Equal(t, int64(0), *commitInfo.ReadVersion)
+	require.False(t, *commitInfo.IsBlindAppend)
+	require.Equal(t, engineInfo, commitInfo.EngineInfo)
+}
+
+func TestTransaction_CommitRetry(t *testing.T) {
+	tbl := writeTestTable(t, nil, nil)
+
+	first := tbl.NewTransaction()
+	first.AddAction(actions.NewAdd("a.parquet", 1, nil, true, 1, nil, nil))
+	second := tbl.NewTransaction()
+	second.AddAction(actions.NewAdd("b.parquet", 1, nil, true, 1, nil, nil))
+
+	version, err := first.Commit()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), version)
+
+	// the second transaction read version 0 as well and is retried as version 2
+	version, err = second.Commit()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), version)
+	require.Len(t, tbl.State.Files, 2)
+}
+
+func TestTransaction_CommitConflict(t *testing.T) {
+	tests := map[string]struct {
+		first   []actions.Action
+		second  []actions.Action
+		options []TransactionOption
+		wantErr error
+	}{
+		"concurrent remove": {
+			first:   []actions.Action{actions.NewRemove("a.parquet", 1, true, false, nil, 0, nil)},
+			second:  []actions.Action{actions.NewRemove("a.parquet", 1, true, false, nil, 0, nil)},
+			wantErr: ErrCommitConflict,
+		},
+		"concurrent app transaction": {
+			first:   []actions.Action{actions.NewTransaction("app", 1, 1)},
+			second:  []actions.Action{actions.NewTransaction("app", 1, 1)},
+			wantErr: ErrCommitConflict,
+		},
+		"concurrent metadata change": {
+			first:   []actions.Action{actions.NewMetadata("2", "", "", actions.DefaultFormat, `{"type":"struct","fields":[]}`, nil, 0, nil)},
+			second:  []actions.Action{actions.NewAdd("b.parquet", 1, nil, true, 1, nil, nil)},
+			wantErr: ErrCommitConflict,
+		},
+		"no retries": {
+			first:   []actions.Action{actions.NewAdd("a.parquet", 1, nil, true, 1, nil, nil)},
+			second:  []actions.Action{actions.NewAdd("b.parquet", 1, nil, true, 1, nil, nil)},
+			options: []TransactionOption{WithMaxCommitRetries(0)},
+			wantErr: ErrMaxCommitRetries,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writeTestTable(t, nil, nil)
+			first := tbl.NewTransaction()
+			first.AddActions(test.first...)
+			second := tbl.NewTransaction(test.options...)
+			second.AddActions(test.second...)
+
+			_, err := first.Commit()
+			require.NoError(t, err)
+			_, err = second.Commit()
+			require.ErrorIs(t, err, test.wantErr)
+		})
+	}
+}
+
+func TestTransaction_Validate(t *testing.T) {
+	tbl := NewTable(nil, nil)
+	tx := tbl.NewTransaction()
+	tx.AddAction(actions.NewAdd("a.parquet", 1, nil, true, 1, nil, nil))
+	_, err := tx.Commit()
+	require.Error(t, err, "the first commit must contain metadata")
+}
@@ -57,6 +57,59 @@ func NewStructField(name string, dtype any, nullable bool, metadata map[string]s
 	}
 }
 
+// StructType returns the nested struct type of the field, or nil if the field is not a struct.
+func (f *StructField) StructType() *StructType {
+	return f.innerStruct
+}
+
+// ArrayType returns the array type of the field, or nil if the field is not an array.
+func (f *StructField) ArrayType() *ArrayType {
+	return f.innerArray
+}
+
+// MapType returns the map type of the field, or nil if the field is not a map.
+func (f *StructField) MapType() *MapType {
+	return f.innerMap
+}
+
+// validate checks that the type of the field is supported.
+func (f *StructField) validate() error {
+	switch f.Type {
+	case "array":
+		if f.innerArray == nil {
+			return fmt.Errorf("field %s: missing array element type", f.Name)
+		}
+		if !isValidPrimitiveType(f.innerArray.ElementType) {
+			return fmt.Errorf("field %s: unsupported array element type %s", f.Name, f.innerArray.ElementType)
+		}
+	case "map":
+		if f.innerMap == nil {
+			return fmt.Errorf("field %s: missing map key and value types", f.Name)
+		}
+		if !isValidPrimitiveType(f.innerMap.KeyType) || !isValidPrimitiveType(f.innerMap.ValueType) {
+			return fmt.Errorf("field %s: unsupported map type %s", f.Name, f.innerMap)
+		}
+	case "struct":
+		if f.innerStruct == nil {
+			return fmt.Errorf("field %s: missing struct fields", f.Name)
+		}
+		if err := f.innerStruct.Validate(); err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+	default:
+		if !isValidPrimitiveType(f.Type) {
+			return fmt.Errorf("field %s: unsupported type %s", f.Name, f.Type)
+		}
+	}
+	return nil
+}
+
+// isValidPrimitiveType returns true for the primitive types that may be written to a
+// schema, which excludes the deprecated "bool" that other readers do not know.
+func isValidPrimitiveType(dt DataType) bool {
+	return dt != DataTypeBool && IsPrimitiveType(dt)
+}
+
 func (f *StructField) String() string {
 	switch f.Type {
 	case "array":
@@ -74,7 +127,7 @@ func (f *StructField) String() string {
 func (f *StructField) UnmarshalJSON(data []byte) error {
 	var v struct {
 		Name     string            `json:"name"`
-		Type     DataType          `json:"type"`
+		Type     json.RawMessage   `json:"type"`
 		Nullable bool              `json:"nullable"`
 		Metadata map[string]string `json:"metadata"`
 	}
@@ -84,11 +137,35 @@ func (f *StructField) UnmarshalJSON(data []byte) error {
 	}
 
 	f.Name = v.Name
-	f.Type = v.Type
 	f.Nullable = v.Nullable
 	f.Metadata = v.Metadata
 
-	return nil
+	// Primitive types are a string, complex types are an object with a nested "type".
+	var primitive DataType
+	if err := json.Unmarshal(v.Type, &primitive); err == nil {
+		f.Type = primitive
+		return nil
+	}
+
+	var complexType struct {
+		Type DataType `json:"type"`
+	}
+	if err := json.Unmarshal(v.Type, &complexType); err != nil {
+		return err
+	}
+	f.Type = complexType.Type
+	switch complexType.Type {
+	case "array":
+		f.innerArray = &ArrayType{}
+		return json.Unmarshal(v.Type, f.innerArray)
+	case "map":
+		f.innerMap = &MapType{}
+		return json.Unmarshal(v.Type, f.innerMap)
+	case "struct":
+		f.innerStruct = &StructType{}
+		return json.Unmarshal(v.Type, f.innerStruct)
+	}
+	return fmt.Errorf("unsupported type: %s", complexType.Type)
 }
 
 // MarshalJSON implements the json.Marshaler interface.
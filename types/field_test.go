@@ -63,3 +63,28 @@ func TestField_MarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestField_UnmarshalJSON(t *testing.T) {
+	tests := map[string]struct {
+		field *StructField
+	}{
+		"primitive": {field: NewStructField("id", DataTypeLong, true, nil)},
+		"array":     {field: NewStructField("tags", NewArrayType(DataTypeString, true), true, nil)},
+		"map":       {field: NewStructField("attrs", NewMapType(DataTypeString, DataTypeLong, true), true, nil)},
+		"struct": {field: NewStructField("nested", NewStruct(
+			NewStructField("id", DataTypeLong, false, nil),
+			NewStructField("tags", NewArrayType(DataTypeString, false), true, nil),
+		), true, nil)},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := test.field.MarshalJSON()
+			require.NoError(t, err)
+
+			var got StructField
+			require.NoError(t, got.UnmarshalJSON(data))
+			require.Equal(t, test.field, &got)
+		})
+	}
+}
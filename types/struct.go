@@ -34,6 +34,29 @@ func (t *StructType) GetFieldByName(name string) (*StructField, error) {
 	return nil, fmt.Errorf("field %s not found", name)
 }
 
+// Validate checks that every field has a non-empty name that is unique within the struct
+// (case-insensitive) and a supported type. Nested structs are validated recursively.
+func (t *StructType) Validate() error {
+	seen := make(map[string]struct{}, len(t.Fields))
+	for i, field := range t.Fields {
+		if field == nil {
+			return fmt.Errorf("field %d is nil", i)
+		}
+		if field.Name == "" {
+			return fmt.Errorf("field %d has an empty name", i)
+		}
+		name := strings.ToLower(field.Name)
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate field %s", field.Name)
+		}
+		seen[name] = struct{}{}
+		if err := field.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t *StructType) String() string {
 	sb := &strings.Builder{}
 	sb.WriteString("StructType<")
@@ -155,3 +155,43 @@ func TestParseMapToStructField(t *testing.T) {
 		})
 	}
 }
+
+func TestStructType_Validate(t *testing.T) {
+	tests := map[string]struct {
+		schema  *StructType
+		wantErr bool
+	}{
+		"empty": {schema: NewStruct()},
+		"primitives": {schema: NewStruct(
+			NewStructField("id", DataTypeLong, false, nil),
+			NewStructField("name", DataTypeString, true, nil),
+			NewStructField("active", DataTypeBoolean, true, nil),
+		)},
+		"complex": {schema: NewStruct(
+			NewStructField("tags", NewArrayType(DataTypeString, true), true, nil),
+			NewStructField("attrs", NewMapType(DataTypeString, DataTypeLong, true), true, nil),
+			NewStructField("nested", NewStruct(NewStructField("id", DataTypeLong, false, nil)), true, nil),
+		)},
+		"empty name": {schema: NewStruct(NewStructField("", DataTypeLong, false, nil)), wantErr: true},
+		"duplicate name": {schema: NewStruct(
+			NewStructField("id", DataTypeLong, false, nil),
+			NewStructField("ID", DataTypeString, false, nil),
+		), wantErr: true},
+		"unsupported type": {schema: NewStruct(NewStructField("id", DataType("uuid"), false, nil)), wantErr: true},
+		"deprecated bool":  {schema: NewStruct(NewStructField("active", DataTypeBool, true, nil)), wantErr: true},
+		"bool elements":    {schema: NewStruct(NewStructField("flags", NewArrayType(DataTypeBool, true), true, nil)), wantErr: true},
+		"invalid nested": {schema: NewStruct(
+			NewStructField("nested", NewStruct(NewStructField("id", DataType("uuid"), false, nil)), true, nil),
+		), wantErr: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.schema.Validate()
+			if test.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
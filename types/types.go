@@ -1,5 +1,11 @@
 package types
 
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
 type DataType string
 
 // Primitive types defined in the Delta Lake specification.
@@ -7,7 +13,7 @@ type DataType string
 const (
 	DataTypeBinary    DataType = "binary"    // go: []byte
 	DataTypeByte      DataType = "byte"      // go: int8
-	DataTypeBool      DataType = "bool"      // go: bool
+	DataTypeBoolean   DataType = "boolean"   // go: bool
 	DataTypeDate      DataType = "date"      // go: time.Time
 	DataTypeDouble    DataType = "double"    // go: float64
 	DataTypeFloat     DataType = "float"     // go: float32
@@ -17,13 +23,19 @@ const (
 	DataTypeShort     DataType = "short"     // go: int16
 	DataTypeString    DataType = "string"    // go: string
 	DataTypeTimestamp DataType = "timestamp" // go: time.Time
+
+	// Deprecated: "bool" is not a type name defined in the specification, use DataTypeBoolean instead.
+	DataTypeBool DataType = "bool"
 )
 
+// IsPrimitiveType returns true for the primitive types and decimals. The deprecated "bool"
+// is accepted so that existing schemas can be read, but StructType.Validate refuses it.
 func IsPrimitiveType(dt DataType) bool {
 	switch dt {
 	case
 		DataTypeBinary,
 		DataTypeByte,
+		DataTypeBoolean,
 		DataTypeBool,
 		DataTypeDate,
 		DataTypeDouble,
@@ -36,5 +48,32 @@ func IsPrimitiveType(dt DataType) bool {
 		DataTypeTimestamp:
 		return true
 	}
-	return false
+	_, _, ok := dt.Decimal()
+	return ok
+}
+
+var decimalPattern = regexp.MustCompile(`^decimal\(\s*(\d+)\s*,\s*(\d+)\s*\)$`)
+
+// MaxDecimalPrecision is the maximum precision of a decimal type.
+const MaxDecimalPrecision = 38
+
+// DecimalType returns the decimal type with the precision and scale, for example
+// "decimal(10,2)". The Go type of decimal values is *big.Rat.
+func DecimalType(precision, scale int) DataType {
+	return DataType(fmt.Sprintf("decimal(%d,%d)", precision, scale))
+}
+
+// Decimal returns the precision and scale of a decimal type. False is returned if the
+// type is not a valid decimal type.
+func (dt DataType) Decimal() (precision, scale int, ok bool) {
+	m := decimalPattern.FindStringSubmatch(string(dt))
+	if m == nil {
+		return 0, 0, false
+	}
+	precision, _ = strconv.Atoi(m[1])
+	scale, _ = strconv.Atoi(m[2])
+	if precision < 1 || precision > MaxDecimalPrecision || scale > precision {
+		return 0, 0, false
+	}
+	return precision, scale, true
 }
@@ -0,0 +1,33 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataType_Decimal(t *testing.T) {
+	tests := map[string]struct {
+		dt        DataType
+		precision int
+		scale     int
+		ok        bool
+	}{
+		"decimal":           {dt: "decimal(10,2)", precision: 10, scale: 2, ok: true},
+		"with spaces":       {dt: "decimal(38, 0)", precision: 38, scale: 0, ok: true},
+		"constructor":       {dt: DecimalType(5, 5), precision: 5, scale: 5, ok: true},
+		"scale > precision": {dt: "decimal(2,3)"},
+		"precision too big": {dt: "decimal(39,2)"},
+		"zero precision":    {dt: "decimal(0,0)"},
+		"not a decimal":     {dt: "decimal"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			precision, scale, ok := test.dt.Decimal()
+			require.Equal(t, test.ok, ok)
+			require.Equal(t, test.precision, precision)
+			require.Equal(t, test.scale, scale)
+			require.Equal(t, test.ok, IsPrimitiveType(test.dt))
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package deltalake
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/types"
+)
+
+// ErrInvalidAssignment is returned by Update and Merge when an assignment references a column that
+// is not in the schema or has a type that cannot be stored in the column.
+var ErrInvalidAssignment = errors.New("invalid assignment")
+
+// UpdateMetrics reports the work done by Update. The files added are written with the rows
+// of the files removed, which are the files with updated rows.
+type UpdateMetrics struct {
+	// Version is the version committed by Update, or the current version if no row matched.
+	Version int64
+	FileMetrics
+	// NumUpdatedRows is the number of rows updated.
+	NumUpdatedRows int64
+	// NumCopiedRows is the number of rows of rewritten files that were not updated.
+	NumCopiedRows int64
+}
+
+// operationMetrics returns the metrics of the UPDATE commitInfo, with the row counts.
+func (m *UpdateMetrics) operationMetrics() map[string]interface{} {
+	metrics := m.FileMetrics.operationMetrics()
+	metrics["numUpdatedRows"] = strconv.FormatInt(m.NumUpdatedRows, 10)
+	metrics["numCopiedRows"] = strconv.FormatInt(m.NumCopiedRows, 10)
+	return metrics
+}
+
+// Update sets the columns of the rows matching the predicate, or of all the rows if the
+// predicate is nil, to the values of the assignment expressions, for example
+//
+//	metrics, err := table.Update(Eq(Col("id"), Lit(1)), map[string]Expr{
+//		"status": Lit("closed"),
+//		"count":  Add(Col("count"), Lit(1)),
+//	})
+//
+// The expressions are evaluated against the values of the row before the update. They are
+// type-checked against the schema first: integers can be assigned to any number column,
+// floats to float, double and decimal columns, and dates and timestamps, which can be given
+// as strings, to date and timestamp columns. Values out of range of their column fail the
+// update. Rows for which the predicate is null are not updated, as in SQL.
+//
+// Only the files that may contain matching rows, according to their partition values and
+// statistics, are read, and only those with matching rows are rewritten. Removed and added
+// files, and the change data files of the updated rows, with their values before and after
+// the update, if the change data feed of the table is enabled, are committed in a single
+// UPDATE commit. Nothing is committed if no row matches.
+// The commit fails with ErrCommitConflict if a concurrent commit removed any of the
+// rewritten files. Tables whose writer version is not supported are refused before any
+// file is written.
+func (t *Table) Update(pred Expr, assignments map[string]Expr) (*UpdateMetrics, error) {
+	if t.State.CurrentMetadata == nil {
+		return nil, errors.New("table has no metadata")
+	}
+	if !t.Config.RequireFiles {
+		return nil, errors.New("update requires a table state loaded with files")
+	}
+	// the commit would be refused after the rewritten files are written
+	if err := t.State.checkWriterVersion(); err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return nil, fmt.Errorf("%w: no columns to update", ErrInvalidAssignment)
+	}
+	assignments, err := checkAssignments(&t.State.CurrentMetadata.Schema, assignments)
+	if err != nil {
+		return nil, err
+	}
+	match := pred
+	if match == nil {
+		match = Lit(true)
+	} else if dt, err := exprType(&t.State.CurrentMetadata.Schema, pred); err != nil {
+		return nil, err
+	} else if dt != types.DataTypeBoolean && dt != types.DataTypeNull {
+		return nil, fmt.Errorf("predicate %s is of type %s, not boolean", pred, dt)
+	}
+
+	files, err := t.State.FilesMatching(match)
+	if err != nil {
+		return nil, err
+	}
+	metrics := &UpdateMetrics{Version: t.State.Version}
+	tx := t.NewTransaction(WithOperation("UPDATE", map[string]interface{}{
+		"predicate": predicateParameter(pred),
+	}))
+	tx.AddReadPredicate(match)
+	changes, err := t.newChangeWriter()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	for _, add := range files {
+		adds, updated, copied, err := t.rewriteFileUpdated(add, match, assignments, changes)
+		if err != nil {
+			return nil, err
+		}
+		if updated == 0 {
+			continue
+		}
+		for _, a := range adds {
+			tx.AddAction(a)
+			metrics.fileAdded(a)
+		}
+		tx.AddAction(actions.NewRemove(add.Path, now, true, true, add.PartitionValues, add.Size, add.Tags))
+		metrics.fileRemoved(add)
+		metrics.NumUpdatedRows += updated
+		metrics.NumCopiedRows += copied
+	}
+
+	log.Debug().
+		Int("candidates", len(files)).
+		Int64("filesRemoved", metrics.NumFilesRemoved).
+		Int64("updatedRows", metrics.NumUpdatedRows).
+		Msg("update")
+	if metrics.NumFilesRemoved == 0 {
+		return metrics, nil
+	}
+	cdcs, err := closeChangeWriter(changes)
+	if err != nil {
+		return nil, err
+	}
+	for _, cdc := range cdcs {
+		tx.AddAction(cdc)
+	}
+
+	tx.SetOperationMetrics(metrics.operationMetrics())
+	version, err := tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	metrics.Version = version
+	return metrics, nil
+}
+
+// checkAssignments checks that the assignments can be stored in their columns and returns
+// them keyed by the names of the columns in the schema, with strings assigned to date and
+// timestamp columns parsed.
+func checkAssignments(schema *types.StructType, assignments map[string]Expr) (map[string]Expr, error) {
+	names := make([]string, 0, len(assignments))
+	for name := range assignments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checked := make(map[string]Expr, len(assignments))
+	for _, name := range names {
+		field, expr, err := resolveAssignment(schema, checked, name, assignments[name])
+		if err != nil {
+			return nil, err
+		}
+		if err := checkAssignment(schema, field, expr); err != nil {
+			return nil, err
+		}
+		checked[field.Name] = expr
+	}
+	return checked, nil
+}
+
+// checkAssignment checks that the values of the expression, for records of the schema, can
+// be stored in the column of the field.
+func checkAssignment(schema *types.StructType, field *types.StructField, expr Expr) error {
+	dt, err := exprType(schema, expr)
+	if err != nil {
+		return fmt.Errorf("%w: column %s: %w", ErrInvalidAssignment, field.Name, err)
+	}
+	if !assignable(field.Type, dt) {
+		return fmt.Errorf("%w: cannot assign %s of type %s to column %s of type %s", ErrInvalidAssignment, expr, dt, field.Name, field.Type)
+	}
+	if dt == types.DataTypeNull && !field.Nullable {
+		return fmt.Errorf("%w: column %s is not nullable", ErrInvalidAssignment, field.Name)
+	}
+	return nil
+}
+
+// resolveAssignment returns the field of the column assigned and the expression, with a
+// string assigned to a date or timestamp column parsed. The column must not be in checked.
+func resolveAssignment(schema *types.StructType, checked map[string]Expr, name string, expr Expr) (*types.StructField, Expr, error) {
+	field := lookupField(schema, name)
+	if field == nil {
+		return nil, nil, fmt.Errorf("%w: column %s not found in schema", ErrInvalidAssignment, name)
+	}
+	if _, ok := checked[field.Name]; ok {
+		return nil, nil, fmt.Errorf("%w: column %s is assigned twice", ErrInvalidAssignment, field.Name)
+	}
+	if lit, ok := expr.(*literalExpr); ok {
+		if s, ok := lit.value.(string); ok && (field.Type == types.DataTypeDate || field.Type == types.DataTypeTimestamp) {
+			v, ok := parseTime(s)
+			if !ok {
+				return nil, nil, fmt.Errorf("%w: cannot parse %q as %s for column %s", ErrInvalidAssignment, s, field.Type, field.Name)
+			}
+			expr = Lit(v)
+		}
+	}
+	return field, expr, nil
+}
+
+// assignable returns true if values of the type src can be stored in a column of the type
+// dst, possibly failing for values out of range.
+func assignable(dst, src types.DataType) bool {
+	_, _, dstDecimal := dst.Decimal()
+	_, _, srcDecimal := src.Decimal()
+	switch {
+	case src == types.DataTypeNull, dst == src:
+		return true
+	case isIntegralType(dst):
+		return isIntegralType(src)
+	case isFloatingType(dst):
+		return isIntegralType(src) || isFloatingType(src)
+	case dstDecimal:
+		return isIntegralType(src) || isFloatingType(src) || srcDecimal
+	case dst == types.DataTypeDate || dst == types.DataTypeTimestamp:
+		return src == types.DataTypeDate || src == types.DataTypeTimestamp
+	case dst == types.DataTypeBinary:
+		return src == types.DataTypeString
+	case dst == types.DataTypeBoolean || dst == types.DataTypeBool:
+		return src == types.DataTypeBoolean || src == types.DataTypeBool
+	}
+	return false
+}
+
+// rewriteFileUpdated reads the rows of the file, applies the assignments to the rows
+// matching the predicate and writes all the rows to new data files, and the updated rows
+// before and after the update to the change data writer, unless it is nil. It returns the
+// add actions of the written files, none if no row matches, and the number of updated and
+// copied rows.
+func (t *Table) rewriteFileUpdated(add *actions.Add, pred Expr, assignments map[string]Expr, changes *Writer) ([]*actions.Add, int64, int64, error) {
+	scanner := newScanner(t.Storage, t.State.CurrentMetadata, []*actions.Add{add})
+	defer scanner.Close()
+	writer, err := t.NewWriter()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	var updated, copied int64
+	for scanner.Next() {
+		row := scanner.Row()
+		ok, err := Matches(pred, row)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if ok {
+			values := make(map[string]any, len(assignments))
+			for column, expr := range assignments {
+				if values[column], err = expr.Eval(row); err != nil {
+					return nil, 0, 0, fmt.Errorf("column %s: %w", column, err)
+				}
+			}
+			if err := writeChange(changes, ChangeUpdatePreimage, row); err != nil {
+				return nil, 0, 0, err
+			}
+			for column, v := range values {
+				row[column] = v
+			}
+			if err := writeChange(changes, ChangeUpdatePostimage, row); err != nil {
+				return nil, 0, 0, err
+			}
+			updated++
+		} else {
+			copied++
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+	// the writer only writes files when closed
+	if updated == 0 {
+		return nil, 0, copied, nil
+	}
+	adds, err := writer.Close()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return adds, updated, copied, nil
+}
@@ -0,0 +1,177 @@
+package deltalake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+)
+
+// rowsByID returns the rows keyed by id.
+func rowsByID(rows []map[string]any) map[int64]map[string]any {
+	byID := make(map[int64]map[string]any, len(rows))
+	for _, row := range rows {
+		byID[row["id"].(int64)] = row
+	}
+	return byID
+}
+
+func TestTable_Update(t *testing.T) {
+	// the table has ids 0-29 in three files on 2021-01-01, 30-39 on 2021-01-02 and 40-59
+	// in two files on 2021-01-03, all named "a"
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		pred        Expr
+		assignments map[string]Expr
+		wantMetrics UpdateMetrics
+		// want returns the expected row after the update of the row with the id
+		want func(id int64, row map[string]any) map[string]any
+	}{
+		"single row": {
+			pred:        Eq(Col("id"), Lit(5)),
+			assignments: map[string]Expr{"name": Lit("b")},
+			wantMetrics: UpdateMetrics{FileMetrics: FileMetrics{NumFilesRemoved: 1, NumFilesAdded: 1}, NumUpdatedRows: 1, NumCopiedRows: 9},
+			want: func(id int64, row map[string]any) map[string]any {
+				if id == 5 {
+					row["name"] = "b"
+				}
+				return row
+			},
+		},
+		"expression": {
+			pred:        Ge(Col("id"), Lit(55)),
+			assignments: map[string]Expr{"id": Add(Col("id"), Lit(100)), "name": Lit(nil)},
+			wantMetrics: UpdateMetrics{FileMetrics: FileMetrics{NumFilesRemoved: 1, NumFilesAdded: 1}, NumUpdatedRows: 5, NumCopiedRows: 5},
+			want: func(id int64, row map[string]any) map[string]any {
+				if id >= 55 {
+					row["id"], row["name"] = id+100, nil
+				}
+				return row
+			},
+		},
+		"partition column": {
+			pred:        Eq(Col("date"), Lit("2021-01-02")),
+			assignments: map[string]Expr{"DATE": Lit("2021-01-04")},
+			wantMetrics: UpdateMetrics{FileMetrics: FileMetrics{NumFilesRemoved: 1, NumFilesAdded: 1}, NumUpdatedRows: 10},
+			want: func(id int64, row map[string]any) map[string]any {
+				if id >= 30 && id < 40 {
+					row["date"] = day.AddDate(0, 0, 3)
+				}
+				return row
+			},
+		},
+		"all rows": {
+			assignments: map[string]Expr{"name": Lit("c")},
+			wantMetrics: UpdateMetrics{FileMetrics: FileMetrics{NumFilesRemoved: 6, NumFilesAdded: 6}, NumUpdatedRows: 60},
+			want: func(id int64, row map[string]any) map[string]any {
+				row["name"] = "c"
+				return row
+			},
+		},
+		"no match": {
+			pred:        Gt(Col("id"), Lit(100)),
+			assignments: map[string]Expr{"name": Lit("b")},
+			want:        func(id int64, row map[string]any) map[string]any { return row },
+		},
+		"no match in candidate file": {
+			pred:        And(Gt(Col("id"), Lit(0)), Lt(Col("id"), Lit(1))),
+			assignments: map[string]Expr{"name": Lit("b")},
+			want:        func(id int64, row map[string]any) map[string]any { return row },
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			version := tbl.State.Version
+			want := make(map[int64]map[string]any)
+			for id, row := range rowsByID(scanAll(t, tbl)) {
+				row = test.want(id, row)
+				want[row["id"].(int64)] = row
+			}
+
+			metrics, err := tbl.Update(test.pred, test.assignments)
+			require.NoError(t, err)
+			got := *metrics
+			got.Version, got.NumBytesAdded, got.NumBytesRemoved = 0, 0, 0
+			require.Equal(t, test.wantMetrics, got)
+			require.Equal(t, want, rowsByID(scanAll(t, tbl)))
+
+			if test.wantMetrics.NumFilesRemoved == 0 {
+				require.Equal(t, version, metrics.Version)
+				require.Equal(t, version, tbl.State.Version)
+				return
+			}
+			require.Equal(t, version+1, metrics.Version)
+
+			acts, err := tbl.peakNextCommit(version)
+			require.NoError(t, err)
+			for _, action := range acts {
+				switch a := action.(type) {
+				case *actions.Add:
+					require.True(t, a.DataChange)
+				case *actions.Remove:
+					require.True(t, a.DataChange)
+				}
+			}
+			history, err := tbl.History(1)
+			require.NoError(t, err)
+			require.Equal(t, "UPDATE", history[0].CommitInfo.Operation)
+			require.Equal(t, predicateParameter(test.pred), history[0].CommitInfo.OperationParameters["predicate"])
+			require.Equal(t, metrics.operationMetrics(), history[0].CommitInfo.OperationMetrics)
+		})
+	}
+}
+
+func TestTable_UpdateInvalid(t *testing.T) {
+	tests := map[string]struct {
+		pred        Expr
+		assignments map[string]Expr
+		wantErr     error
+	}{
+		"no assignments":   {assignments: map[string]Expr{}, wantErr: ErrInvalidAssignment},
+		"unknown column":   {assignments: map[string]Expr{"missing": Lit(1)}, wantErr: ErrInvalidAssignment},
+		"assigned twice":   {assignments: map[string]Expr{"name": Lit("b"), "NAME": Lit("c")}, wantErr: ErrInvalidAssignment},
+		"string to long":   {assignments: map[string]Expr{"id": Lit("1")}, wantErr: ErrInvalidAssignment},
+		"long to string":   {assignments: map[string]Expr{"name": Col("id")}, wantErr: ErrInvalidAssignment},
+		"double to long":   {assignments: map[string]Expr{"id": Div(Col("id"), Lit(2))}, wantErr: ErrInvalidAssignment},
+		"null to required": {assignments: map[string]Expr{"id": Lit(nil)}, wantErr: ErrInvalidAssignment},
+		"invalid date":     {assignments: map[string]Expr{"date": Lit("tomorrow")}, wantErr: ErrInvalidAssignment},
+		"string operand":   {assignments: map[string]Expr{"id": Add(Col("name"), Lit(1))}, wantErr: ErrInvalidAssignment},
+		"predicate":        {pred: Add(Col("id"), Lit(1)), assignments: map[string]Expr{"name": Lit("b")}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			version := tbl.State.Version
+			_, err := tbl.Update(test.pred, test.assignments)
+			require.Error(t, err)
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+			}
+			require.Equal(t, version, tbl.State.Version)
+		})
+	}
+}
+
+func TestTable_UpdateOverflow(t *testing.T) {
+	tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+	version := tbl.State.Version
+	_, err := tbl.Update(Eq(Col("id"), Lit(1)), map[string]Expr{"id": Mul(Mul(Col("id"), Lit(int64(1)<<62)), Lit(4))})
+	require.Error(t, err)
+	require.Equal(t, version, tbl.State.Version)
+}
+
+func TestTable_UpdateUnsupportedProtocol(t *testing.T) {
+	tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+	tbl.State.MinWriterVersion = 3
+	objects, err := tbl.Storage.List("")
+	require.NoError(t, err)
+
+	_, err = tbl.Update(Eq(Col("id"), Lit(1)), map[string]Expr{"name": Lit("b")})
+	require.Error(t, err)
+	after, err := tbl.Storage.List("")
+	require.NoError(t, err)
+	require.Len(t, after, len(objects))
+}
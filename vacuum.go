@@ -0,0 +1,237 @@
+package deltalake
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultVacuumConcurrency is the number of files deleted at once by Vacuum.
+const DefaultVacuumConcurrency = 8
+
+// ErrRetentionTooShort is returned by Vacuum when the retention is shorter than the
+// retention of deleted files configured for the table, which could delete files that
+// readers of recent versions still need.
+var ErrRetentionTooShort = errors.New("retention is shorter than the deleted file retention of the table")
+
+// VacuumResult reports the files deleted by Vacuum, or the files that would be deleted
+// for a dry run.
+type VacuumResult struct {
+	// Files are the paths of the files, relative to the root of the table, sorted.
+	Files []string
+	// Bytes is the total size of the files.
+	Bytes int64
+	// DryRun is true if no files were deleted.
+	DryRun bool
+}
+
+type VacuumOption func(*vacuumOptions)
+
+type vacuumOptions struct {
+	retention      *time.Duration
+	dryRun         bool
+	retentionCheck bool
+	concurrency    int
+}
+
+// WithVacuumRetention sets how long files must have been unreferenced before they are
+// deleted. Defaults to the deleted file retention of the table.
+func WithVacuumRetention(retention time.Duration) VacuumOption {
+	return func(o *vacuumOptions) {
+		o.retention = &retention
+	}
+}
+
+// WithVacuumDryRun reports the files that would be deleted without deleting them.
+func WithVacuumDryRun() VacuumOption {
+	return func(o *vacuumOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithoutRetentionCheck allows a retention shorter than the deleted file retention of the
+// table. Readers and writers of older versions may fail once their files are deleted.
+func WithoutRetentionCheck() VacuumOption {
+	return func(o *vacuumOptions) {
+		o.retentionCheck = false
+	}
+}
+
+// WithVacuumConcurrency sets the number of files deleted at once.
+func WithVacuumConcurrency(n int) VacuumOption {
+	return func(o *vacuumOptions) {
+		o.concurrency = n
+	}
+}
+
+// Vacuum deletes the files under the root of the table that are no longer referenced by the
+// table and have not been for the retention. Files of the current version and files removed
+// more recently than the retention are kept, as are files modified more recently than the
+// retention, which may belong to a commit in progress. The delta log and other hidden
+// files and directories, whose names start with "_" or ".", are never deleted, except for
+// partition directories and the change data files, which are deleted once they are older
+// than the retention. Tables whose writer version is not supported are refused, as their
+// files may be referenced in ways this library does not know of, like deletion vectors.
+// If some files cannot be deleted, the others are still deleted and the errors are returned
+// along with the result listing all the files.
+//
+// The table must be loaded at its latest version, as the files of newer versions would be
+// deleted otherwise: tables loaded at an older version, or that other writers committed to
+// since they were loaded, are refused and must be loaded again.
+func (t *Table) Vacuum(opts ...VacuumOption) (*VacuumResult, error) {
+	options := vacuumOptions{retentionCheck: true, concurrency: DefaultVacuumConcurrency}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+	if t.State.Version < 0 || t.State.CurrentMetadata == nil {
+		return nil, errors.New("table has no metadata")
+	}
+	if !t.Config.RequireFiles || !t.Config.RequireTombstones {
+		return nil, errors.New("vacuum requires a table state loaded with files and tombstones")
+	}
+	if err := t.State.checkWriterVersion(); err != nil {
+		return nil, err
+	}
+	// files added by newer versions would not be referenced by the state
+	listing, err := t.listLog()
+	if err != nil {
+		return nil, err
+	}
+	if latest := listing.latestVersion(); t.State.Version < latest {
+		return nil, fmt.Errorf("vacuum requires the latest version %d of the table, loaded version is %d", latest, t.State.Version)
+	}
+
+	tableRetention := time.Duration(t.State.TombstoneRetentionMillis) * time.Millisecond
+	if tableRetention <= 0 {
+		tableRetention = DefaultTombstoneRetentionMillis * time.Millisecond
+	}
+	retention := tableRetention
+	if options.retention != nil {
+		retention = *options.retention
+	}
+	if options.retentionCheck && retention < tableRetention {
+		return nil, fmt.Errorf("%w: %s < %s", ErrRetentionTooShort, retention, tableRetention)
+	}
+	cutoff := time.Now().Add(-retention)
+
+	referenced, err := t.State.referencedFiles(cutoff.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	infos, err := t.Storage.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VacuumResult{DryRun: options.dryRun}
+	for _, info := range infos {
+		p := strings.TrimPrefix(info.Path, "/")
+		if isHiddenPath(p, t.State.CurrentMetadata.PartitionColumns) {
+			continue
+		}
+		if _, ok := referenced[p]; ok {
+			continue
+		}
+		if info.LastModified.After(cutoff) {
+			continue
+		}
+		result.Files = append(result.Files, p)
+		result.Bytes += info.Size
+	}
+	sort.Strings(result.Files)
+
+	log.Debug().
+		Dur("retention", retention).
+		Int("files", len(result.Files)).
+		Int64("bytes", result.Bytes).
+		Bool("dryRun", options.dryRun).
+		Msg("vacuum")
+	if options.dryRun {
+		return result, nil
+	}
+	return result, t.deleteFiles(result.Files, options.concurrency)
+}
+
+// referencedFiles returns the decoded paths of the files of the current version and of the
+// files removed after the cutoff (milliseconds since epoch).
+func (s *TableState) referencedFiles(cutoff int64) (map[string]struct{}, error) {
+	referenced := make(map[string]struct{}, len(s.Files)+len(s.Tombstones))
+	for _, add := range s.activeFiles() {
+		p, err := add.PathDecoded()
+		if err != nil {
+			return nil, err
+		}
+		referenced[p] = struct{}{}
+	}
+	for _, remove := range s.Tombstones {
+		if remove.DeletionTimestamp <= cutoff {
+			continue
+		}
+		p, err := remove.PathDecoded()
+		if err != nil {
+			return nil, err
+		}
+		referenced[p] = struct{}{}
+	}
+	return referenced, nil
+}
+
+// deleteFiles deletes the files, up to concurrency at once. All files are attempted and the
+// errors of those that could not be deleted are returned joined.
+func (t *Table) deleteFiles(paths []string, concurrency int) error {
+	errs := make([]error, len(paths))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, p := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, p string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := t.Storage.Delete(p); err != nil {
+				errs[i] = fmt.Errorf("delete %s: %w", p, err)
+			}
+		}(i, p)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// isHiddenPath returns true if a directory or the file of the path, relative to the root of
+// the table, starts with "_" or ".", like the delta log or the checksum files of writers.
+// Like Spark, the change data directory and partition directories, which may start with
+// "_", are not hidden.
+func isHiddenPath(p string, partitionColumns []string) bool {
+	for _, name := range strings.Split(path.Clean(p), "/") {
+		if !strings.HasPrefix(name, "_") && !strings.HasPrefix(name, ".") {
+			continue
+		}
+		if name == changeDataDir || isPartitionDir(name, partitionColumns) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// isPartitionDir returns true if the name is of the form column=value for a partition
+// column.
+func isPartitionDir(name string, partitionColumns []string) bool {
+	for _, column := range partitionColumns {
+		if strings.HasPrefix(name, column+"=") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package deltalake
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/storage"
+)
+
+// writeVacuumTestTable creates a table with two live files and a removed file, all last
+// modified a month ago, an orphaned file from a month ago and an orphaned file from now.
+func writeVacuumTestTable(t *testing.T) (*Table, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := storage.NewLocalStorage(dir)
+	require.NoError(t, err)
+	tbl := writePartitionedTestTable(t, store, 1, []int{1, 1, 1}, removeTestCommit)
+
+	old := time.Now().AddDate(0, -1, 0)
+	files := map[string]time.Time{
+		"date=2021-01-01/orphan-old.parquet": old,
+		"orphan-new.parquet":                 time.Now(),
+		"_hidden/old.parquet":                old,
+	}
+	for _, add := range tbl.State.Files {
+		p, err := add.PathDecoded()
+		require.NoError(t, err)
+		files[p] = old
+	}
+	touchTestFiles(t, dir, files)
+	return tbl, dir
+}
+
+// touchTestFiles sets the modification times of the files under the directory, creating
+// those that do not exist.
+func touchTestFiles(t *testing.T, dir string, files map[string]time.Time) {
+	t.Helper()
+	for name, modified := range files {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+			require.NoError(t, os.WriteFile(p, []byte("data"), 0o644))
+		}
+		require.NoError(t, os.Chtimes(p, modified, modified))
+	}
+}
+
+func TestTable_Vacuum(t *testing.T) {
+	tests := map[string]struct {
+		opts      []VacuumOption
+		wantFiles func(tbl *Table) []string
+		wantErr   error
+	}{
+		"default retention": {
+			wantFiles: func(*Table) []string { return []string{"date=2021-01-01/orphan-old.parquet"} },
+		},
+		"zero retention": {
+			opts: []VacuumOption{WithVacuumRetention(0), WithoutRetentionCheck()},
+			wantFiles: func(tbl *Table) []string {
+				var removed string
+				for p := range tbl.State.Tombstones {
+					removed = p
+				}
+				return []string{removed, "date=2021-01-01/orphan-old.parquet", "orphan-new.parquet"}
+			},
+		},
+		"retention check": {
+			opts:    []VacuumOption{WithVacuumRetention(time.Hour)},
+			wantErr: ErrRetentionTooShort,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl, dir := writeVacuumTestTable(t)
+
+			dryRun, err := tbl.Vacuum(append(test.opts, WithVacuumDryRun())...)
+			if test.wantErr != nil {
+				require.True(t, errors.Is(err, test.wantErr), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			want := test.wantFiles(tbl)
+			require.ElementsMatch(t, want, dryRun.Files)
+			require.True(t, dryRun.DryRun)
+			for _, p := range want {
+				require.FileExists(t, filepath.Join(dir, p))
+			}
+
+			result, err := tbl.Vacuum(test.opts...)
+			require.NoError(t, err)
+			require.Equal(t, dryRun.Files, result.Files)
+			require.Equal(t, dryRun.Bytes, result.Bytes)
+			require.False(t, result.DryRun)
+			for _, p := range want {
+				require.NoFileExists(t, filepath.Join(dir, p))
+			}
+
+			// the table is still readable and hidden files are kept
+			require.FileExists(t, filepath.Join(dir, "_hidden/old.parquet"))
+			loaded, err := LoadTable(tbl.Storage, nil)
+			require.NoError(t, err)
+			for _, add := range loaded.State.activeFiles() {
+				p, err := add.PathDecoded()
+				require.NoError(t, err)
+				require.FileExists(t, filepath.Join(dir, p))
+			}
+		})
+	}
+}
+
+func TestTable_VacuumTableRetention(t *testing.T) {
+	tbl, _ := writeVacuumTestTable(t)
+	tbl.State.TombstoneRetentionMillis = (2 * time.Hour).Milliseconds()
+
+	_, err := tbl.Vacuum(WithVacuumRetention(time.Hour), WithVacuumDryRun())
+	require.ErrorIs(t, err, ErrRetentionTooShort)
+	result, err := tbl.Vacuum(WithVacuumRetention(3*time.Hour), WithVacuumDryRun())
+	require.NoError(t, err)
+	require.Equal(t, []string{"date=2021-01-01/orphan-old.parquet"}, result.Files)
+}
+
+func TestTable_VacuumOldVersion(t *testing.T) {
+	tbl, dir := writeVacuumTestTable(t)
+	old, err := LoadTableAtVersion(tbl.Storage, nil, 1)
+	require.NoError(t, err)
+	_, err = old.Vacuum(WithVacuumRetention(0), WithoutRetentionCheck())
+	require.Error(t, err)
+
+	// a table that another writer committed to since it was loaded is stale too
+	other, err := LoadTable(tbl.Storage, nil)
+	require.NoError(t, err)
+	_, err = other.NewTransaction().Commit()
+	require.NoError(t, err)
+	_, err = tbl.Vacuum(WithVacuumRetention(0), WithoutRetentionCheck())
+	require.Error(t, err)
+
+	for _, add := range other.State.activeFiles() {
+		p, err := add.PathDecoded()
+		require.NoError(t, err)
+		require.FileExists(t, filepath.Join(dir, p))
+	}
+}
+
+func TestTable_VacuumChangeData(t *testing.T) {
+	tbl, dir := writeVacuumTestTable(t)
+	old := time.Now().AddDate(0, -1, 0)
+	touchTestFiles(t, dir, map[string]time.Time{
+		"_change_data/old.parquet":        old,
+		"_change_data/new.parquet":        time.Now(),
+		"_change_data/_hidden/old.crc":    old,
+		"_date=2021-01-01/orphan.parquet": old,
+	})
+
+	result, err := tbl.Vacuum(WithVacuumDryRun())
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"_change_data/old.parquet",
+		"date=2021-01-01/orphan-old.parquet",
+	}, result.Files)
+}
+
+func TestIsHiddenPath(t *testing.T) {
+	partitionColumns := []string{"_p"}
+	require.True(t, isHiddenPath("_delta_log/00000000000000000000.json", partitionColumns))
+	require.True(t, isHiddenPath("_p=1/.part-0.parquet.crc", partitionColumns))
+	require.True(t, isHiddenPath("_q=1/part-0.parquet", partitionColumns))
+	require.False(t, isHiddenPath("_p=1/part-0.parquet", partitionColumns))
+	require.False(t, isHiddenPath("_change_data/cdc-0.parquet", partitionColumns))
+}
+
+func TestTable_VacuumUnsupportedProtocol(t *testing.T) {
+	tbl, _ := writeVacuumTestTable(t)
+	tbl.State.MinReaderVersion, tbl.State.MinWriterVersion = 3, 7
+
+	_, err := tbl.Vacuum(WithVacuumDryRun())
+	require.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package deltalake
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog/log"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+// ErrWriterClosed is returned when writing to a closed Writer.
+var ErrWriterClosed = errors.New("writer is closed")
+
+// Writer writes records to snappy compressed parquet data files of a table. One file
+// is written per partition every time the writer is flushed. The add actions of the
+// written files, with the statistics of their records, are returned by Close and must be
+// committed to the table with a Transaction for the data to become visible.
+type Writer struct {
+	storage          storage.ObjectStorage
+	schema           *types.StructType
+	partitionColumns []string
+	dataFields       []*types.StructField
+	dataSchema       *parquet.Schema
+	encoder          *rowEncoder
+	dataChange       bool
+	dir              string // directory of the files, relative to the root of the table
+
+	partitions map[string]*partitionWriter
+	order      []string // partition paths in the order they were first written
+	adds       []*actions.Add
+	part       int
+	closed     bool
+}
+
+type WriterOption func(*Writer)
+
+// WithDataChange sets the dataChange flag of the add actions. Defaults to true; use false
+// when rewriting data that is already in the table.
+func WithDataChange(dataChange bool) WriterOption {
+	return func(w *Writer) {
+		w.dataChange = dataChange
+	}
+}
+
+// withDirectory writes the files to the directory instead of the root of the table.
+func withDirectory(dir string) WriterOption {
+	return func(w *Writer) {
+		w.dir = dir
+	}
+}
+
+// partitionWriter buffers the data file of a single partition.
+type partitionWriter struct {
+	path   string
+	values map[string]string
+	buf    *bytes.Buffer
+	writer *parquet.Writer
+	stats  *statsCollector
+}
+
+// NewWriter returns a Writer for records of the table described by the metadata.
+func NewWriter(storage storage.ObjectStorage, metadata *TableMetadata, opts ...WriterOption) (*Writer, error) {
+	if metadata == nil {
+		return nil, errors.New("metadata is required")
+	}
+
+	var dataFields []*types.StructField
+	for _, field := range metadata.Schema.Fields {
+		if !isPartitionColumn(metadata.PartitionColumns, field.Name) {
+			dataFields = append(dataFields, field)
+		}
+	}
+	dataSchema, err := parquetSchema(dataFields)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		storage:          storage,
+		schema:           &metadata.Schema,
+		partitionColumns: metadata.PartitionColumns,
+		dataFields:       dataFields,
+		dataSchema:       dataSchema,
+		encoder:          newRowEncoder(dataSchema),
+		dataChange:       true,
+		partitions:       make(map[string]*partitionWriter),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w, nil
+}
+
+// NewWriter returns a Writer for records of the current version of the table.
+func (t *Table) NewWriter(opts ...WriterOption) (*Writer, error) {
+	if t.State.CurrentMetadata == nil {
+		return nil, errors.New("table has no metadata")
+	}
+	return NewWriter(t.Storage, t.State.CurrentMetadata, opts...)
+}
+
+// Write validates the records against the schema of the table and buffers them.
+// A record is a map[string]any keyed by column name or a struct whose fields are
+// mapped to columns by name or by their `delta` tag.
+func (w *Writer) Write(records ...any) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	for _, record := range records {
+		values, err := normalizeRecord(w.schema, record)
+		if err != nil {
+			return err
+		}
+		partitionValues := make(map[string]string, len(w.partitionColumns))
+		for _, column := range w.partitionColumns {
+			field, err := w.schema.GetFieldByName(column)
+			if err != nil {
+				return err
+			}
+			if partitionValues[column], err = formatPartitionValue(field.Type, values[field.Name]); err != nil {
+				return fmt.Errorf("partition column %s: %w", column, err)
+			}
+			delete(values, field.Name)
+		}
+
+		pw := w.partition(partitionValues)
+		row, err := w.encoder.encode(values)
+		if err != nil {
+			return err
+		}
+		if _, err := pw.writer.WriteRows([]parquet.Row{row}); err != nil {
+			return err
+		}
+		pw.stats.add(values)
+	}
+	return nil
+}
+
+// partition returns the writer of the partition, creating it if needed.
+func (w *Writer) partition(values map[string]string) *partitionWriter {
+	dir := partitionPath(w.partitionColumns, values)
+	if pw, ok := w.partitions[dir]; ok {
+		return pw
+	}
+	buf := new(bytes.Buffer)
+	pw := &partitionWriter{
+		path:   dir,
+		values: values,
+		buf:    buf,
+		writer: parquet.NewWriter(buf, w.dataSchema, parquet.Compression(&parquet.Snappy)),
+		stats:  newStatsCollector(w.dataFields),
+	}
+	w.partitions[dir] = pw
+	w.order = append(w.order, dir)
+	return pw
+}
+
+// Flush writes the buffered records to one data file per partition.
+func (w *Writer) Flush() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	for _, dir := range w.order {
+		pw := w.partitions[dir]
+		if err := pw.writer.Close(); err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("part-%05d-%s-c000.snappy.parquet", w.part, uuid.New())
+		filePath := path.Join(w.dir, pw.path, name)
+		size := int64(pw.buf.Len())
+		if err := w.storage.Put(filePath, pw.buf); err != nil {
+			return err
+		}
+		log.Debug().Str("path", filePath).Int64("rows", pw.stats.numRecords).Int64("size", size).Msg("wrote data file")
+
+		w.adds = append(w.adds, actions.NewAdd(
+			encodePath(filePath),
+			size,
+			pw.values,
+			w.dataChange,
+			time.Now().UnixMilli(),
+			pw.stats.stats(),
+			nil,
+		))
+		w.part++
+	}
+	w.partitions = make(map[string]*partitionWriter)
+	w.order = nil
+	return nil
+}
+
+// Close flushes the buffered records and returns the add actions of all the data
+// files written by the writer.
+func (w *Writer) Close() ([]*actions.Add, error) {
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	w.closed = true
+	return w.adds, nil
+}
+
+func isPartitionColumn(partitionColumns []string, name string) bool {
+	for _, column := range partitionColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,292 @@
+package deltalake
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+	"deltalake/storage"
+	"deltalake/types"
+)
+
+// readDataFile reads the rows of a data file of the table.
+func readDataFile[T any](t *testing.T, store storage.ObjectStorage, add *actions.Add) []T {
+	t.Helper()
+	path, err := url.PathUnescape(add.Path)
+	require.NoError(t, err)
+	obj, err := store.Get(path)
+	require.NoError(t, err)
+	defer obj.Close()
+	data, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	require.Equal(t, add.Size, int64(len(data)))
+	rows, err := parquet.Read[T](bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	return rows
+}
+
+func TestWriter(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	tbl, err := CreateTable(store, NewTableMetadata("test", "", actions.DefaultFormat, testSchema(), []string{"date"}, nil))
+	require.NoError(t, err)
+
+	type record struct {
+		ID      int64     `delta:"id"`
+		Name    *string   `delta:"name"`
+		Date    time.Time `delta:"date"`
+		Ignored string    `delta:"-"`
+	}
+	name := "a"
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	w, err := tbl.NewWriter()
+	require.NoError(t, err)
+	require.NoError(t, w.Write(
+		record{ID: 1, Name: &name, Date: day},
+		&record{ID: 2, Date: day},
+		map[string]any{"id": 3, "name": "c", "date": day.AddDate(0, 0, 1)},
+		map[string]any{"id": int32(4)},
+	))
+	adds, err := w.Close()
+	require.NoError(t, err)
+	require.Len(t, adds, 3)
+
+	partitions := make(map[string]*actions.Add)
+	for _, add := range adds {
+		require.True(t, add.DataChange)
+		require.NotZero(t, add.ModificationTime)
+		require.Regexp(t, `part-\d{5}-[0-9a-f-]{36}-c000\.snappy\.parquet$`, add.Path)
+		partitions[add.PartitionValues["date"]] = add
+	}
+	require.Contains(t, partitions, "2021-01-01")
+	require.Contains(t, partitions, "2021-01-02")
+	require.Contains(t, partitions, "")
+	require.True(t, strings.HasPrefix(partitions["2021-01-01"].Path, "date=2021-01-01/"))
+	require.True(t, strings.HasPrefix(partitions[""].Path, "date=__HIVE_DEFAULT_PARTITION__/"))
+
+	type row struct {
+		ID   int64   `parquet:"id"`
+		Name *string `parquet:"name,optional"`
+	}
+	rows := readDataFile[row](t, store, partitions["2021-01-01"])
+	require.Len(t, rows, 2)
+	require.Equal(t, int64(1), rows[0].ID)
+	require.Equal(t, "a", *rows[0].Name)
+	require.Equal(t, int64(2), rows[1].ID)
+	require.Nil(t, rows[1].Name)
+
+	tx := tbl.NewTransaction()
+	for _, add := range adds {
+		tx.AddAction(add)
+	}
+	version, err := tx.Commit()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), version)
+
+	loaded, err := LoadTable(store, nil)
+	require.NoError(t, err)
+	require.Len(t, loaded.State.Files, 3)
+}
+
+func TestWriter_ComplexTypes(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	schema := *types.NewStruct(
+		types.NewStructField("id", types.DataTypeLong, false, nil),
+		types.NewStructField("tags", types.NewArrayType(types.DataTypeString, true), true, nil),
+		types.NewStructField("counts", types.NewMapType(types.DataTypeString, types.DataTypeLong, true), true, nil),
+		types.NewStructField("point", types.NewStruct(
+			types.NewStructField("x", types.DataTypeDouble, false, nil),
+			types.NewStructField("y", types.DataTypeDouble, false, nil),
+		), true, nil),
+		types.NewStructField("ts", types.DataTypeTimestamp, true, nil),
+	)
+	tbl, err := CreateTable(store, &TableMetadata{Schema: schema})
+	require.NoError(t, err)
+
+	ts := time.Date(2021, 1, 1, 12, 30, 0, 123456789, time.UTC)
+	w, err := tbl.NewWriter(WithDataChange(false))
+	require.NoError(t, err)
+	require.NoError(t, w.Write(
+		map[string]any{
+			"id":     1,
+			"tags":   []string{"a", "b"},
+			"counts": map[string]int64{"x": 1, "y": 2},
+			"point":  map[string]any{"x": 1.5, "y": 2},
+			"ts":     ts,
+		},
+		map[string]any{"id": 2, "tags": []string{}},
+	))
+	adds, err := w.Close()
+	require.NoError(t, err)
+	require.Len(t, adds, 1)
+	require.False(t, adds[0].DataChange)
+	require.Empty(t, adds[0].PartitionValues)
+
+	type point struct {
+		X float64 `parquet:"x"`
+		Y float64 `parquet:"y"`
+	}
+	type row struct {
+		ID     int64            `parquet:"id"`
+		Tags   []string         `parquet:"tags,optional,list"`
+		Counts map[string]int64 `parquet:"counts,optional"`
+		Point  *point           `parquet:"point,optional"`
+		TS     *int64           `parquet:"ts,optional"`
+	}
+	rows := readDataFile[row](t, store, adds[0])
+	require.Len(t, rows, 2)
+	require.Equal(t, []string{"a", "b"}, rows[0].Tags)
+	require.Equal(t, map[string]int64{"x": 1, "y": 2}, rows[0].Counts)
+	require.Equal(t, &point{X: 1.5, Y: 2}, rows[0].Point)
+	require.Equal(t, ts.UnixMicro(), *rows[0].TS)
+	require.Empty(t, rows[1].Tags)
+	require.Nil(t, rows[1].Point)
+	require.Nil(t, rows[1].TS)
+}
+
+func TestWriter_Decimals(t *testing.T) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	schema := *types.NewStruct(
+		types.NewStructField("id", types.DataTypeLong, false, nil),
+		types.NewStructField("small", types.DecimalType(9, 2), true, nil),
+		types.NewStructField("medium", types.DecimalType(18, 4), true, nil),
+		types.NewStructField("large", types.DecimalType(38, 10), true, nil),
+	)
+	tbl, err := CreateTable(store, &TableMetadata{Schema: schema})
+	require.NoError(t, err)
+
+	w, err := tbl.NewWriter()
+	require.NoError(t, err)
+	require.NoError(t, w.Write(
+		map[string]any{"id": 1, "small": "1234567.89", "medium": "-12345678901234.5678", "large": "-1234567890123456789012345678.0123456789"},
+		map[string]any{"id": 2, "small": -0.5, "medium": 1, "large": "0.0000000001"},
+		map[string]any{"id": 3},
+	))
+	adds, err := w.Close()
+	require.NoError(t, err)
+
+	// the physical types follow the precision like Spark
+	path, err := adds[0].PathDecoded()
+	require.NoError(t, err)
+	obj, err := storage.OpenReaderAt(store, path)
+	require.NoError(t, err)
+	defer obj.Close()
+	file, err := parquet.OpenFile(obj, obj.Size())
+	require.NoError(t, err)
+	for column, kind := range map[string]parquet.Kind{"small": parquet.Int32, "medium": parquet.Int64, "large": parquet.FixedLenByteArray} {
+		leaf, ok := file.Schema().Lookup(column)
+		require.True(t, ok)
+		require.Equal(t, kind, leaf.Node.Type().Kind(), column)
+		require.NotNil(t, leaf.Node.Type().LogicalType().Decimal, column)
+	}
+	large, _ := file.Schema().Lookup("large")
+	require.Equal(t, 16, large.Node.Type().Length())
+
+	tx := tbl.NewTransaction()
+	tx.AddActions(toActions(adds)...)
+	_, err = tx.Commit()
+	require.NoError(t, err)
+	rat := func(s string) *big.Rat {
+		r, ok := new(big.Rat).SetString(s)
+		require.True(t, ok)
+		return r
+	}
+	want := map[int64][]*big.Rat{
+		1: {rat("1234567.89"), rat("-12345678901234.5678"), rat("-1234567890123456789012345678.0123456789")},
+		2: {rat("-0.5"), rat("1"), rat("0.0000000001")},
+	}
+	rows := scanAll(t, tbl)
+	require.Len(t, rows, 3)
+	for _, row := range rows {
+		id := row["id"].(int64)
+		if id == 3 {
+			require.Nil(t, row["small"])
+			require.Nil(t, row["large"])
+			continue
+		}
+		for i, column := range []string{"small", "medium", "large"} {
+			require.Zero(t, want[id][i].Cmp(row[column].(*big.Rat)), "%d %s: %v", id, column, row[column])
+		}
+	}
+
+	// the stats are decimals, so that files can be skipped
+	files, err := tbl.State.FilesMatching(Lt(Col("large"), Lit(rat("-1"))))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	files, err = tbl.State.FilesMatching(Gt(Col("small"), Lit(rat("1234567.89"))))
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestWriter_InvalidRecords(t *testing.T) {
+	tests := map[string]struct {
+		record any
+	}{
+		"unknown column":       {record: map[string]any{"id": 1, "missing": 1}},
+		"missing non-nullable": {record: map[string]any{"name": "a"}},
+		"wrong type":           {record: map[string]any{"id": "a"}},
+		"out of range":         {record: map[string]any{"id": uint64(1 << 63)}},
+		"unsupported record":   {record: 1},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			store, err := storage.NewLocalStorage(t.TempDir())
+			require.NoError(t, err)
+			w, err := NewWriter(store, &TableMetadata{Schema: testSchema()})
+			require.NoError(t, err)
+			require.Error(t, w.Write(test.record))
+		})
+	}
+}
+
+func TestPartitionPath(t *testing.T) {
+	tests := map[string]struct {
+		columns []string
+		values  map[string]string
+		want    string
+	}{
+		"none":    {want: ""},
+		"single":  {columns: []string{"date"}, values: map[string]string{"date": "2021-01-01"}, want: "date=2021-01-01"},
+		"null":    {columns: []string{"a", "b"}, values: map[string]string{"a": "x", "b": ""}, want: "a=x/b=__HIVE_DEFAULT_PARTITION__"},
+		"escaped": {columns: []string{"ts"}, values: map[string]string{"ts": "2021-01-01 10:00:00"}, want: "ts=2021-01-01 10%3A00%3A00"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.want, partitionPath(test.columns, test.values))
+		})
+	}
+}
+
+func TestFormatPartitionValue(t *testing.T) {
+	tests := map[string]struct {
+		dt    types.DataType
+		value any
+		want  string
+	}{
+		"null":      {dt: types.DataTypeString, value: nil, want: ""},
+		"string":    {dt: types.DataTypeString, value: "a", want: "a"},
+		"long":      {dt: types.DataTypeLong, value: int64(-1), want: "-1"},
+		"boolean":   {dt: types.DataTypeBoolean, value: true, want: "true"},
+		"double":    {dt: types.DataTypeDouble, value: 1.5, want: "1.5"},
+		"date":      {dt: types.DataTypeDate, value: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), want: "2021-01-02"},
+		"timestamp": {dt: types.DataTypeTimestamp, value: time.Date(2021, 1, 2, 3, 4, 5, 6000, time.UTC), want: "2021-01-02 03:04:05.000006"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := formatPartitionValue(test.dt, test.value)
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
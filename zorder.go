@@ -0,0 +1,265 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"deltalake/actions"
+	"deltalake/types"
+)
+
+// Tags of the files written by a z-order, like Spark: the files written from the same bin
+// share a cube id and record the columns they are sorted by.
+const (
+	zCubeIDTag       = "ZCUBE_ID"
+	zCubeZOrderByTag = "ZCUBE_ZORDER_BY"
+)
+
+// ErrInvalidZOrderColumn is returned by Optimize when a z-order column is not a primitive
+// data column of the table.
+var ErrInvalidZOrderColumn = errors.New("invalid z-order column")
+
+// validateZOrderColumns checks that the columns are primitive columns of the schema that
+// are not partition columns, which are the same for all rows of a partition, and returns
+// their names in the schema.
+func validateZOrderColumns(metadata *TableMetadata, columns []string) ([]string, error) {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		field := lookupField(&metadata.Schema, column)
+		switch {
+		case field == nil:
+			return nil, fmt.Errorf("%w: %s not found in schema", ErrInvalidZOrderColumn, column)
+		case isPartitionColumn(metadata.PartitionColumns, field.Name):
+			return nil, fmt.Errorf("%w: %s is a partition column", ErrInvalidZOrderColumn, column)
+		case !types.IsPrimitiveType(field.Type):
+			return nil, fmt.Errorf("%w: %s is not of a primitive type", ErrInvalidZOrderColumn, column)
+		}
+		names[i] = field.Name
+	}
+	return names, nil
+}
+
+// zOrderBy returns the z-order columns as a JSON array, as recorded in the OPTIMIZE
+// commitInfo and in the tags of the files.
+func zOrderBy(columns []string) string {
+	data, _ := json.Marshal(append([]string{}, columns...))
+	return string(data)
+}
+
+// zOrderBins groups the files by partition, as z-ordering sorts all the rows of a
+// partition together. Partitions larger than maxBinSize, if positive, are split into bins
+// of up to maxBinSize that are sorted separately, keeping the files of a cube together
+// where possible. Partitions of a single file are rewritten too, as the file may not be
+// sorted, but bins whose files were all written by the same z-order of the columns are
+// left as they are.
+func zOrderBins(partitionColumns []string, files []*actions.Add, columns []string, maxBinSize int64) [][]*actions.Add {
+	partitions := make(map[string][]*actions.Add)
+	var order []string
+	for _, add := range files {
+		dir := partitionPath(partitionColumns, add.PartitionValues)
+		if _, ok := partitions[dir]; !ok {
+			order = append(order, dir)
+		}
+		partitions[dir] = append(partitions[dir], add)
+	}
+	sort.Strings(order)
+
+	orderBy := zOrderBy(columns)
+	var bins [][]*actions.Add
+	addBin := func(bin []*actions.Add) {
+		if len(bin) > 0 && !isZCube(bin, orderBy) {
+			bins = append(bins, bin)
+		}
+	}
+	for _, dir := range order {
+		candidates := partitions[dir]
+		if maxBinSize <= 0 {
+			addBin(candidates)
+			continue
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Tags[zCubeIDTag] < candidates[j].Tags[zCubeIDTag]
+		})
+		var bin []*actions.Add
+		var size int64
+		for _, add := range candidates {
+			if len(bin) > 0 && size+add.Size > maxBinSize {
+				addBin(bin)
+				bin, size = nil, 0
+			}
+			bin = append(bin, add)
+			size += add.Size
+		}
+		addBin(bin)
+	}
+	return bins
+}
+
+// isZCube returns true if the files were all written from the same bin by a z-order of the
+// columns.
+func isZCube(files []*actions.Add, orderBy string) bool {
+	id := files[0].Tags[zCubeIDTag]
+	if id == "" {
+		return false
+	}
+	for _, add := range files {
+		if add.Tags[zCubeIDTag] != id || add.Tags[zCubeZOrderByTag] != orderBy {
+			return false
+		}
+	}
+	return true
+}
+
+// sortByZOrder sorts the rows by the z-order of the columns. The values of each column
+// are replaced by their rank among the rows, scaled to the same number of bits so that
+// all columns weigh the same, and the bits of the ranks are interleaved into a single
+// key. Nulls rank first.
+func sortByZOrder(rows []map[string]any, columns []string) {
+	if len(rows) == 0 || len(columns) == 0 {
+		return
+	}
+	bits := 64 / len(columns)
+	if bits > 32 {
+		bits = 32
+	}
+	ranks := make([][]uint64, len(columns))
+	for i, column := range columns {
+		ranks[i] = zOrderRanks(rows, column, bits)
+	}
+
+	keys := make([]uint64, len(rows))
+	values := make([]uint64, len(columns))
+	for j := range rows {
+		for i := range columns {
+			values[i] = ranks[i][j]
+		}
+		keys[j] = interleaveBits(values, bits)
+	}
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return keys[order[a]] < keys[order[b]] })
+
+	sorted := make([]map[string]any, len(rows))
+	for i, j := range order {
+		sorted[i] = rows[j]
+	}
+	copy(rows, sorted)
+}
+
+// zOrderRanks returns the rank of the value of the column of each row, scaled to bits.
+// Equal values have the same rank.
+func zOrderRanks(rows []map[string]any, column string, bits int) []uint64 {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	compare := func(a, b any) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		case b == nil:
+			return 1
+		}
+		c, _ := compareValues(a, b)
+		return c
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return compare(rows[order[a]][column], rows[order[b]][column]) < 0
+	})
+
+	// distinct values are ranked densely and then spread over the range of the bits
+	dense := make([]uint64, len(rows))
+	var rank uint64
+	for i, j := range order {
+		if i > 0 && compare(rows[order[i-1]][column], rows[j][column]) != 0 {
+			rank++
+		}
+		dense[j] = rank
+	}
+	if rank == 0 {
+		return dense
+	}
+	limit := uint64(1)<<bits - 1
+	for j, r := range dense {
+		if rank <= limit {
+			dense[j] = r * (limit / rank)
+		} else {
+			dense[j] = uint64(float64(r) / float64(rank) * float64(limit))
+		}
+	}
+	return dense
+}
+
+// interleaveBits interleaves the lowest bits of the values, most significant bits first.
+func interleaveBits(values []uint64, bits int) uint64 {
+	var key uint64
+	for b := bits - 1; b >= 0; b-- {
+		for _, v := range values {
+			key = key<<1 | (v>>b)&1
+		}
+	}
+	return key
+}
+
+// rewriteFilesZOrder writes the rows of the files, sorted by the z-order of the columns, to
+// new data files of about the target size, flagged as not changing data and tagged as a
+// cube of the columns, and returns their add actions. All the rows of the files are held
+// in memory.
+func (t *Table) rewriteFilesZOrder(files []*actions.Add, columns []string, targetSize int64) ([]*actions.Add, error) {
+	scanner := newScanner(t.Storage, t.State.CurrentMetadata, files)
+	defer scanner.Close()
+	var rows []map[string]any
+	for scanner.Next() {
+		rows = append(rows, scanner.Row())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sortByZOrder(rows, columns)
+
+	var size int64
+	for _, add := range files {
+		size += add.Size
+	}
+	numFiles := int64(1)
+	if targetSize > 0 && size > targetSize {
+		numFiles = (size + targetSize - 1) / targetSize
+	}
+	rowsPerFile := (int64(len(rows)) + numFiles - 1) / numFiles
+
+	writer, err := t.NewWriter(WithDataChange(false))
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		if err := writer.Write(row); err != nil {
+			return nil, err
+		}
+		if rowsPerFile > 0 && int64(i+1)%rowsPerFile == 0 && i+1 < len(rows) {
+			if err := writer.Flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	adds, err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	id, orderBy := uuid.New().String(), zOrderBy(columns)
+	for _, add := range adds {
+		if add.Tags == nil {
+			add.Tags = make(map[string]string)
+		}
+		add.Tags[zCubeIDTag] = id
+		add.Tags[zCubeZOrderByTag] = orderBy
+	}
+	return adds, nil
+}
@@ -0,0 +1,158 @@
+package deltalake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"deltalake/actions"
+	"deltalake/types"
+)
+
+func TestTable_OptimizeZOrder(t *testing.T) {
+	// the points of a 16x16 grid, written in four files that each cover the whole grid
+	schema := *types.NewStruct(
+		types.NewStructField("lat", types.DataTypeLong, false, nil),
+		types.NewStructField("lon", types.DataTypeLong, false, nil),
+		types.NewStructField("name", types.DataTypeString, true, nil),
+	)
+	commits := make([]testCommit, 4)
+	for lat := 0; lat < 16; lat++ {
+		for lon := 0; lon < 16; lon++ {
+			file := (lat + lon) % 4
+			commits[file].rows = append(commits[file].rows, map[string]any{"lat": lat, "lon": lon, "name": "p"})
+		}
+	}
+	tbl := writeTestTable(t, nil, NewTableMetadata("geo", "", actions.DefaultFormat, schema, nil, nil), commits...)
+	var size int64
+	for _, add := range tbl.State.activeFiles() {
+		size += add.Size
+	}
+	rows := len(scanAll(t, tbl))
+
+	filters := map[string]struct {
+		pred Expr
+		want int
+	}{
+		"lat":     {pred: Lt(Col("lat"), Lit(int64(8))), want: 2},
+		"lon":     {pred: Ge(Col("lon"), Lit(int64(8))), want: 2},
+		"lat&lon": {pred: And(Lt(Col("lat"), Lit(int64(8))), Lt(Col("lon"), Lit(int64(8)))), want: 1},
+	}
+	for _, filter := range filters {
+		files, err := tbl.State.FilesMatching(filter.pred)
+		require.NoError(t, err)
+		require.Len(t, files, 4)
+	}
+
+	metrics, err := tbl.Optimize(WithZOrder("lat", "lon"), WithTargetFileSize((size+3)/4))
+	require.NoError(t, err)
+	require.Equal(t, int64(4), metrics.NumFilesRemoved)
+	require.Equal(t, int64(4), metrics.NumFilesAdded)
+	require.Equal(t, int64(1), metrics.NumBins)
+	require.Len(t, scanAll(t, tbl), rows)
+
+	// each file holds a quadrant of the grid
+	for name, filter := range filters {
+		files, err := tbl.State.FilesMatching(filter.pred)
+		require.NoError(t, err)
+		require.Len(t, files, filter.want, name)
+	}
+
+	history, err := tbl.History(1)
+	require.NoError(t, err)
+	require.Equal(t, "OPTIMIZE", history[0].CommitInfo.Operation)
+	require.Equal(t, `["lat","lon"]`, history[0].CommitInfo.OperationParameters["zOrderBy"])
+}
+
+func TestTable_OptimizeZOrderPartitions(t *testing.T) {
+	tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+	before := sortedIDs(scanAll(t, tbl))
+
+	// partitions of a single file are rewritten too
+	metrics, err := tbl.Optimize(WithZOrder("id", "name"))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), metrics.NumBins)
+	require.Equal(t, int64(3), metrics.PartitionsOptimized)
+	require.Equal(t, int64(6), metrics.NumFilesRemoved)
+	require.Equal(t, int64(3), metrics.NumFilesAdded)
+	require.Zero(t, metrics.TotalFilesSkipped)
+	require.Equal(t, before, sortedIDs(scanAll(t, tbl)))
+
+	// partitions already z-ordered by the columns are not rewritten
+	version := tbl.State.Version
+	metrics, err = tbl.Optimize(WithZOrder("ID", "name"))
+	require.NoError(t, err)
+	require.Zero(t, metrics.NumBins)
+	require.Equal(t, int64(3), metrics.TotalFilesSkipped)
+	require.Equal(t, version, tbl.State.Version)
+
+	metrics, err = tbl.Optimize(WithZOrder("name", "id"))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), metrics.NumBins)
+	require.Equal(t, before, sortedIDs(scanAll(t, tbl)))
+}
+
+func TestTable_OptimizeZOrderMaxBinSize(t *testing.T) {
+	tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+	before := sortedIDs(scanAll(t, tbl))
+	var maxSize int64
+	for _, add := range tbl.State.activeFiles() {
+		maxSize = max(maxSize, add.Size)
+	}
+
+	// the partition of three files is split into bins of two files and one file
+	metrics, err := tbl.Optimize(WithZOrder("id", "name"), WithMaxZOrderBinSize(2*maxSize))
+	require.NoError(t, err)
+	require.Equal(t, int64(4), metrics.NumBins)
+	require.Equal(t, int64(3), metrics.PartitionsOptimized)
+	require.Equal(t, int64(6), metrics.NumFilesRemoved)
+	require.Equal(t, int64(4), metrics.NumFilesAdded)
+	require.Equal(t, before, sortedIDs(scanAll(t, tbl)))
+}
+
+func TestTable_OptimizeZOrderInvalidColumn(t *testing.T) {
+	tests := map[string]string{
+		"unknown":   "lat",
+		"partition": "date",
+	}
+	for name, column := range tests {
+		t.Run(name, func(t *testing.T) {
+			tbl := writePartitionedTestTable(t, nil, 10, []int{3, 1, 2})
+			_, err := tbl.Optimize(WithZOrder("id", column))
+			require.ErrorIs(t, err, ErrInvalidZOrderColumn)
+		})
+	}
+}
+
+func TestSortByZOrder(t *testing.T) {
+	var rows []map[string]any
+	for _, p := range [][2]any{{int64(1), "b"}, {int64(0), "a"}, {int64(1), "a"}, {int64(0), "b"}, {nil, "a"}} {
+		rows = append(rows, map[string]any{"x": p[0], "y": p[1]})
+	}
+	sortByZOrder(rows, []string{"x", "y"})
+
+	var got [][2]any
+	for _, row := range rows {
+		got = append(got, [2]any{row["x"], row["y"]})
+	}
+	want := [][2]any{{nil, "a"}, {int64(0), "a"}, {int64(0), "b"}, {int64(1), "a"}, {int64(1), "b"}}
+	require.Equal(t, want, got)
+}
+
+func TestInterleaveBits(t *testing.T) {
+	tests := map[string]struct {
+		values []uint64
+		bits   int
+		want   uint64
+	}{
+		"single":    {values: []uint64{0b101}, bits: 3, want: 0b101},
+		"two":       {values: []uint64{0b11, 0b00}, bits: 2, want: 0b1010},
+		"three":     {values: []uint64{0b1, 0b0, 0b1}, bits: 1, want: 0b101},
+		"high bits": {values: []uint64{0b100, 0b011}, bits: 2, want: 0b0101},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.want, interleaveBits(test.values, test.bits))
+		})
+	}
+}